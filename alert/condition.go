@@ -0,0 +1,260 @@
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Condition is a boolean expression over the values of metrics.
+type Condition interface {
+	// Eval reports whether the condition holds, known is false when it cannot be
+	// decided because a metric is missing.
+	Eval(values map[string]float64) (holds bool, known bool)
+	// Metrics appends the names of the metrics the condition refers to.
+	Metrics(names []string) []string
+	String() string
+}
+
+type comparison struct {
+	metric    string
+	operator  string
+	threshold float64
+}
+
+func (c *comparison) Eval(values map[string]float64) (bool, bool) {
+	value, ok := values[c.metric]
+	if !ok {
+		return false, false
+	}
+	switch c.operator {
+	case ">":
+		return value > c.threshold, true
+	case ">=":
+		return value >= c.threshold, true
+	case "<":
+		return value < c.threshold, true
+	case "<=":
+		return value <= c.threshold, true
+	case "==":
+		return value == c.threshold, true
+	default:
+		return value != c.threshold, true
+	}
+}
+
+func (c *comparison) Metrics(names []string) []string {
+	return append(names, c.metric)
+}
+
+func (c *comparison) String() string {
+	return fmt.Sprintf("%s %s %s", c.metric, c.operator, strconv.FormatFloat(c.threshold, 'g', -1, 64))
+}
+
+type logical struct {
+	and         bool
+	left, right Condition
+}
+
+func (l *logical) Eval(values map[string]float64) (bool, bool) {
+	left, leftKnown := l.left.Eval(values)
+	right, rightKnown := l.right.Eval(values)
+	if l.and {
+		// A known false side decides an and on its own.
+		return left && right, (leftKnown && rightKnown) || (leftKnown && !left) || (rightKnown && !right)
+	}
+	return left || right, (leftKnown && rightKnown) || (leftKnown && left) || (rightKnown && right)
+}
+
+func (l *logical) Metrics(names []string) []string {
+	return l.right.Metrics(l.left.Metrics(names))
+}
+
+func (l *logical) String() string {
+	operator := "or"
+	if l.and {
+		operator = "and"
+	}
+	return fmt.Sprintf("(%s %s %s)", l.left, operator, l.right)
+}
+
+type negation struct {
+	condition Condition
+}
+
+func (n *negation) Eval(values map[string]float64) (bool, bool) {
+	holds, known := n.condition.Eval(values)
+	return !holds, known
+}
+
+func (n *negation) Metrics(names []string) []string {
+	return n.condition.Metrics(names)
+}
+
+func (n *negation) String() string {
+	return fmt.Sprintf("not %s", n.condition)
+}
+
+// metricNames returns the sorted and distinct names of the metrics of c.
+func metricNames(c Condition) []string {
+	names := c.Metrics(nil)
+	sort.Strings(names)
+	distinct := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			distinct = append(distinct, name)
+		}
+	}
+	return distinct
+}
+
+// ParseCondition parses comparisons of a metric with a number, like
+// wt_cache_dirty_pct > 20, combined with and, or, not and parentheses.
+func ParseCondition(s string) (Condition, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], s)
+	}
+	return c, nil
+}
+
+func tokenize(s string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.ContainsRune("<>=!", c):
+			j := i + 1
+			if j < len(s) && s[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		case c == '_' || c == '.' || c == '-' || c == '+' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", c, s)
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *parser) or() (Condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Condition, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Condition, error) {
+	switch token := p.peek(); {
+	case strings.EqualFold(token, "not"):
+		p.next()
+		c, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &negation{condition: c}, nil
+	case token == "(":
+		p.next()
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return c, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Condition, error) {
+	metric := p.next()
+	if metric == "" || !isMetricName(metric) {
+		return nil, fmt.Errorf("expected a metric name, got %q", metric)
+	}
+	operator := p.next()
+	switch operator {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return nil, fmt.Errorf("expected a comparison operator after %s, got %q", metric, operator)
+	}
+	threshold, err := strconv.ParseFloat(p.next(), 64)
+	if err != nil {
+		return nil, fmt.Errorf("expected a number after %s %s", metric, operator)
+	}
+	return &comparison{metric: metric, operator: operator, threshold: threshold}, nil
+}
+
+func isMetricName(s string) bool {
+	for i, c := range s {
+		if !(c == '_' || unicode.IsLetter(c) || (i > 0 && (unicode.IsDigit(c) || c == '.'))) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+package alert
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// resolvedRetention is how long a resolved alert stays listed.
+const resolvedRetention = 5 * time.Minute
+
+// Alert is the state of a rule for a target.
+type Alert struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Target   string `json:"target"`
+	State    State  `json:"state"`
+	Expr     string `json:"expr"`
+	// Since is when the alert entered its state.
+	Since time.Time `json:"since"`
+	// ActiveSince is when the condition started to hold.
+	ActiveSince time.Time          `json:"activeSince"`
+	Values      map[string]float64 `json:"values"`
+}
+
+// Engine evaluates the rules for every target each time a target is observed.
+type Engine struct {
+	rules []*Rule
+	// alerts are the alerts by target and by rule name.
+	alerts map[string]map[string]*Alert
+	// values are the last values of every metric of every target.
+	values   map[string]map[string]float64
+	lastTime map[string]time.Time
+	mutex    sync.Mutex
+
+	// OnChange is called with every alert changing its state when it is not nil.
+	OnChange func(Alert)
+}
+
+// NewEngine returns an engine evaluating rules.
+func NewEngine(rules []*Rule) *Engine {
+	return &Engine{
+		rules:    rules,
+		alerts:   map[string]map[string]*Alert{},
+		values:   map[string]map[string]float64{},
+		lastTime: map[string]time.Time{},
+	}
+}
+
+// Observe updates the values of the metrics of target observed at t and evaluates
+// the rules for target. The metrics not in values keep their last value, so the
+// metrics fetched at different intervals can be observed separately.
+func (e *Engine) Observe(target string, t time.Time, values map[string]float64) {
+	e.mutex.Lock()
+	targetValues, ok := e.values[target]
+	if !ok {
+		targetValues = map[string]float64{}
+		e.values[target] = targetValues
+		e.alerts[target] = map[string]*Alert{}
+	}
+	for name, value := range values {
+		targetValues[name] = value
+	}
+	if t.After(e.lastTime[target]) {
+		e.lastTime[target] = t
+	}
+
+	changes := []Alert{}
+	for _, rule := range e.rules {
+		a, ok := e.alerts[target][rule.Name]
+		if !ok {
+			a = &Alert{
+				Rule:     rule.Name,
+				Severity: rule.Severity,
+				Target:   target,
+				State:    StateInactive,
+				Expr:     rule.When.String(),
+			}
+			e.alerts[target][rule.Name] = a
+		}
+		if evaluate(rule, a, t, targetValues) {
+			changes = append(changes, a.copy())
+		}
+	}
+	e.mutex.Unlock()
+
+	if e.OnChange != nil {
+		for _, a := range changes {
+			e.OnChange(a)
+		}
+	}
+}
+
+// evaluate moves the alert of a rule to its next state and reports whether it changed.
+func evaluate(rule *Rule, a *Alert, t time.Time, values map[string]float64) bool {
+	holds, known := rule.When.Eval(values)
+	if !known {
+		return false
+	}
+	a.Values = map[string]float64{}
+	for _, name := range rule.metrics {
+		if value, ok := values[name]; ok {
+			a.Values[name] = value
+		}
+	}
+
+	previous := a.State
+	switch a.State {
+	case StateInactive, StateResolved:
+		if holds {
+			a.ActiveSince = t
+			a.State = StatePending
+			if rule.For <= 0 {
+				a.State = StateFiring
+			}
+		}
+	case StatePending:
+		if !holds {
+			a.State = StateInactive
+		} else if t.Sub(a.ActiveSince) >= rule.For {
+			a.State = StateFiring
+		}
+	case StateFiring:
+		if resolved, known := rule.Resolve.Eval(values); known && resolved {
+			a.State = StateResolved
+		}
+	}
+	if a.State == previous {
+		return false
+	}
+	a.Since = t
+	return true
+}
+
+func (a *Alert) copy() Alert {
+	c := *a
+	c.Values = map[string]float64{}
+	for name, value := range a.Values {
+		c.Values[name] = value
+	}
+	return c
+}
+
+// Alerts returns the pending, firing and recently resolved alerts, the firing
+// alerts first and then the most recent.
+func (e *Engine) Alerts() []Alert {
+	e.mutex.Lock()
+	alerts := []Alert{}
+	for target, rules := range e.alerts {
+		for _, a := range rules {
+			if a.State == StateInactive {
+				continue
+			}
+			if a.State == StateResolved && e.lastTime[target].Sub(a.Since) > resolvedRetention {
+				continue
+			}
+			alerts = append(alerts, a.copy())
+		}
+	}
+	e.mutex.Unlock()
+
+	rank := map[State]int{StateFiring: 0, StatePending: 1, StateResolved: 2}
+	sort.Slice(alerts, func(i, j int) bool {
+		if rank[alerts[i].State] != rank[alerts[j].State] {
+			return rank[alerts[i].State] < rank[alerts[j].State]
+		}
+		return alerts[i].Since.After(alerts[j].Since)
+	})
+	return alerts
+}
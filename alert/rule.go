@@ -0,0 +1,114 @@
+package alert
+
+import (
+	"fmt"
+	metrichelper "mongo-monitor/metric_helper"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// forRegexp matches the duration a condition must hold at the end of an expression.
+var forRegexp = regexp.MustCompile(`(?i)^(.*?)\s+for\s+(\S+)\s*$`)
+
+// RuleConfig is a rule as written in the configuration file:
+//
+//	[[rules]]
+//	name = "replication lag"
+//	expr = "repl_lag_seconds > 30 for 2m"
+//	resolve = "repl_lag_seconds < 10"
+//	severity = "critical"
+type RuleConfig struct {
+	Name string `mapstructure:"name"`
+	// Expr is the condition firing the alert, optionally followed by the duration
+	// it must hold for.
+	Expr string `mapstructure:"expr"`
+	// For is the duration the condition must hold for, when Expr does not specify it.
+	For string `mapstructure:"for"`
+	// Resolve is the condition resolving a firing alert, the negation of Expr when it
+	// is empty. A threshold below the one of Expr keeps a flapping metric firing.
+	Resolve  string `mapstructure:"resolve"`
+	Severity string `mapstructure:"severity"`
+}
+
+// Rule fires an alert for a target once When holds for For, and resolves it once
+// Resolve holds.
+type Rule struct {
+	Name     string
+	Severity string
+	When     Condition
+	For      time.Duration
+	Resolve  Condition
+	metrics  []string
+}
+
+// ParseRule parses a rule of the configuration file.
+func ParseRule(config RuleConfig) (*Rule, error) {
+	expr := config.Expr
+	forDuration := config.For
+	if m := forRegexp.FindStringSubmatch(expr); m != nil {
+		expr, forDuration = m[1], m[2]
+	}
+
+	when, err := ParseCondition(expr)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %v", config.Name, err)
+	}
+	rule := &Rule{
+		Name:     config.Name,
+		Severity: config.Severity,
+		When:     when,
+		Resolve:  &negation{condition: when},
+	}
+	if rule.Name == "" {
+		rule.Name = when.String()
+	}
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+	if forDuration != "" {
+		if rule.For, err = time.ParseDuration(forDuration); err != nil {
+			return nil, fmt.Errorf("rule %q: %v", rule.Name, err)
+		}
+	}
+	if config.Resolve != "" {
+		if rule.Resolve, err = ParseCondition(config.Resolve); err != nil {
+			return nil, fmt.Errorf("rule %q: %v", rule.Name, err)
+		}
+	}
+	rule.metrics = metricNames(&logical{left: rule.When, right: rule.Resolve})
+	for _, name := range rule.metrics {
+		if !knownMetric(name) {
+			return nil, fmt.Errorf(
+				"rule %q: unknown metric %q, the metrics are %s and their anomaly scores suffixed with _score",
+				rule.Name, name, strings.Join(metrichelper.MetricNames(), ", "),
+			)
+		}
+	}
+	return rule, nil
+}
+
+// knownMetric reports whether name is a metric observed by the rules, or the anomaly
+// score of one.
+func knownMetric(name string) bool {
+	name = strings.TrimSuffix(name, "_score")
+	for _, known := range metrichelper.MetricNames() {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRules parses the rules of the configuration file.
+func ParseRules(configs []RuleConfig) ([]*Rule, error) {
+	rules := []*Rule{}
+	for _, config := range configs {
+		rule, err := ParseRule(config)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,46 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule(RuleConfig{
+		Name:    "replication lag",
+		Expr:    "repl_lag_seconds > 30 for 2m",
+		Resolve: "repl_lag_seconds < 10",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.For != 2*time.Minute || rule.Severity != "warning" {
+		t.Errorf("ParseRule() = %+v, want for 2m and the warning severity", rule)
+	}
+	if len(rule.metrics) != 1 || rule.metrics[0] != "repl_lag_seconds" {
+		t.Errorf("ParseRule() metrics = %v, want [repl_lag_seconds]", rule.metrics)
+	}
+}
+
+func TestParseRuleMetrics(t *testing.T) {
+	tests := []struct {
+		expr  string
+		valid bool
+	}{
+		{"wt_cache_dirty_pct > 20 and wt_cache_used_pct > 80", true},
+		{"query_per_second_score > 6", true},
+		{"connections_used_pct > 90", true},
+		{"query_per_sec > 100", false},
+		{"wt_cache_dirty_pct > 20 and unknown_score > 6", false},
+	}
+	for _, test := range tests {
+		_, err := ParseRule(RuleConfig{Expr: test.expr})
+		if test.valid && err != nil {
+			t.Errorf("ParseRule(%q) = %v, want no error", test.expr, err)
+		}
+		if !test.valid && (err == nil || !strings.Contains(err.Error(), "query_per_second")) {
+			t.Errorf("ParseRule(%q) = %v, want an error listing the metrics", test.expr, err)
+		}
+	}
+}
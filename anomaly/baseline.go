@@ -0,0 +1,112 @@
+package anomaly
+
+import (
+	"math"
+	"time"
+)
+
+// hoursPerWeek is the number of hours the seasonal baseline remembers.
+const hoursPerWeek = 7 * 24
+
+// baseline is what a metric is expected to be, and how far from it it usually is.
+type baseline interface {
+	// expect returns the expected value and deviation at t, ok is false until the
+	// baseline has seen enough samples.
+	expect(t time.Time) (expected float64, deviation float64, ok bool)
+	add(t time.Time, value float64)
+}
+
+// ewma is an exponentially weighted moving average and variance, following the
+// recent level of a metric.
+type ewma struct {
+	alpha    float64
+	warmup   int
+	count    int
+	mean     float64
+	variance float64
+}
+
+func (e *ewma) expect(t time.Time) (float64, float64, bool) {
+	return e.mean, math.Sqrt(e.variance), e.count >= e.warmup
+}
+
+func (e *ewma) add(t time.Time, value float64) {
+	if e.count == 0 {
+		e.mean = value
+	} else {
+		diff := value - e.mean
+		increment := e.alpha * diff
+		e.mean += increment
+		e.variance = (1 - e.alpha) * (e.variance + diff*increment)
+	}
+	e.count++
+}
+
+// hourStats are the mean and variance of a metric over one hour of one week.
+type hourStats struct {
+	week  int64
+	count int
+	mean  float64
+	m2    float64
+}
+
+func (s *hourStats) add(value float64) {
+	s.count++
+	diff := value - s.mean
+	s.mean += diff / float64(s.count)
+	s.m2 += diff * (value - s.mean)
+}
+
+func (s *hourStats) deviation() float64 {
+	if s.count < 2 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.count-1))
+}
+
+// seasonal compares a metric with the same hour of the previous week, for the
+// metrics following the daily and weekly cycles of the applications. The recent
+// level is used for the hours not seen yet.
+type seasonal struct {
+	warmup int
+	// current are the stats of the hours of the running week, last the stats of
+	// their previous occurrence.
+	current  [hoursPerWeek]*hourStats
+	last     [hoursPerWeek]*hourStats
+	fallback *ewma
+}
+
+func hourOfWeek(t time.Time) (week int64, hour int) {
+	hours := t.Unix() / 3600
+	return hours / hoursPerWeek, int(hours % hoursPerWeek)
+}
+
+func (s *seasonal) expect(t time.Time) (float64, float64, bool) {
+	_, hour := hourOfWeek(t)
+	if last := s.last[hour]; last != nil && last.count >= s.warmup {
+		return last.mean, last.deviation(), true
+	}
+	return s.fallback.expect(t)
+}
+
+func (s *seasonal) add(t time.Time, value float64) {
+	s.fallback.add(t, value)
+
+	week, hour := hourOfWeek(t)
+	current := s.current[hour]
+	switch {
+	case current == nil:
+		current = &hourStats{week: week}
+		s.current[hour] = current
+	case week > current.week:
+		s.last[hour] = current
+		current = &hourStats{week: week}
+		s.current[hour] = current
+	case week < current.week:
+		// The clock went back, the samples are those of another recording.
+		current = &hourStats{week: week}
+		s.current[hour] = current
+		s.last[hour] = nil
+	}
+	current.add(value)
+}
@@ -0,0 +1,209 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+// maxAnomalies is the number of anomalies kept for Anomalies.
+const maxAnomalies = 100
+
+// Config configures the detection as written in the configuration file:
+//
+//	[anomaly]
+//	enabled = true
+//	threshold = 4
+//	seasonal = true
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Metrics are the names of the metrics checked, the rates of the opcounters,
+	// network and checkpoints when it is empty.
+	Metrics []string `mapstructure:"metrics"`
+	// Alpha is the weight of a new sample in the moving average.
+	Alpha float64 `mapstructure:"alpha"`
+	// Threshold is how many deviations away from its baseline a sample is anomalous.
+	Threshold float64 `mapstructure:"threshold"`
+	// Warmup is the number of samples seen before a baseline is trusted.
+	Warmup int `mapstructure:"warmup"`
+	// MinDeviation is the smallest deviation, relative to the expected value, so a
+	// metric flat for a while is not anomalous on its first change.
+	MinDeviation float64 `mapstructure:"min_deviation"`
+	// Seasonal compares the metrics with the same hour of the previous week rather
+	// than with their recent level.
+	Seasonal bool `mapstructure:"seasonal"`
+	// Seed is a session recorded by mongostat --record the baselines are learned from
+	// at startup, rather than waiting for the warmup or a week for the seasonal ones.
+	Seed string `mapstructure:"seed"`
+}
+
+// Anomaly is a sample of a metric deviating from its baseline.
+type Anomaly struct {
+	Target   string    `json:"target"`
+	Metric   string    `json:"metric"`
+	Time     time.Time `json:"time"`
+	Value    float64   `json:"value"`
+	Expected float64   `json:"expected"`
+	Lower    float64   `json:"lower"`
+	Upper    float64   `json:"upper"`
+	// Score is the number of deviations the value is away from the expected value,
+	// negative below it.
+	Score float64 `json:"score"`
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf(
+		"%s %s=%.4g outside %.4g..%.4g (expected %.4g, score %+.1f)",
+		a.Target, a.Metric, a.Value, a.Lower, a.Upper, a.Expected, a.Score,
+	)
+}
+
+// Detector flags the samples of every target deviating from the baselines learned
+// from their history.
+type Detector struct {
+	config    Config
+	baselines map[string]map[string]baseline
+	anomalies []Anomaly
+	mutex     sync.Mutex
+}
+
+// DefaultMetrics returns the names of the rates of metric_helper.Metrics.
+func DefaultMetrics() []string {
+	names := []string{}
+	for name := range (&metrichelper.Metrics{}).Values() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewDetector returns a detector, the settings missing from config get their default.
+func NewDetector(config Config) *Detector {
+	if len(config.Metrics) == 0 {
+		config.Metrics = DefaultMetrics()
+	}
+	if config.Alpha <= 0 || config.Alpha > 1 {
+		config.Alpha = 0.03
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = 4
+	}
+	if config.Warmup <= 0 {
+		config.Warmup = 60
+	}
+	if config.MinDeviation <= 0 {
+		config.MinDeviation = 0.1
+	}
+	return &Detector{
+		config:    config,
+		baselines: map[string]map[string]baseline{},
+	}
+}
+
+// Metrics returns the names of the metrics checked.
+func (d *Detector) Metrics() []string {
+	return d.config.Metrics
+}
+
+func (d *Detector) newBaseline() baseline {
+	e := &ewma{alpha: d.config.Alpha, warmup: d.config.Warmup}
+	if d.config.Seasonal {
+		return &seasonal{warmup: d.config.Warmup, fallback: e}
+	}
+	return e
+}
+
+// Seed learns the baselines of target from its recorded metrics, oldest first,
+// without looking for anomalies in them.
+func (d *Detector) Seed(target string, ms metrichelper.MetricsSlice) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	baselines, ok := d.baselines[target]
+	if !ok {
+		baselines = map[string]baseline{}
+		d.baselines[target] = baselines
+	}
+	for _, metrics := range ms {
+		values := metrics.Values()
+		for _, metric := range d.config.Metrics {
+			value, ok := values[metric]
+			if !ok {
+				continue
+			}
+			b, ok := baselines[metric]
+			if !ok {
+				b = d.newBaseline()
+				baselines[metric] = b
+			}
+			b.add(metrics.EndTime, value)
+		}
+	}
+}
+
+// Observe checks the values of target observed at t against their baselines before
+// learning them. It returns the scores of the metrics having a trusted baseline by
+// metric name suffixed with _score, so rules can refer to them, and the anomalies.
+func (d *Detector) Observe(target string, t time.Time, values map[string]float64) (map[string]float64, []Anomaly) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	baselines, ok := d.baselines[target]
+	if !ok {
+		baselines = map[string]baseline{}
+		d.baselines[target] = baselines
+	}
+	scores := map[string]float64{}
+	anomalies := []Anomaly{}
+	for _, metric := range d.config.Metrics {
+		value, ok := values[metric]
+		if !ok {
+			continue
+		}
+		b, ok := baselines[metric]
+		if !ok {
+			b = d.newBaseline()
+			baselines[metric] = b
+		}
+		if expected, deviation, ok := b.expect(t); ok {
+			// The rates are whole numbers, a deviation below one is only the absence of noise.
+			deviation = math.Max(deviation, math.Max(d.config.MinDeviation*math.Abs(expected), 1))
+			score := (value - expected) / deviation
+			scores[metric+"_score"] = score
+			if math.Abs(score) > d.config.Threshold {
+				anomalies = append(anomalies, Anomaly{
+					Target:   target,
+					Metric:   metric,
+					Time:     t,
+					Value:    value,
+					Expected: expected,
+					Lower:    expected - d.config.Threshold*deviation,
+					Upper:    expected + d.config.Threshold*deviation,
+					Score:    score,
+				})
+			}
+		}
+		b.add(t, value)
+	}
+
+	d.anomalies = append(d.anomalies, anomalies...)
+	if len(d.anomalies) > maxAnomalies {
+		d.anomalies = d.anomalies[len(d.anomalies)-maxAnomalies:]
+	}
+	return scores, anomalies
+}
+
+// Anomalies returns the last anomalies of every target, the most recent first.
+func (d *Detector) Anomalies() []Anomaly {
+	d.mutex.Lock()
+	anomalies := make([]Anomaly, len(d.anomalies))
+	for i, a := range d.anomalies {
+		anomalies[len(anomalies)-1-i] = a
+	}
+	d.mutex.Unlock()
+	return anomalies
+}
@@ -0,0 +1,122 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+var seriesStart = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// steadySeries returns queries per second around 100 with a small deterministic noise,
+// one sample every interval.
+func steadySeries(count int, interval time.Duration) metrichelper.MetricsSlice {
+	ms := metrichelper.MetricsSlice{}
+	for i := 0; i < count; i++ {
+		ms = append(ms, metrichelper.Metrics{
+			Host:                "a:27017",
+			QueryCountPerSecond: 100 + math.Round(5*math.Sin(float64(i))),
+			EndTime:             seriesStart.Add(time.Duration(i) * interval),
+		})
+	}
+	return ms
+}
+
+func observeQueries(d *Detector, t time.Time, queries float64) (map[string]float64, []Anomaly) {
+	return d.Observe("a:27017", t, map[string]float64{"query_per_second": queries})
+}
+
+func TestDetectorSpike(t *testing.T) {
+	d := NewDetector(Config{Metrics: []string{"query_per_second"}, Warmup: 20})
+	series := steadySeries(100, time.Second)
+	spike := 60
+	series[spike].QueryCountPerSecond = 400
+
+	for i, m := range series {
+		scores, anomalies := observeQueries(d, m.EndTime, m.QueryCountPerSecond)
+		_, scored := scores["query_per_second_score"]
+		if scored != (i >= 20) {
+			t.Errorf("sample %d scored %v, want the scores once the %d samples of warmup are seen", i, scored, 20)
+		}
+		if i != spike && len(anomalies) > 0 {
+			t.Errorf("sample %d: anomalies %v, want none", i, anomalies)
+		}
+		if i == spike {
+			if len(anomalies) != 1 {
+				t.Fatalf("spike: anomalies %v, want one", anomalies)
+			}
+			a := anomalies[0]
+			if a.Metric != "query_per_second" || a.Value != 400 || a.Score <= d.config.Threshold ||
+				a.Expected < 95 || a.Expected > 105 || !a.Time.Equal(m.EndTime) {
+				t.Errorf("spike: anomaly %+v, want query_per_second 400 expected about 100", a)
+			}
+		}
+	}
+	if anomalies := d.Anomalies(); len(anomalies) != 1 {
+		t.Errorf("Anomalies() = %v, want the spike", anomalies)
+	}
+}
+
+func TestDetectorDrop(t *testing.T) {
+	d := NewDetector(Config{Metrics: []string{"query_per_second"}, Warmup: 20})
+	for _, m := range steadySeries(40, time.Second) {
+		observeQueries(d, m.EndTime, m.QueryCountPerSecond)
+	}
+	scores, anomalies := observeQueries(d, seriesStart.Add(time.Minute), 0)
+	if len(anomalies) != 1 || scores["query_per_second_score"] >= -d.config.Threshold {
+		t.Errorf("drop to 0: scores %v and anomalies %v, want a negative anomaly", scores, anomalies)
+	}
+}
+
+func TestDetectorSeed(t *testing.T) {
+	series := steadySeries(30, time.Second)
+	spikeTime := seriesStart.Add(time.Minute)
+
+	unseeded := NewDetector(Config{Metrics: []string{"query_per_second"}, Warmup: 20})
+	if scores, anomalies := observeQueries(unseeded, spikeTime, 400); len(scores) != 0 || len(anomalies) != 0 {
+		t.Errorf("without history: scores %v and anomalies %v, want none before the warmup", scores, anomalies)
+	}
+
+	seeded := NewDetector(Config{Metrics: []string{"query_per_second"}, Warmup: 20})
+	seeded.Seed("a:27017", series)
+	if anomalies := seeded.Anomalies(); len(anomalies) != 0 {
+		t.Errorf("Seed() flagged %v, want the history learned only", anomalies)
+	}
+	if _, anomalies := observeQueries(seeded, spikeTime, 400); len(anomalies) != 1 {
+		t.Errorf("seeded: anomalies %v, want the spike flagged", anomalies)
+	}
+	if _, anomalies := seeded.Observe("b:27017", spikeTime, map[string]float64{"query_per_second": 400}); len(anomalies) != 0 {
+		t.Errorf("other target: anomalies %v, want none before its warmup", anomalies)
+	}
+}
+
+func TestDetectorSeasonal(t *testing.T) {
+	d := NewDetector(Config{Metrics: []string{"query_per_second"}, Warmup: 3, Seasonal: true})
+	// Two weeks busy on the even hours, quiet on the odd ones, a sample every 10 minutes.
+	queries := func(t time.Time, i int) float64 {
+		if _, hour := hourOfWeek(t); hour%2 == 0 {
+			return 1000 + float64(i%3)
+		}
+		return 100 + float64(i%3)
+	}
+	ms := metrichelper.MetricsSlice{}
+	for i := 0; i < 2*hoursPerWeek*6; i++ {
+		end := seriesStart.Add(time.Duration(i) * 10 * time.Minute)
+		ms = append(ms, metrichelper.Metrics{QueryCountPerSecond: queries(end, i), EndTime: end})
+	}
+	d.Seed("a:27017", ms)
+
+	thirdWeek := seriesStart.Add(2 * hoursPerWeek * time.Hour)
+	for hour := 0; hour < 4; hour++ {
+		at := thirdWeek.Add(time.Duration(hour)*time.Hour + 5*time.Minute)
+		_, weekHour := hourOfWeek(at)
+		if _, anomalies := observeQueries(d, at, queries(at, 1)); len(anomalies) != 0 {
+			t.Errorf("hour %d: anomalies %v for the usual load", weekHour, anomalies)
+		}
+		if _, anomalies := observeQueries(d, at.Add(time.Minute), 1000); (weekHour%2 == 1) != (len(anomalies) == 1) {
+			t.Errorf("hour %d: anomalies %v for 1000 queries per second", weekHour, anomalies)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/storage"
+)
+
+// handleEvents serves the events of target between from and to, oldest first, with
+// the from, to and range parameters of handleQuery. The events of every target are
+// served without target, and to defaults to the last sample of any target.
+func (server *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	last := time.Now()
+	if m, err := server.storage.FetchLastMetrics(); err == nil {
+		last = m.EndTime
+	}
+	q, err := parseQuery(r.URL.Query(), last)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	events, err := server.storage.FetchEvents(r.URL.Query().Get("target"), q.from, q.to)
+	if _, ok := err.(*storage.DataNotFound); ok {
+		events = metrichelper.EventsSlice{}
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, events)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/storage"
+)
+
+// LatencyPoint are the latency histograms of a target at a time by kind of
+// operations, the bucket i of a histogram holds the operations per second that took
+// from 2^i to 2^(i+1) microseconds.
+type LatencyPoint struct {
+	Time      time.Time                                `json:"time"`
+	Latencies map[string]metrichelper.LatencyHistogram `json:"latencies"`
+}
+
+// LatencyResult are the latency histograms of a range query.
+type LatencyResult struct {
+	Target string    `json:"target"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	// Step is the resolution of the points, zero when the samples are not aggregated.
+	Step   string         `json:"step"`
+	Points []LatencyPoint `json:"points"`
+}
+
+// handleLatencies serves the latency histograms between from and to, averaged over
+// step, with the parameters of handleQuery but metric. The samples without
+// histogram have no point.
+func (server *Server) handleLatencies(w http.ResponseWriter, r *http.Request) {
+	target, ok := server.target(w, r)
+	if !ok {
+		return
+	}
+	last, err := server.storage.FetchLastHostMetrics(target)
+	if err != nil {
+		writeStorageError(w, err, fmt.Errorf("no sample of %s", target))
+		return
+	}
+	q, err := parseQuery(r.URL.Query(), last.EndTime)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ms, err := server.storage.FetchMetricsSlice(target, q.from, q.to)
+	if _, ok := err.(*storage.DataNotFound); !ok && err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if q.step == 0 && len(ms) > maxPoints {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%d samples between from and to, set a step", len(ms)))
+		return
+	}
+
+	result := LatencyResult{Target: target, From: q.from, To: q.to, Step: "0s", Points: []LatencyPoint{}}
+	if q.step > 0 {
+		result.Step = q.step.String()
+		// A rolled up metrics is at the start of its step, as the points of handleQuery.
+		for _, m := range ms.Rollup(q.from, q.step) {
+			if m.Latencies != nil {
+				result.Points = append(result.Points, LatencyPoint{Time: m.StartTime, Latencies: m.Latencies})
+			}
+		}
+	} else {
+		for _, m := range ms {
+			if m.Latencies != nil {
+				result.Points = append(result.Points, LatencyPoint{Time: m.EndTime, Latencies: m.Latencies})
+			}
+		}
+	}
+	writeJSON(w, result)
+}
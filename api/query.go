@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/storage"
+)
+
+// maxPoints is the most points of a series, the step is raised to stay below.
+const maxPoints = 11000
+
+// Point is the value of a metric at a time.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Series are the points of a metric.
+type Series struct {
+	Metric string  `json:"metric"`
+	Points []Point `json:"points"`
+}
+
+// QueryResult are the series of a range query.
+type QueryResult struct {
+	Target string    `json:"target"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	// Step is the resolution of the series, zero when the samples are not aggregated.
+	Step   string   `json:"step"`
+	Series []Series `json:"series"`
+}
+
+// query are the parameters of a range query.
+type query struct {
+	metrics []string
+	from    time.Time
+	to      time.Time
+	step    time.Duration
+}
+
+// parseQuery parses the metric, from, to, range and step parameters. to defaults to
+// the last sample of the target, from to range, or an hour, before to, and every
+// metric is returned when there is no metric parameter.
+func parseQuery(values url.Values, last time.Time) (*query, error) {
+	q := &query{metrics: values["metric"], to: last}
+	known := map[string]bool{}
+	for _, name := range metricNames() {
+		known[name] = true
+	}
+	for _, metric := range q.metrics {
+		if !known[metric] {
+			return nil, fmt.Errorf("unknown metric %q", metric)
+		}
+	}
+	if len(q.metrics) == 0 {
+		q.metrics = metricNames()
+	}
+
+	var err error
+	if to := values.Get("to"); to != "" {
+		if q.to, err = time.Parse(time.RFC3339, to); err != nil {
+			return nil, fmt.Errorf("to: %v", err)
+		}
+	}
+	q.from = q.to.Add(-defaultRange)
+	if r := values.Get("range"); r != "" {
+		d, err := time.ParseDuration(r)
+		if err != nil {
+			return nil, fmt.Errorf("range: %v", err)
+		}
+		q.from = q.to.Add(-d)
+	}
+	if from := values.Get("from"); from != "" {
+		if q.from, err = time.Parse(time.RFC3339, from); err != nil {
+			return nil, fmt.Errorf("from: %v", err)
+		}
+	}
+	if q.from.After(q.to) {
+		return nil, fmt.Errorf("from %s is after to %s", q.from.Format(time.RFC3339), q.to.Format(time.RFC3339))
+	}
+	if step := values.Get("step"); step != "" {
+		if q.step, err = time.ParseDuration(step); err != nil {
+			return nil, fmt.Errorf("step: %v", err)
+		}
+		if q.step < 0 {
+			return nil, fmt.Errorf("step must not be negative")
+		}
+	}
+	if q.step > 0 {
+		if minStep := q.to.Sub(q.from) / maxPoints; q.step < minStep {
+			return nil, fmt.Errorf("step %s makes more than %d points, raise it", q.step, maxPoints)
+		}
+	}
+	return q, nil
+}
+
+func (server *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	target, ok := server.target(w, r)
+	if !ok {
+		return
+	}
+	last, err := server.storage.FetchLastHostMetrics(target)
+	if err != nil {
+		writeStorageError(w, err, fmt.Errorf("no sample of %s", target))
+		return
+	}
+	q, err := parseQuery(r.URL.Query(), last.EndTime)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ms, err := server.storage.FetchMetricsSlice(target, q.from, q.to)
+	if _, ok := err.(*storage.DataNotFound); !ok && err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if q.step == 0 && len(ms) > maxPoints {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%d samples between from and to, set a step", len(ms)))
+		return
+	}
+
+	result := QueryResult{Target: target, From: q.from, To: q.to, Step: "0s", Series: []Series{}}
+	if q.step > 0 {
+		result.Step = q.step.String()
+	}
+	// The values of a sample are computed once for every metric.
+	values := make([]map[string]float64, len(ms))
+	for i := range ms {
+		values[i] = ms[i].Values()
+	}
+	for _, metric := range q.metrics {
+		result.Series = append(result.Series, Series{Metric: metric, Points: points(ms, values, metric, q.from, q.step)})
+	}
+	writeJSON(w, result)
+}
+
+// points returns the values of metric, averaged over the steps starting at from
+// when step is not zero. A step without sample has no point. values are the values
+// of the samples of ms.
+func points(
+	ms metrichelper.MetricsSlice,
+	values []map[string]float64,
+	metric string,
+	from time.Time,
+	step time.Duration,
+) []Point {
+	ps := []Point{}
+	if step <= 0 {
+		for i, metrics := range ms {
+			ps = append(ps, Point{Time: metrics.EndTime, Value: values[i][metric]})
+		}
+		return ps
+	}
+
+	var bucket time.Time
+	sum, count := 0.0, 0
+	flush := func() {
+		if count > 0 {
+			ps = append(ps, Point{Time: bucket, Value: sum / float64(count)})
+		}
+	}
+	for i, metrics := range ms {
+		start := from.Add(time.Duration(math.Floor(float64(metrics.EndTime.Sub(from))/float64(step))) * step)
+		if !start.Equal(bucket) {
+			flush()
+			bucket, sum, count = start, 0, 0
+		}
+		sum += values[i][metric]
+		count++
+	}
+	flush()
+	return ps
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+func TestPoints(t *testing.T) {
+	from := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	ms := metrichelper.MetricsSlice{}
+	for _, s := range []int{0, 10, 20, 70, 130} {
+		ms = append(ms, metrichelper.Metrics{QueryCountPerSecond: float64(s), EndTime: from.Add(time.Duration(s) * time.Second)})
+	}
+	values := make([]map[string]float64, len(ms))
+	for i := range ms {
+		values[i] = ms[i].Values()
+	}
+
+	raw := points(ms, values, "query_per_second", from, 0)
+	if len(raw) != 5 || raw[3].Value != 70 || !raw[3].Time.Equal(from.Add(70*time.Second)) {
+		t.Errorf("points() without step = %v, want every sample", raw)
+	}
+
+	// The samples of the first minute are averaged.
+	rolled := points(ms, values, "query_per_second", from, time.Minute)
+	want := []Point{
+		{Time: from, Value: 10},
+		{Time: from.Add(time.Minute), Value: 70},
+		{Time: from.Add(2 * time.Minute), Value: 130},
+	}
+	if len(rolled) != len(want) {
+		t.Fatalf("points() with step = %v, want %v", rolled, want)
+	}
+	for i := range want {
+		if !rolled[i].Time.Equal(want[i].Time) || rolled[i].Value != want[i].Value {
+			t.Errorf("points() with step = %v, want %v", rolled, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"mongo-monitor/alert"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/storage"
+)
+
+// defaultRange is the range of a query without from.
+const defaultRange = time.Hour
+
+// Server serves the metrics of a storage as JSON:
+//
+//	GET /api/v1/targets
+//	GET /api/v1/metrics
+//	GET /api/v1/latest?target=host:port
+//	GET /api/v1/query?target=host:port&metric=query_per_second&from=...&to=...&step=10s
+//	GET /api/v1/query?target=host:port&metric=query_per_second&range=15m
+//	GET /api/v1/latencies?target=host:port&range=15m&step=10s
+//	GET /api/v1/alerts
+//	GET /api/v1/topology?target=host:port
+//	GET /api/v1/events?target=host:port&range=1h
+//
+// The target may be omitted while a single target is monitored.
+type Server struct {
+	storage storage.Storage
+	mux     *http.ServeMux
+
+	// Alerts returns the current alerts when it is not nil.
+	Alerts func() []alert.Alert
+}
+
+// NewServer returns a server serving the metrics of s.
+func NewServer(s storage.Storage) *Server {
+	server := &Server{storage: s, mux: http.NewServeMux()}
+	server.mux.HandleFunc("/api/v1/targets", server.handleTargets)
+	server.mux.HandleFunc("/api/v1/metrics", server.handleMetrics)
+	server.mux.HandleFunc("/api/v1/latest", server.handleLatest)
+	server.mux.HandleFunc("/api/v1/query", server.handleQuery)
+	server.mux.HandleFunc("/api/v1/latencies", server.handleLatencies)
+	server.mux.HandleFunc("/api/v1/alerts", server.handleAlerts)
+	server.mux.HandleFunc("/api/v1/topology", server.handleTopology)
+	server.mux.HandleFunc("/api/v1/events", server.handleEvents)
+	return server
+}
+
+// ServeHTTP serves the API, only GET is allowed.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	server.mux.ServeHTTP(w, r)
+}
+
+// Sample is the value of every metric of a target at a time, and its latency
+// histograms when mongod reports them.
+type Sample struct {
+	Target    string                                   `json:"target"`
+	Time      time.Time                                `json:"time"`
+	Values    map[string]float64                       `json:"values"`
+	Latencies map[string]metrichelper.LatencyHistogram `json:"latencies,omitempty"`
+}
+
+func (server *Server) handleTargets(w http.ResponseWriter, r *http.Request) {
+	targets, err := server.storage.FetchTargets()
+	if _, ok := err.(*storage.DataNotFound); ok {
+		targets = []string{}
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, targets)
+}
+
+func (server *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, metricNames())
+}
+
+func (server *Server) handleLatest(w http.ResponseWriter, r *http.Request) {
+	target, ok := server.target(w, r)
+	if !ok {
+		return
+	}
+	metrics, err := server.storage.FetchLastHostMetrics(target)
+	if err != nil {
+		writeStorageError(w, err, fmt.Errorf("no sample of %s", target))
+		return
+	}
+	writeJSON(w, Sample{Target: target, Time: metrics.EndTime, Values: metrics.Values(), Latencies: metrics.Latencies})
+}
+
+func (server *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts := []alert.Alert{}
+	if server.Alerts != nil {
+		alerts = server.Alerts()
+	}
+	if target := r.URL.Query().Get("target"); target != "" {
+		filtered := []alert.Alert{}
+		for _, a := range alerts {
+			if a.Target == target {
+				filtered = append(filtered, a)
+			}
+		}
+		alerts = filtered
+	}
+	writeJSON(w, alerts)
+}
+
+func (server *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
+	target, ok := server.target(w, r)
+	if !ok {
+		return
+	}
+	topology, err := server.storage.FetchLastTopology(target)
+	if err != nil {
+		writeStorageError(w, err, fmt.Errorf("no topology of %s", target))
+		return
+	}
+	writeJSON(w, topology)
+}
+
+// target returns the target of the request, the only target when the request has
+// none. An error is written and ok is false when there is no such target.
+func (server *Server) target(w http.ResponseWriter, r *http.Request) (string, bool) {
+	targets, err := server.storage.FetchTargets()
+	if _, ok := err.(*storage.DataNotFound); !ok && err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return "", false
+	}
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		if len(targets) == 0 {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no target monitored yet"))
+			return "", false
+		}
+		if len(targets) != 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("target is required, %d targets are monitored", len(targets)))
+			return "", false
+		}
+		return targets[0], true
+	}
+	for _, t := range targets {
+		if t == target {
+			return target, true
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("unknown target %q", target))
+	return "", false
+}
+
+// metricNames returns the names of the metrics a target has, sorted.
+func metricNames() []string {
+	names := []string{}
+	for name := range (&metrichelper.Metrics{}).Values() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// writeStorageError writes notFound when err is a storage.DataNotFound, err otherwise.
+func writeStorageError(w http.ResponseWriter, err error, notFound error) {
+	if _, ok := err.(*storage.DataNotFound); ok {
+		writeError(w, http.StatusNotFound, notFound)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"mongo-monitor/alert"
+	"mongo-monitor/notify"
+	"mongo-monitor/termui"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// alertEngine evaluates the alert rules of the configuration file, it is nil when
+// there is no rule.
+var alertEngine *alert.Engine
+
+// alertDispatcher sends the alerts to the notifiers of the configuration file, it is
+// nil when there is no notifier.
+var alertDispatcher *notify.Dispatcher
+
+// startAlerting creates alertEngine and alertDispatcher from the configuration file.
+func startAlerting() {
+	configs := []alert.RuleConfig{}
+	if err := viper.UnmarshalKey("rules", &configs); err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	if len(configs) == 0 {
+		return
+	}
+	rules, err := alert.ParseRules(configs)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	alertDispatcher = newDispatcher()
+
+	alertEngine = alert.NewEngine(rules)
+	if usingUI {
+		termui.SetAlertsFunc(alertEngine.Alerts)
+	}
+	alertEngine.OnChange = func(a alert.Alert) {
+		if !usingUI {
+			logAlert(a)
+		}
+		recordAlertEvent(a)
+		if alertDispatcher != nil {
+			alertDispatcher.Add(a)
+		}
+	}
+}
+
+// stopAlerting sends the alerts waiting to be notified.
+func stopAlerting() {
+	if alertDispatcher != nil {
+		alertDispatcher.Close()
+	}
+}
+
+// newDispatcher returns a dispatcher sending to the notifiers of the configuration
+// file, nil when there is no notifier.
+func newDispatcher() *notify.Dispatcher {
+	config := notify.Config{}
+	if err := viper.UnmarshalKey("notify", &config); err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	d, err := notify.NewDispatcher(config)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	if d != nil && !usingUI {
+		d.OnError = func(notifier notify.Notifier, n notify.Notification, err error) {
+			logrus.Errorf("%s failed to notify %s: %v", notifier.Name(), n.Title(), err)
+		}
+	}
+	return d
+}
+
+// observeAlerts evaluates the alert rules with the values of target observed at t.
+func observeAlerts(target string, t time.Time, values map[string]float64) {
+	if alertEngine != nil {
+		alertEngine.Observe(target, t, values)
+	}
+}
+
+func logAlert(a alert.Alert) {
+	entry := logrus.WithFields(logrus.Fields{
+		"rule":     a.Rule,
+		"target":   a.Target,
+		"severity": a.Severity,
+	})
+	for name, value := range a.Values {
+		entry = entry.WithField(name, value)
+	}
+	switch a.State {
+	case alert.StateFiring:
+		entry.Warnf("alert firing: %s", a.Expr)
+	case alert.StatePending:
+		entry.Infof("alert pending: %s", a.Expr)
+	case alert.StateResolved:
+		entry.Infof("alert resolved: %s", a.Expr)
+	default:
+		entry.Debugf("alert inactive: %s", a.Expr)
+	}
+}
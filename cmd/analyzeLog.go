@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mongo-monitor/loganalyzer"
+	"mongo-monitor/termui"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var analyzeLogJSON = false
+var analyzeLogBucket time.Duration
+var analyzeLogMaxErrors = 100
+
+// analyzeLogCmd will run analyzeLog function
+var analyzeLogCmd = &cobra.Command{
+	Use:   "analyze-log <file>...",
+	Short: "Analyze mongod log files offline",
+	Long: "Extract slow operations, connection churn, elections and errors from structured (4.4+) " +
+		"or legacy mongod log files, gzipped or not, and aggregate the slow operations by query shape.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		analyzeLog(args)
+	},
+}
+
+func init() {
+	pf := analyzeLogCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.BoolVar(&analyzeLogJSON, "json", false, "print the analysis as JSON")
+	pf.DurationVar(&analyzeLogBucket, "bucket", 0, "the size of the time series buckets, 0 splits the logs in 50 buckets")
+	pf.IntVar(&analyzeLogMaxErrors, "max-errors", 100, "the maximum number of errors listed")
+
+	rootCmd.AddCommand(analyzeLogCmd)
+}
+
+func analyzeLog(files []string) {
+	analyzer := loganalyzer.NewAnalyzer(analyzeLogMaxErrors)
+	for _, file := range files {
+		if err := analyzeLogFile(analyzer, file); err != nil {
+			logrus.Error(err)
+			panic(err)
+		}
+	}
+	report := analyzer.Report(analyzeLogBucket)
+
+	if usingUI {
+		termui.UpdateLogReport(report)
+		termui.RenderLogAnalysis(context.Background())
+		return
+	}
+	if analyzeLogJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			panic(err)
+		}
+		return
+	}
+	printLogReport(report)
+}
+
+func analyzeLogFile(analyzer *loganalyzer.Analyzer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	return analyzer.AnalyzeReader(r)
+}
+
+func printLogReport(r *loganalyzer.Report) {
+	fmt.Printf(
+		"%s - %s, %d lines (%d parsed)\n",
+		r.FirstTime.Format(time.RFC3339),
+		r.LastTime.Format(time.RFC3339),
+		r.Lines,
+		r.ParsedLines,
+	)
+	fmt.Printf("slow operations:    %d\n", r.SlowOperations)
+	fmt.Printf("connections:        %d opened, %d closed, %.0f peak\n", r.ConnectionsOpened, r.ConnectionsClosed, r.PeakConnections)
+	fmt.Printf("errors:             %d\n", r.ErrorCount)
+
+	fmt.Printf("\n%-32s %8s %10s %9s %9s %10s %-16s %s\n", "NS", "COUNT", "TOTAL ms", "AVG ms", "MAX ms", "EXAM/RET", "PLAN", "SHAPE")
+	for _, s := range r.Shapes {
+		fmt.Printf(
+			"%-32s %8.0f %10.0f %9.1f %9.0f %10.1f %-16s %s\n",
+			s.Ns,
+			s.Count,
+			s.TotalMillis,
+			s.AvgMillis,
+			s.MaxMillis,
+			s.ExaminedPerReturned(),
+			s.PlanSummary,
+			s.Shape,
+		)
+	}
+
+	fmt.Printf("\n%-20s %8s %8s %8s %8s\n", "BUCKET", "SLOW", "OPENED", "CLOSED", "ERRORS")
+	for _, b := range r.Buckets {
+		slow := 0.0
+		for _, count := range b.SlowOperations {
+			slow += count
+		}
+		fmt.Printf(
+			"%-20s %8.0f %8.0f %8.0f %8.0f\n",
+			b.Start.Format("2006-01-02 15:04:05"),
+			slow,
+			b.ConnectionsOpened,
+			b.ConnectionsClosed,
+			b.Errors,
+		)
+	}
+
+	fmt.Printf("\nEVENTS\n")
+	for _, e := range r.Events {
+		fmt.Printf("%s %-12s %s\n", e.Time.Format(time.RFC3339), e.Kind, e.Message)
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"mongo-monitor/anomaly"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/session"
+	"mongo-monitor/termui"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// anomalyDetector flags the samples deviating from their baseline, it is nil when
+// the detection is not enabled in the configuration file.
+var anomalyDetector *anomaly.Detector
+
+// anomalySeedPeriod is the history of the metrics the baselines are learned from at
+// startup, a week for the seasonal baselines.
+const anomalySeedPeriod = 7 * 24 * time.Hour
+
+// startAnomalyDetection creates anomalyDetector from the configuration file, its
+// baselines learned from the session of the seed setting.
+func startAnomalyDetection() {
+	config := anomaly.Config{}
+	if err := viper.UnmarshalKey("anomaly", &config); err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	if !config.Enabled {
+		return
+	}
+	anomalyDetector = anomaly.NewDetector(config)
+	if config.Seed != "" {
+		if err := seedAnomalyBaselines(config.Seed); err != nil {
+			logrus.Error(err)
+			panic(err)
+		}
+	}
+	if usingUI {
+		termui.SetAnomaliesFunc(anomalyDetector.Anomalies)
+	}
+}
+
+// seedAnomalyBaselines learns the baselines of anomalyDetector from the metrics of
+// every target of the session recorded at path, in the last anomalySeedPeriod of it.
+func seedAnomalyBaselines(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	extractors := map[string]*metrichelper.MetricsExtractor{}
+	recorded := map[string]metrichelper.MetricsSlice{}
+	last := time.Time{}
+	reader := session.NewReader(f)
+	for {
+		sample, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		host := sample.Status.Host
+		if extractors[host] == nil {
+			extractors[host] = metrichelper.NewMetricsExtractor()
+		}
+		if metrics := extractors[host].Extract(sample.Status); metrics != nil {
+			recorded[host] = append(recorded[host], *metrics)
+			if metrics.EndTime.After(last) {
+				last = metrics.EndTime
+			}
+		}
+	}
+
+	for target, ms := range recorded {
+		i := sort.Search(len(ms), func(i int) bool {
+			return !ms[i].EndTime.Before(last.Add(-anomalySeedPeriod))
+		})
+		anomalyDetector.Seed(target, ms[i:])
+	}
+	return nil
+}
+
+// observeSample looks for anomalies in the values of a sample, then evaluates the
+// alert rules with the values and their anomaly scores, and writes them to the sinks
+// and the UI.
+// metrics are nil for the first sample.
+func observeSample(status *mongowrapper.ServerStatusStats, metrics *metrichelper.Metrics) {
+	target, t := status.Host, status.LocalTime
+	values := metrichelper.MetricValues(status, metrics)
+	if anomalyDetector != nil {
+		scores, anomalies := anomalyDetector.Observe(target, t, values)
+		for name, score := range scores {
+			values[name] = score
+		}
+		if !usingUI {
+			for _, a := range anomalies {
+				logrus.Warnf("anomaly: %s", a)
+			}
+		}
+	}
+	observeAlerts(target, t, values)
+	writeToSinks(status, values)
+	if usingUI {
+		termui.UpdateValues(target, t, values)
+	}
+}
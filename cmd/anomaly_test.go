@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mongo-monitor/anomaly"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/session"
+)
+
+func TestSeedAnomalyBaselines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.ndjson")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 100 inserts per second, a bit more on the odd seconds.
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	w := session.NewWriter(f)
+	inserts := 0.0
+	for i := 0; i <= 40; i++ {
+		inserts += 100 + float64(i%2)*10
+		at := start.Add(time.Duration(i) * time.Second)
+		w.Write(at, &mongowrapper.ServerStatusStats{
+			Host:       "a:27017",
+			Uptime:     float64(i),
+			LocalTime:  at,
+			Network:    &mongowrapper.NetworkStats{},
+			Opcounters: &mongowrapper.OpcountersStats{Insert: inserts},
+			WiredTiger: &mongowrapper.WiredTigerStats{Transaction: &mongowrapper.WTTransactionStats{}},
+		})
+	}
+	f.Close()
+
+	defer func() { anomalyDetector = nil }()
+	anomalyDetector = anomaly.NewDetector(anomaly.Config{Metrics: []string{"insert_per_second"}, Warmup: 20})
+	if err := seedAnomalyBaselines(path); err != nil {
+		t.Fatal(err)
+	}
+	_, anomalies := anomalyDetector.Observe("a:27017", start.Add(time.Minute), map[string]float64{"insert_per_second": 1000})
+	if len(anomalies) != 1 {
+		t.Errorf("anomalies %v once seeded, want the spike flagged without a warmup", anomalies)
+	}
+
+	if err := seedAnomalyBaselines(filepath.Join(dir, "missing.ndjson")); err == nil {
+		t.Error("seedAnomalyBaselines() of a missing session succeeded")
+	}
+}
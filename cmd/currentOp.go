@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/termui"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var currentOpInterval = 1000 * time.Millisecond
+var currentOpFilter = ""
+
+// currentOpCmd will run currentOp function
+var currentOpCmd = &cobra.Command{
+	Use:   "currentop",
+	Short: "Show the operations running right now, like top",
+	Long:  "Show the operations reported by $currentOp and kill them from the UI.",
+	Run: func(cmd *cobra.Command, args []string) {
+		i := viper.GetInt("currentop-interval")
+		if i == 0 {
+			panic("The parameter interval must be greater than 0.")
+		}
+		currentOpInterval = time.Duration(i) * time.Millisecond
+		currentOp()
+	},
+}
+
+func init() {
+	pf := currentOpCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.Uint("interval", 1000, "the interval (millisecond) fetching the current operations")
+	pf.StringVar(&currentOpFilter, "filter", "", "only show the operations whose ns, op, plan, client or desc contain this keyword")
+
+	viper.BindPFlag("currentop-interval", currentOpCmd.PersistentFlags().Lookup("interval"))
+
+	addURIFlag(currentOpCmd)
+	rootCmd.AddCommand(currentOpCmd)
+}
+
+func currentOp() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := mongowrapper.CreateClient(ctx, mongoURI)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fetchOperationsPeriodically(ctx, client, currentOpInterval)
+	}()
+
+	if usingUI {
+		termui.SetOperationsFilter(currentOpFilter)
+		termui.SetKillOpFunc(func(opID interface{}) error {
+			return mongowrapper.KillOp(ctx, client, opID)
+		})
+		termui.RenderCurrentOp(ctx)
+	} else {
+		waitForInterrupt(ctx)
+	}
+	cancel()
+	wg.Wait()
+}
+
+func fetchOperationsPeriodically(
+	ctx context.Context,
+	client *mongo.Client,
+	interval time.Duration,
+) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ops, err := mongowrapper.GetCurrentOps(ctx, client)
+		if err != nil {
+			if !usingUI {
+				logrus.Error(err)
+			}
+		} else if usingUI {
+			termui.UpdateOperations(metrichelper.ExtractOperations(ops))
+		} else {
+			logOperations(metrichelper.ExtractOperations(ops))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func logOperations(ops metrichelper.Operations) {
+	ops = ops.Filter(currentOpFilter)
+	ops.Sort(metrichelper.SortOperationsBySecsRunning)
+	logrus.Infof("%d operations: opid ns op secs_running lock_wait_ms waiting_for_lock client plan", len(ops))
+	for _, op := range ops {
+		logrus.Infof(
+			"    %s %s %s %.1f %.0f %t %s %s\n",
+			op.OpIDString(),
+			op.Ns,
+			op.Op,
+			op.SecsRunning,
+			op.LockWaitMicros/1000,
+			op.WaitingForLock,
+			op.Client,
+			op.PlanSummary,
+		)
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"mongo-monitor/collector"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var demoSeed int64 = 1
+var demoNodes = 1
+
+// demoCmd will run demo function
+var demoCmd = &cobra.Command{
+	Use:   "demo",
+	Short: "Show the metrics of a synthetic load, without mongo",
+	Long:  "Generate the serverStatus of a mongod under a realistic synthetic load and show its metrics, for demos.",
+	Run: func(cmd *cobra.Command, args []string) {
+		i := viper.GetInt("demo-interval")
+		if i == 0 {
+			panic("The parameter interval must be greater than 0.")
+		}
+		if demoNodes < 1 {
+			panic("The parameter nodes must be greater than 0.")
+		}
+		demo(time.Duration(i) * time.Millisecond)
+	},
+}
+
+func init() {
+	pf := demoCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.Uint("interval", 1000, "the interval (millisecond) between two samples")
+	pf.Int64Var(&demoSeed, "seed", 1, "the seed of the synthetic load, the same seed generates the same load")
+	pf.IntVar(&demoNodes, "nodes", 1, "the number of nodes under a synthetic load")
+
+	viper.BindPFlag("demo-interval", demoCmd.PersistentFlags().Lookup("interval"))
+
+	rootCmd.AddCommand(demoCmd)
+}
+
+func demo(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if demoNodes == 1 {
+		runReplay(ctx, cancel, collector.NewSyntheticSource(demoSeed, interval, true))
+		return
+	}
+	sources := make([]collector.StatusSource, demoNodes)
+	for i := range sources {
+		source := collector.NewSyntheticSource(demoSeed+int64(i), interval, true)
+		source.SetHost(fmt.Sprintf("synthetic-%d:27017", i+1))
+		sources[i] = source
+	}
+	runReplay(ctx, cancel, sources...)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"mongo-monitor/alert"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/storage"
+	"mongo-monitor/termui"
+	"mongo-monitor/web"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// eventStorage records the events detected outside of the collectors, it is set by
+// startEvents.
+var eventStorage storage.Storage
+
+// eventDashboard is the web dashboard the events are published to, it is nil without
+// dashboard.
+var eventDashboard *web.Server
+var eventMutex sync.Mutex
+
+// startEvents records the events on s and shows them on the UI.
+func startEvents(s storage.Storage, dashboard *web.Server) {
+	eventMutex.Lock()
+	eventStorage, eventDashboard = s, dashboard
+	eventMutex.Unlock()
+	if usingUI {
+		termui.SetEventsFunc(s.FetchEvents)
+	}
+}
+
+// observeEvent logs an event recorded by a collector or publishes it to the dashboard.
+func observeEvent(event metrichelper.Event) {
+	eventMutex.Lock()
+	dashboard := eventDashboard
+	eventMutex.Unlock()
+	if dashboard != nil {
+		dashboard.PublishEvent(event)
+	}
+	if !usingUI {
+		logrus.WithFields(logrus.Fields{
+			"target": event.Target,
+			"kind":   event.Kind,
+		}).Infof("event: %s", event.Message)
+	}
+}
+
+// recordEvent records an event detected outside of the collectors and observes it.
+func recordEvent(event metrichelper.Event) {
+	eventMutex.Lock()
+	s := eventStorage
+	eventMutex.Unlock()
+	if s == nil {
+		return
+	}
+	if err := s.RecordEvent(event); err != nil {
+		if !usingUI {
+			logrus.Error(err)
+		}
+		return
+	}
+	observeEvent(event)
+}
+
+// recordAlertEvent records the alerts starting to fire as events.
+func recordAlertEvent(a alert.Alert) {
+	if a.State != alert.StateFiring {
+		return
+	}
+	recordEvent(metrichelper.Event{
+		Time:    a.Since,
+		Target:  a.Target,
+		Kind:    metrichelper.EventAlert,
+		Message: fmt.Sprintf("%s %s firing: %s", a.Severity, a.Rule, a.Expr),
+	})
+}
+
+// recordTopologyEvents records the state changes of the members of topology and
+// the change of the featureCompatibilityVersion of the node.
+func recordTopologyEvents(
+	ctx context.Context,
+	client *mongo.Client,
+	detector *metrichelper.EventDetector,
+	topology metrichelper.Topology,
+) {
+	events := detector.DetectTopology(topology)
+	// The featureCompatibilityVersion is unknown before 3.6, there is no event then.
+	version, _ := mongowrapper.GetFeatureCompatibilityVersion(ctx, client)
+	events = append(events, detector.DetectFCV(topology.Host, topology.Time, version)...)
+	for _, event := range events {
+		recordEvent(event)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"mongo-monitor/collector"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var ftdcSpeed = 60.0
+var ftdcFrom = ""
+var ftdcTo = ""
+
+// ftdcCmd will run replayFTDC function
+var ftdcCmd = &cobra.Command{
+	Use:   "ftdc <diagnostic.data or metrics file>",
+	Short: "Replay the serverStatus samples of mongod diagnostic data",
+	Long: "Decode the full-time diagnostic data capture (FTDC) files mongod writes in diagnostic.data " +
+		"and replay their serverStatus samples, without access to the cluster.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, err := parseOptionalTime(ftdcFrom)
+		if err != nil {
+			panic("The parameter from must be a RFC3339 time.")
+		}
+		to, err := parseOptionalTime(ftdcTo)
+		if err != nil {
+			panic("The parameter to must be a RFC3339 time.")
+		}
+		if ftdcSpeed < 0 {
+			panic("The parameter speed must not be negative.")
+		}
+		replayFTDC(args[0], from, to)
+	},
+}
+
+func init() {
+	pf := ftdcCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.Float64Var(&ftdcSpeed, "speed", 60, "the replay speed on UI, relative to the time the samples were taken (0 means as fast as possible)")
+	pf.StringVar(&ftdcFrom, "from", "", "skip the samples taken before this RFC3339 time")
+	pf.StringVar(&ftdcTo, "to", "", "stop at the samples taken after this RFC3339 time")
+
+	rootCmd.AddCommand(ftdcCmd)
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func replayFTDC(path string, from time.Time, to time.Time) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pacer := &collector.Pacer{}
+	if usingUI {
+		pacer.Speed = ftdcSpeed
+	}
+	runReplay(ctx, cancel, collector.NewFTDCSource(path, from, to, pacer))
+}
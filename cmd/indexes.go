@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/storage"
+	"mongo-monitor/termui"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var indexesInterval = 600 * time.Second
+var indexStatsDelay = 100 * time.Millisecond
+var unusedSince = 7 * 24 * time.Hour
+
+// systemDatabases are skipped when iterating user collections.
+var systemDatabases = map[string]bool{"admin": true, "local": true, "config": true}
+
+// indexesCmd will run indexes function
+var indexesCmd = &cobra.Command{
+	Use:   "indexes",
+	Short: "Track the usage of indexes with $indexStats",
+	Long:  "Track accesses and sizes of every index periodically and highlight the unused ones.",
+	Run: func(cmd *cobra.Command, args []string) {
+		i := viper.GetInt("indexes-interval")
+		if i == 0 {
+			panic("The parameter interval must be greater than 0.")
+		}
+		indexesInterval = time.Duration(i) * time.Second
+		indexStatsDelay = time.Duration(viper.GetInt("index-stats-delay")) * time.Millisecond
+		indexes()
+	},
+}
+
+func init() {
+	pf := indexesCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.Uint("interval", 600, "the interval (second) fetching index stats")
+	pf.Uint("index-stats-delay", 100, "the delay (millisecond) between the commands sent for two collections")
+	pf.DurationVar(&unusedSince, "unused-since", 7*24*time.Hour, "report indexes not accessed during this period as unused")
+
+	viper.BindPFlag("indexes-interval", indexesCmd.PersistentFlags().Lookup("interval"))
+	viper.BindPFlag("index-stats-delay", indexesCmd.PersistentFlags().Lookup("index-stats-delay"))
+
+	addURIFlag(indexesCmd)
+	rootCmd.AddCommand(indexesCmd)
+}
+
+func indexes() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := mongowrapper.CreateClient(ctx, mongoURI)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	s := storage.CreateStorage(storage.Memory)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recordIndexesPeriodically(ctx, client, s, indexesInterval)
+	}()
+
+	if usingUI {
+		termui.SetIndexUnusedSince(unusedSince)
+		termui.RenderIndexes(ctx)
+	} else {
+		waitForInterrupt(ctx)
+	}
+	cancel()
+	wg.Wait()
+}
+
+func recordIndexesPeriodically(
+	ctx context.Context,
+	client *mongo.Client,
+	s storage.Storage,
+	interval time.Duration,
+) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		recordIndexes(ctx, client, s)
+		ms, err := s.FetchLastIndexMetricsSlice()
+		if err == nil {
+			if usingUI {
+				termui.UpdateIndexMetricsSlice(ms)
+			} else {
+				logIndexMetrics(ms)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordIndexes records the usage and the size of every index of the user collections.
+func recordIndexes(ctx context.Context, client *mongo.Client, s storage.Storage) {
+	dbs, err := mongowrapper.ListDatabaseNames(ctx, client)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	for _, db := range dbs {
+		if systemDatabases[db] {
+			continue
+		}
+		collections, err := mongowrapper.ListCollectionNames(ctx, client, db)
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+		for _, collection := range collections {
+			recordCollectionIndexes(ctx, client, s, db, collection)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(indexStatsDelay):
+			}
+		}
+	}
+}
+
+func recordCollectionIndexes(
+	ctx context.Context,
+	client *mongo.Client,
+	s storage.Storage,
+	db string,
+	collection string,
+) {
+	stats, err := mongowrapper.GetIndexStats(ctx, client, db, collection)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+	collStats, err := mongowrapper.GetCollStats(ctx, client, db, collection)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	now := time.Now()
+	for _, stat := range stats {
+		var previous *metrichelper.IndexMetrics
+		id := metrichelper.IndexMetrics{Database: db, Collection: collection, Name: stat.Name}.ID()
+		if last, err := s.FetchLastIndexMetrics(id); err == nil {
+			previous = &last
+		}
+		metrics := metrichelper.ExtractIndexMetrics(db, collection, stat, collStats.IndexSizes[stat.Name], previous, now)
+		s.RecordIndexMetrics(*metrics)
+	}
+}
+
+func logIndexMetrics(ms metrichelper.IndexMetricsSlice) {
+	ms.SortBySize()
+	since := time.Now().Add(-unusedSince)
+	logrus.Info("index size ops ops/h idle_since")
+	for _, m := range ms {
+		format := "    %s %s %.0f %.2f %s\n"
+		if m.Unused(since) {
+			format = "    %s %s %.0f %.2f %s UNUSED\n"
+		}
+		logrus.Infof(
+			format,
+			m.ID(),
+			metrichelper.FormatBytes(m.SizeBytes),
+			m.Ops,
+			m.OpsPerHour,
+			m.IdleSince.Format(time.RFC3339),
+		)
+	}
+}
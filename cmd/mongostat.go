@@ -2,13 +2,13 @@ package cmd
 
 import (
 	"context"
-	"fmt"
+	"mongo-monitor/collector"
 	metrichelper "mongo-monitor/metric_helper"
 	"mongo-monitor/mongowrapper"
+	"mongo-monitor/session"
 	"mongo-monitor/storage"
 	"mongo-monitor/termui"
 	"os"
-	"os/signal"
 	"sync"
 	"time"
 
@@ -20,6 +20,12 @@ import (
 
 var usingUI = false
 var interval = 1 * time.Millisecond
+var oplogInterval = 60 * time.Second
+var oplogAlertHours float64
+var topInterval = 1000 * time.Millisecond
+var hotCollectionsCount = 5
+var recordPath = ""
+var nodeURIs []string
 
 // mongostatCmd will run mongostat function
 var mongostatCmd = &cobra.Command{
@@ -32,6 +38,16 @@ var mongostatCmd = &cobra.Command{
 			panic("The parameter interval must be greater than 0.")
 		}
 		interval = time.Duration(i) * time.Millisecond
+		oi := viper.GetInt("oplog-interval")
+		if oi == 0 {
+			panic("The parameter oplog-interval must be greater than 0.")
+		}
+		oplogInterval = time.Duration(oi) * time.Second
+		ti := viper.GetInt("top-interval")
+		if ti == 0 {
+			panic("The parameter top-interval must be greater than 0.")
+		}
+		topInterval = time.Duration(ti) * time.Millisecond
 		mongostat()
 	},
 }
@@ -40,10 +56,20 @@ func init() {
 	pf := mongostatCmd.PersistentFlags()
 
 	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
-	pf.Uint("interval", 1, "the interval (millisecond) updating and fetching mongo data")
+	pf.Uint("interval", 1000, "the interval (millisecond) fetching serverStatus")
+	pf.Uint("oplog-interval", 60, "the interval (second) fetching the oplog window")
+	pf.Float64Var(&oplogAlertHours, "oplog-alert-hours", 0, "warn when the oplog window or the time until a lagging secondary falls off drops below this many hours (0 disables)")
+
+	pf.Uint("top-interval", 1000, "the interval (millisecond) fetching the top command")
+	pf.IntVar(&hotCollectionsCount, "hot-collections", 5, "the number of hottest collections logged without UI")
+	pf.StringVar(&recordPath, "record", "", "record every serverStatus sample to this newline delimited JSON file, see the replay command")
+	pf.StringSliceVar(&nodeURIs, "node", nil, "URI of another mongod to collect the metrics of, like the other members of the replica set (repeatable)")
 
 	viper.BindPFlag("interval", mongostatCmd.PersistentFlags().Lookup("interval"))
+	viper.BindPFlag("oplog-interval", mongostatCmd.PersistentFlags().Lookup("oplog-interval"))
+	viper.BindPFlag("top-interval", mongostatCmd.PersistentFlags().Lookup("top-interval"))
 
+	addURIFlag(mongostatCmd)
 	rootCmd.AddCommand(mongostatCmd)
 }
 
@@ -55,37 +81,8 @@ func mongostat() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-
-		sigs := make(chan os.Signal, 1)
-		done := make(chan bool, 1)
-
-		signal.Notify(sigs, os.Interrupt)
-
-		go func() {
-			_, ok := <-sigs
-			if ok {
-				fmt.Println("Receive single os.Interrupt")
-			} else {
-				fmt.Println("Close the sigs channel")
-			}
-			done <- true
-		}()
-
-	Loop:
-		for {
-			select {
-			case <-done:
-				close(sigs)
-				break Loop
-			case <-ctx.Done():
-				close(sigs)
-				break Loop
-			default:
-			}
-		}
-	_:
+		waitForInterrupt(ctx)
 		cancel()
-		println("cancel")
 	}()
 
 	client, err := mongowrapper.CreateClient(ctx, mongoURI)
@@ -95,103 +92,296 @@ func mongostat() {
 	}
 
 	s := storage.CreateStorage(storage.Memory)
+	startEvents(s, nil)
+	startAnomalyDetection()
+	startAlerting()
+	defer stopAlerting()
+	startSinks()
+	defer stopSinks()
+
+	var recorder *session.Writer
+	if recordPath != "" {
+		f, err := os.Create(recordPath)
+		if err != nil {
+			logrus.Error(err)
+			panic(err)
+		}
+		defer f.Close()
+		recorder = session.NewWriter(f)
+	}
+
+	// The collectors publish their samples on the bus, every consumer handles them in
+	// its own goroutine.
+	samples := collector.NewBus()
+	consumers := sync.WaitGroup{}
+	consumeSamples(ctx, &consumers, samples, func(sample collector.Sample) {
+		observeSample(sample.Status, sample.Metrics)
+	})
+	if usingUI {
+		consumeSamples(ctx, &consumers, samples, func(sample collector.Sample) {
+			updateTermuiMetrics(s, sample.Status.Host)
+		})
+	} else {
+		logMetricsHeader()
+		consumeSamples(ctx, &consumers, samples, func(sample collector.Sample) {
+			if sample.Metrics != nil {
+				logMetrics(*sample.Metrics)
+			}
+		})
+	}
+
+	collectors := sync.WaitGroup{}
+	collectors.Add(1)
+	go func() {
+		defer collectors.Done()
+		c := collector.New(collector.NewLiveSource(client, interval), s)
+		c.Recorder = recorder
+		c.OnSample = samples.Publish
+		c.OnEvent = observeEvent
+		if err := c.Run(ctx); err != nil {
+			logrus.Error(err)
+		}
+		cancel()
+	}()
+
+	for _, uri := range nodeURIs {
+		nodeClient, err := mongowrapper.CreateClient(ctx, uri)
+		if err != nil {
+			logrus.Error(err)
+			panic(err)
+		}
+		collectors.Add(1)
+		go func() {
+			defer collectors.Done()
+			c := collector.New(collector.NewLiveSource(nodeClient, interval), s)
+			c.OnSample = samples.Publish
+			c.OnEvent = observeEvent
+			if err := c.Run(ctx); err != nil && !usingUI {
+				logrus.Error(err)
+			}
+		}()
+	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		recordMetricsPeriodically(ctx, client, s, 1*time.Millisecond)
-		cancel()
+		recordOplogPeriodically(ctx, client, s, oplogInterval)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if usingUI {
-			go func() {
-				termui.Render(ctx)
-				cancel()
-			}()
-			updateTermuiDataPeriodically(ctx, s, 1*time.Millisecond)
-		} else {
-			logMetricsPeriodically(ctx, s, 1*time.Millisecond)
-		}
-		cancel()
+		recordTopPeriodically(ctx, client, s, topInterval)
 	}()
 
+	if usingUI {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fetchOperationsPeriodically(ctx, client, currentOpInterval)
+		}()
+
+		termui.SetOplogAlertHours(oplogAlertHours)
+		termui.SetHistoryFunc(s.FetchMetricsRollup)
+		termui.SetKillOpFunc(func(opID interface{}) error {
+			return mongowrapper.KillOp(ctx, client, opID)
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			termui.Render(ctx)
+			cancel()
+		}()
+	}
+
+	collectors.Wait()
+	samples.Close()
+	consumers.Wait()
 	wg.Wait()
 }
 
-func recordMetricsPeriodically(
+// sampleBuffer is the number of samples a consumer may lag behind before samples
+// are dropped for it.
+const sampleBuffer = 64
+
+// consumeSamples calls fn with the samples published on bus in a goroutine of wg,
+// until the context expires or the bus is closed.
+func consumeSamples(ctx context.Context, wg *sync.WaitGroup, bus *collector.Bus, fn func(collector.Sample)) {
+	subscription := bus.Subscribe(sampleBuffer)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		subscription.Run(ctx, fn)
+	}()
+}
+
+// recordOplogPeriodically records the oplog metrics, the topology of the replica set
+// and its events.
+func recordOplogPeriodically(
 	ctx context.Context,
 	client *mongo.Client,
 	s storage.Storage,
 	interval time.Duration,
 ) error {
+	detector := metrichelper.NewEventDetector()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
+		status := mongowrapper.GetServerStatus(ctx, client)
+		var replStatus *mongowrapper.ReplSetStatus
+		var replErr error
+		if status.Repl != nil {
+			// The node may be a replica set member that cannot run replSetGetStatus yet,
+			// the oplog window is still worth reporting in that case.
+			replStatus, replErr = mongowrapper.GetReplSetStatus(ctx, client)
+			if replErr != nil && !usingUI {
+				logrus.Error(replErr)
+			}
+			recordOplogMetrics(ctx, client, s, status.Host, replStatus)
+		}
+		// Without the replica set status the node would look standalone, the topology
+		// and its events wait for the next status.
+		if status.Host != "" && replErr == nil {
+			topology := metrichelper.ExtractTopology(status.Host, status.LocalTime, replStatus)
+			s.RecordTopology(*topology)
+			recordTopologyEvents(ctx, client, detector, *topology)
+			if usingUI {
+				termui.UpdateTopology(*topology)
+			}
+		}
 		select {
 		case <-ctx.Done():
 			return nil
-		default:
-			status := mongowrapper.GetServerStatus(ctx, client)
-			metrics := metrichelper.ExtractMetrics(status)
-			if metrics != nil {
-				s.RecordMetrics(*metrics)
-			}
-			time.Sleep(interval)
+		case <-ticker.C:
 		}
 	}
 }
 
-func logMetricsPeriodically(
+func recordOplogMetrics(
 	ctx context.Context,
+	client *mongo.Client,
 	s storage.Storage,
-	interval time.Duration,
-) error {
-	logrus.Info("insert query update delete getmore command network_in network_out checkpoint")
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-			metrics, err := s.FetchLastMetrics()
-			if _, ok := err.(*storage.DataNotFound); ok {
-				continue
-			}
-			logrus.Infof(
-				"    *%d    *%d     *%d     *%d       %d       %d        %d       %d          %d\n",
-				int64(metrics.InsertCountPerSecond),
-				int64(metrics.QueryCountPerSecond),
-				int64(metrics.UpdateCountPerSecond),
-				int64(metrics.DeleteCountPerSecond),
-				int64(metrics.GetmoreCountPerSecond),
-				int64(metrics.CommandCountPerSecond),
-				int64(metrics.NetworkInBytesPerSecond),
-				int64(metrics.NetworkOutBytesPerSecond),
-				// int64(status.Connections.Current),
-				int64(metrics.CheckpointCountPerSecond),
-			)
-			time.Sleep(interval)
+	host string,
+	replStatus *mongowrapper.ReplSetStatus,
+) {
+	oplog, err := mongowrapper.GetOplogStats(ctx, client)
+	if err != nil {
+		if !usingUI {
+			logrus.Error(err)
 		}
+		return
+	}
+	metrics := metrichelper.ExtractOplogMetrics(host, oplog, replStatus)
+	if metrics == nil {
+		return
+	}
+	s.RecordOplogMetrics(*metrics)
+	observeAlerts(metrics.Host, metrics.Time, metrics.Values())
+	if usingUI {
+		termui.UpdateOplogMetrics(*metrics)
+		return
+	}
+
+	logrus.Infof(
+		"oplog %s window %.2fh used %.2fGB/%.2fGB churn %.3fGB/h",
+		metrics.Host,
+		metrics.WindowHours,
+		metrics.UsedGB(),
+		metrics.MaxGB(),
+		metrics.GBPerHour,
+	)
+	if metrics.LaggingMember != "" {
+		logrus.Infof(
+			"oplog %s most lagging secondary %s lag %.0fs falls off in %.2fh",
+			metrics.Host,
+			metrics.LaggingMember,
+			metrics.MaxLagSeconds,
+			metrics.HoursUntilFallOff,
+		)
+	}
+	if oplogAlertHours > 0 && metrics.HoursUntilFallOff < oplogAlertHours {
+		logrus.Warnf(
+			"oplog %s has only %.2fh left, below the %.2fh threshold",
+			metrics.Host,
+			metrics.HoursUntilFallOff,
+			oplogAlertHours,
+		)
 	}
 }
 
-func updateTermuiDataPeriodically(
+func recordTopPeriodically(
 	ctx context.Context,
+	client *mongo.Client,
 	s storage.Storage,
 	interval time.Duration,
 ) error {
+	extractor := metrichelper.NewTopExtractor()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
+		top, err := mongowrapper.GetTopStats(ctx, client)
+		if err != nil {
+			if !usingUI {
+				logrus.Error(err)
+			}
+		} else if ms := extractor.Extract(top); ms != nil {
+			s.RecordTopMetrics(ms)
+			if usingUI {
+				termui.UpdateTopMetrics(ms)
+			} else {
+				logTopMetrics(ms)
+			}
+		}
 		select {
 		case <-ctx.Done():
 			return nil
-		default:
-			ms, err := s.FetchLastFewMetricsSlice(50)
-			if _, ok := err.(*storage.DataNotFound); ok {
-				time.Sleep(interval)
-				continue
-			}
-			termui.UpdateMetricsSlice(ms)
-			time.Sleep(interval)
+		case <-ticker.C:
 		}
 	}
 }
+
+func logTopMetrics(ms metrichelper.NamespaceMetricsSlice) {
+	if len(ms) > hotCollectionsCount {
+		ms = ms[:hotCollectionsCount]
+	}
+	logrus.Info("hottest collections: ns total_ms/s read_ms/s write_ms/s read/s write/s")
+	for _, m := range ms {
+		logrus.Infof(
+			"    %s %.1f %.1f %.1f %.1f %.1f\n",
+			m.Ns,
+			m.TotalMillisPerSecond,
+			m.ReadLockMillisPerSecond,
+			m.WriteLockMillisPerSecond,
+			m.ReadOpsPerSecond,
+			m.WriteOpsPerSecond,
+		)
+	}
+}
+
+func logMetricsHeader() {
+	logrus.Info("host insert query update delete getmore command network_in network_out checkpoint")
+}
+
+func logMetrics(metrics metrichelper.Metrics) {
+	logrus.Infof(
+		"    %s *%d    *%d     *%d     *%d       %d       %d        %d       %d          %d\n",
+		metrics.Host,
+		int64(metrics.InsertCountPerSecond),
+		int64(metrics.QueryCountPerSecond),
+		int64(metrics.UpdateCountPerSecond),
+		int64(metrics.DeleteCountPerSecond),
+		int64(metrics.GetmoreCountPerSecond),
+		int64(metrics.CommandCountPerSecond),
+		int64(metrics.NetworkInBytesPerSecond),
+		int64(metrics.NetworkOutBytesPerSecond),
+		// int64(status.Connections.Current),
+		int64(metrics.CheckpointCountPerSecond),
+	)
+}
+
+// updateTermuiMetrics sets the last samples of target on the UI.
+func updateTermuiMetrics(s storage.Storage, target string) {
+	if ms, err := s.FetchLastFewHostMetricsSlice(target, termui.ChartLength); err == nil {
+		termui.UpdateNodeMetricsSlice(target, ms)
+	}
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/termui"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var profileDB = ""
+var profileSlowMs = -1
+var profileDuration = 5 * time.Minute
+var profileInterval = 1000 * time.Millisecond
+var profileJSON = false
+
+// profileCmd will run profile function
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Aggregate the slow queries of the database profiler by query shape",
+	Long: "Tail system.profile of a database and aggregate the operations by query shape. " +
+		"With --slowms the profiler is enabled for --duration and restored afterwards.",
+	Run: func(cmd *cobra.Command, args []string) {
+		i := viper.GetInt("profile-interval")
+		if i == 0 {
+			panic("The parameter interval must be greater than 0.")
+		}
+		profileInterval = time.Duration(i) * time.Millisecond
+		profile()
+	},
+}
+
+func init() {
+	pf := profileCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.StringVar(&profileDB, "db", "", "the database to profile")
+	pf.IntVar(&profileSlowMs, "slowms", -1, "enable the profiler for operations slower than this (millisecond), -1 keeps the current profiling level")
+	pf.DurationVar(&profileDuration, "duration", 5*time.Minute, "how long to profile before restoring the profiling level, 0 means until interrupted")
+	pf.Uint("interval", 1000, "the interval (millisecond) fetching new system.profile documents")
+	pf.BoolVar(&profileJSON, "json", false, "print the aggregated query shapes as JSON when finished")
+
+	viper.BindPFlag("profile-interval", profileCmd.PersistentFlags().Lookup("interval"))
+	cobra.MarkFlagRequired(pf, "db")
+
+	addURIFlag(profileCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+func profile() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := mongowrapper.CreateClient(ctx, mongoURI)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	profileCtx, profileCancel := context.WithCancel(ctx)
+	if profileDuration > 0 {
+		profileCtx, profileCancel = context.WithTimeout(ctx, profileDuration)
+	}
+	defer profileCancel()
+
+	// The tail starts before the profiling level is set, so no operation profiled
+	// once it is set is missed.
+	tail, err := seedProfileTail(ctx, client)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	restore := func() {}
+	if profileSlowMs >= 0 {
+		previous, err := mongowrapper.SetProfilingLevel(ctx, client, profileDB, 1, int32(profileSlowMs))
+		if err != nil {
+			logrus.Error(err)
+			panic(err)
+		}
+		once := sync.Once{}
+		restore = func() {
+			once.Do(func() {
+				// The parent context may already be cancelled, restoring must still happen.
+				_, err := mongowrapper.SetProfilingLevel(context.Background(), client, profileDB, previous.Was, previous.SlowMs)
+				if err != nil {
+					logrus.Error(err)
+				}
+			})
+		}
+		defer restore()
+	}
+
+	aggregator := metrichelper.NewQueryShapeAggregator()
+	status := fmt.Sprintf("Profiling %s until interrupted", profileDB)
+	if profileDuration > 0 {
+		status = fmt.Sprintf("Profiling %s until %s", profileDB, time.Now().Add(profileDuration).Format("15:04:05"))
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tailProfilePeriodically(profileCtx, client, tail, aggregator, profileInterval)
+		restore()
+		if usingUI {
+			termui.SetQueryShapesStatus(fmt.Sprintf("Finished profiling %s, the profiling level is restored", profileDB))
+		} else {
+			cancel()
+		}
+	}()
+
+	if usingUI {
+		termui.SetQueryShapesStatus(status)
+		termui.RenderQueryShapes(ctx, "Database Profiler")
+	} else {
+		logrus.Info(status)
+		waitForInterrupt(ctx)
+	}
+	cancel()
+	wg.Wait()
+
+	if profileJSON {
+		printQueryShapesJSON(aggregator.Slice())
+	} else if !usingUI {
+		logQueryShapes(aggregator.Slice())
+	}
+}
+
+// profileTail follows system.profile from its newest document. The documents are
+// read again from the millisecond of the last one, as several may share it, the
+// ones of that millisecond already read are skipped.
+type profileTail struct {
+	last time.Time
+	seen map[string]bool
+}
+
+// newProfileTail returns a tail starting after the documents of last.
+func newProfileTail(last time.Time) *profileTail {
+	return &profileTail{last: last, seen: map[string]bool{}}
+}
+
+// next returns the entries not read yet among entries, read since t.last oldest first.
+func (t *profileTail) next(entries []mongowrapper.ProfileEntry) []mongowrapper.ProfileEntry {
+	unread := []mongowrapper.ProfileEntry{}
+	for _, entry := range entries {
+		key := string(entry.Raw)
+		if entry.Ts.After(t.last) {
+			t.last = entry.Ts
+			t.seen = map[string]bool{}
+		} else if entry.Ts.Before(t.last) || t.seen[key] {
+			continue
+		}
+		t.seen[key] = true
+		unread = append(unread, entry)
+	}
+	return unread
+}
+
+// seedProfileTail returns a tail starting after the newest document of system.profile,
+// the server clock rather than the local one telling which documents are new.
+func seedProfileTail(ctx context.Context, client *mongo.Client) (*profileTail, error) {
+	last, err := mongowrapper.GetLastProfileTime(ctx, client, profileDB)
+	if err != nil {
+		return nil, err
+	}
+	tail := newProfileTail(last)
+	if last.IsZero() {
+		return tail, nil
+	}
+	entries, err := mongowrapper.GetProfileEntriesSince(ctx, client, profileDB, last)
+	if err != nil {
+		return nil, err
+	}
+	tail.next(entries)
+	return tail, nil
+}
+
+func tailProfilePeriodically(
+	ctx context.Context,
+	client *mongo.Client,
+	tail *profileTail,
+	aggregator *metrichelper.QueryShapeAggregator,
+	interval time.Duration,
+) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	profileNs := profileDB + ".system.profile"
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		entries, err := mongowrapper.GetProfileEntriesSince(ctx, client, profileDB, tail.last)
+		if err != nil {
+			if !usingUI && ctx.Err() == nil {
+				logrus.Error(err)
+			}
+			continue
+		}
+		for _, entry := range tail.next(entries) {
+			// Skip the queries this command sends to system.profile.
+			if entry.Ns == profileNs {
+				continue
+			}
+			aggregator.Add(metrichelper.ExtractProfileOperation(entry))
+		}
+		if usingUI {
+			termui.UpdateQueryShapes(aggregator.Slice())
+		}
+	}
+}
+
+func printQueryShapesJSON(ss metrichelper.QueryShapeStatsSlice) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(ss); err != nil {
+		panic(err)
+	}
+}
+
+func logQueryShapes(ss metrichelper.QueryShapeStatsSlice) {
+	logrus.Info("ns count total_ms avg_ms max_ms docs_examined/returned plan shape")
+	for _, s := range ss {
+		logrus.Infof(
+			"    %s %.0f %.0f %.1f %.0f %.1f %s %s\n",
+			s.Ns,
+			s.Count,
+			s.TotalMillis,
+			s.AvgMillis,
+			s.MaxMillis,
+			s.ExaminedPerReturned(),
+			s.PlanSummary,
+			s.Shape,
+		)
+	}
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"mongo-monitor/mongowrapper"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func profileEntry(t *testing.T, ts time.Time, op string) mongowrapper.ProfileEntry {
+	raw, err := bson.Marshal(bson.D{{Key: "op", Value: op}, {Key: "ts", Value: ts}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mongowrapper.ProfileEntry{Op: op, Ts: ts, Raw: raw}
+}
+
+func TestProfileTailSkipsTheEntriesAlreadyRead(t *testing.T) {
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	seeded := profileEntry(t, start, "seeded")
+	tail := newProfileTail(start)
+	if got := tail.next([]mongowrapper.ProfileEntry{seeded}); len(got) != 1 {
+		t.Fatalf("the seeding read returned %d entries, want 1", len(got))
+	}
+
+	// A second entry of the same millisecond comes after the first read.
+	sameMilli := profileEntry(t, start, "same millisecond")
+	later := profileEntry(t, start.Add(time.Millisecond), "later")
+	got := tail.next([]mongowrapper.ProfileEntry{seeded, sameMilli, later})
+	if len(got) != 2 || got[0].Op != "same millisecond" || got[1].Op != "later" {
+		t.Fatalf("next() = %v, want the same millisecond and later entries", got)
+	}
+
+	got = tail.next([]mongowrapper.ProfileEntry{later})
+	if len(got) != 0 {
+		t.Fatalf("next() = %v, want no entry read again", got)
+	}
+}
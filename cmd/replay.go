@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"mongo-monitor/collector"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/session"
+	"mongo-monitor/storage"
+	"mongo-monitor/termui"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var replaySpeed = 1.0
+var replayStep = false
+
+// replayCmd will run replaySession function
+var replayCmd = &cobra.Command{
+	Use:   "replay <session.ndjson>",
+	Short: "Replay a session recorded by mongostat --record",
+	Long:  "Feed the serverStatus samples recorded by mongostat --record back through the metrics, at real speed, N times the real speed or step by step.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if replaySpeed < 0 {
+			panic("The parameter speed must not be negative.")
+		}
+		replaySession(args[0])
+	},
+}
+
+func init() {
+	pf := replayCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.Float64Var(&replaySpeed, "speed", 1, "the replay speed relative to the recording (0 means as fast as possible)")
+	pf.BoolVar(&replayStep, "step", false, "wait for n/Space on UI, or Enter without UI, before each sample")
+
+	rootCmd.AddCommand(replayCmd)
+}
+
+func replaySession(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pacer := &collector.Pacer{Speed: replaySpeed}
+	if replayStep {
+		steps := make(chan struct{})
+		pacer.Steps = steps
+		if usingUI {
+			// The step is sent aside from the key handler, so a key pressed before the
+			// pacer waits is not lost and the UI does not block.
+			termui.SetReplayStepFunc(func() {
+				go sendStep(ctx, steps)
+			})
+		} else {
+			go func() {
+				scanner := bufio.NewScanner(os.Stdin)
+				for scanner.Scan() && sendStep(ctx, steps) {
+				}
+			}()
+		}
+	}
+
+	runReplay(ctx, cancel, collector.NewSessionSource(session.NewReader(f), pacer))
+}
+
+// sendStep sends a step to the pacer and reports whether it was sent before ctx is done.
+func sendStep(ctx context.Context, steps chan<- struct{}) bool {
+	select {
+	case steps <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// runReplay collects the samples of replayed sources, one for each node, showing them
+// on the replay UI or logging them.
+func runReplay(ctx context.Context, cancel context.CancelFunc, sources ...collector.StatusSource) {
+	s := storage.CreateStorage(storage.Memory)
+	startEvents(s, nil)
+	startAnomalyDetection()
+	startAlerting()
+	defer stopAlerting()
+	startSinks()
+	defer stopSinks()
+	onSample := func(status *mongowrapper.ServerStatusStats, metrics *metrichelper.Metrics) {
+		observeSample(status, metrics)
+		if !usingUI {
+			if metrics != nil {
+				logMetrics(*metrics)
+			}
+			return
+		}
+		termui.SetReplayStatus(fmt.Sprintf("%s at %s", status.Host, status.LocalTime.Format("2006-01-02 15:04:05")))
+		if ms, err := s.FetchLastFewHostMetricsSlice(status.Host, termui.ChartLength); err == nil {
+			termui.UpdateNodeMetricsSlice(status.Host, ms)
+		}
+	}
+
+	if !usingUI {
+		logMetricsHeader()
+	} else {
+		termui.SetHistoryFunc(s.FetchMetricsRollup)
+	}
+	// The collectors share the samples callback, it is not called concurrently.
+	var mutex sync.Mutex
+	errs := make(chan error, len(sources))
+	wg := sync.WaitGroup{}
+	for _, source := range sources {
+		c := collector.New(source, s)
+		c.OnSample = func(status *mongowrapper.ServerStatusStats, metrics *metrichelper.Metrics) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			onSample(status, metrics)
+		}
+		c.OnEvent = func(event metrichelper.Event) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			observeEvent(event)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Run(ctx); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	if !usingUI {
+		wg.Wait()
+		close(errs)
+		if err := <-errs; err != nil {
+			logrus.Error(err)
+			panic(err)
+		}
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(errs)
+		if err := <-errs; err != nil {
+			termui.SetReplayStatus(err.Error())
+		} else if ctx.Err() == nil {
+			termui.SetReplayStepFunc(nil)
+			termui.SetReplayStatus("End of the replay")
+		}
+		close(done)
+	}()
+
+	termui.RenderReplay(ctx)
+	cancel()
+	<-done
+}
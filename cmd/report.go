@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/storage"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var reportJSON = false
+var reportUnusedOnly = false
+
+// reportCmd groups the one-shot reports
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Print one-shot reports",
+	Long:  "Print one-shot reports about the monitored mongo.",
+}
+
+// reportIndexesCmd will run reportIndexes function
+var reportIndexesCmd = &cobra.Command{
+	Use:   "indexes",
+	Short: "Report the usage and the size of every index",
+	Long:  "Report accesses from $indexStats and sizes from collStats of every index, unused indexes first.",
+	Run: func(cmd *cobra.Command, args []string) {
+		reportIndexes()
+	},
+}
+
+func init() {
+	pf := reportCmd.PersistentFlags()
+
+	pf.BoolVar(&reportJSON, "json", false, "print the report as JSON")
+
+	rif := reportIndexesCmd.Flags()
+	rif.BoolVar(&reportUnusedOnly, "unused-only", false, "only report unused indexes")
+	rif.DurationVar(&unusedSince, "unused-since", 7*24*time.Hour, "report indexes not accessed during this period as unused")
+
+	addURIFlag(reportIndexesCmd)
+	reportCmd.AddCommand(reportIndexesCmd)
+	rootCmd.AddCommand(reportCmd)
+}
+
+type indexReport struct {
+	metrichelper.IndexMetrics
+	Unused bool `json:"unused"`
+}
+
+func reportIndexes() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := mongowrapper.CreateClient(ctx, mongoURI)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	s := storage.CreateStorage(storage.Memory)
+	recordIndexes(ctx, client, s)
+	ms, err := s.FetchLastIndexMetricsSlice()
+	if err != nil {
+		ms = metrichelper.IndexMetricsSlice{}
+	}
+	ms.SortBySize()
+
+	since := time.Now().Add(-unusedSince)
+	reports := []indexReport{}
+	for _, unused := range []bool{true, false} {
+		for _, m := range ms {
+			if m.Unused(since) != unused || (reportUnusedOnly && !unused) {
+				continue
+			}
+			reports = append(reports, indexReport{IndexMetrics: m, Unused: unused})
+		}
+	}
+
+	if reportJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	fmt.Printf("%-64s %12s %14s %s\n", "INDEX", "SIZE", "OPS", "IDLE SINCE")
+	for _, r := range reports {
+		unused := ""
+		if r.Unused {
+			unused = "  UNUSED"
+		}
+		fmt.Printf(
+			"%-64s %12s %14.0f %s%s\n",
+			r.ID(),
+			metrichelper.FormatBytes(r.SizeBytes),
+			r.Ops,
+			r.IdleSince.Format(time.RFC3339),
+			unused,
+		)
+	}
+}
@@ -9,6 +9,7 @@ import (
 )
 
 var mongoURI string = "mongodb://127.0.0.1:27017"
+var configFile = ""
 
 var rootCmd = &cobra.Command{
 	Use:   "mongo-monitor",
@@ -28,9 +29,29 @@ func init() {
 	pf := rootCmd.PersistentFlags()
 
 	pf.Bool("debug", false, "Run the program with debug mode")
-	pf.StringVar(&mongoURI, "uri", "mongodb://127.0.0.1:27017", "URI of mongo you want to monitor")
+	pf.StringVar(&configFile, "config", "", "the configuration file (TOML) holding the alert rules")
 
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 
+	cobra.OnInitialize(readConfig)
+}
+
+// addURIFlag adds the required --uri flag to a command connecting to mongo, the
+// commands reading files do not need it.
+func addURIFlag(cmd *cobra.Command) {
+	pf := cmd.PersistentFlags()
+	pf.StringVar(&mongoURI, "uri", "mongodb://127.0.0.1:27017", "URI of mongo you want to monitor")
 	cobra.MarkFlagRequired(pf, "uri")
 }
+
+// readConfig reads the configuration file into viper.
+func readConfig() {
+	if configFile == "" {
+		return
+	}
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
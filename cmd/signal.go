@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// waitForInterrupt blocks until os.Interrupt is received or the context expires.
+func waitForInterrupt(ctx context.Context) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	select {
+	case <-sigs:
+		fmt.Println("Receive single os.Interrupt")
+	case <-ctx.Done():
+	}
+}
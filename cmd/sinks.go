@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/sink"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// sinkDispatcher writes the samples to the sinks of the configuration file, it is
+// nil when there is no sink.
+var sinkDispatcher *sink.Dispatcher
+
+// startSinks creates and starts sinkDispatcher from the configuration file.
+func startSinks() {
+	config := sink.Config{}
+	if err := viper.UnmarshalKey("sinks", &config); err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	d, err := sink.NewDispatcher(config)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+	if d == nil {
+		return
+	}
+	if !usingUI {
+		d.OnError = func(s sink.Sink, points int, err error) {
+			logrus.Errorf("%s failed to write %d points, kept for the next flush: %v", s.Name(), points, err)
+		}
+		d.OnReject = func(s sink.Sink, points int, err error) {
+			logrus.Errorf("%s rejected %d points, dropped: %v", s.Name(), points, err)
+		}
+	}
+	d.Start()
+	sinkDispatcher = d
+}
+
+// stopSinks writes the points waiting to be written.
+func stopSinks() {
+	if sinkDispatcher != nil {
+		sinkDispatcher.Close()
+	}
+}
+
+// writeToSinks queues the values of a sample for the sinks.
+func writeToSinks(status *mongowrapper.ServerStatusStats, values map[string]float64) {
+	if sinkDispatcher == nil {
+		return
+	}
+	p := sink.Point{
+		Target:  status.Host,
+		Time:    status.LocalTime,
+		Version: status.Version,
+		Values:  values,
+	}
+	if status.Repl != nil {
+		p.ReplicaSet = status.Repl.SetName
+	}
+	sinkDispatcher.Add(p)
+}
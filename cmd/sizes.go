@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/storage"
+	"mongo-monitor/termui"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var sizesInterval = 300 * time.Second
+var collStatsEnabled = false
+var collStatsDelay = 100 * time.Millisecond
+var maxCollStatsPerRound = 0
+var sizesSort = "size"
+
+// sizesCmd will run sizes function
+var sizesCmd = &cobra.Command{
+	Use:   "sizes",
+	Short: "Track the size and the growth of databases and collections",
+	Long:  "Track dataSize, storageSize, indexSize and document count of every database and, optionally, every collection.",
+	Run: func(cmd *cobra.Command, args []string) {
+		i := viper.GetInt("sizes-interval")
+		if i == 0 {
+			panic("The parameter interval must be greater than 0.")
+		}
+		sizesInterval = time.Duration(i) * time.Second
+		collStatsDelay = time.Duration(viper.GetInt("coll-stats-delay")) * time.Millisecond
+		if sizesSort != "size" && sizesSort != "growth" {
+			panic("The parameter sort must be size or growth.")
+		}
+		sizes()
+	},
+}
+
+func init() {
+	pf := sizesCmd.PersistentFlags()
+
+	pf.BoolVar(&usingUI, "ui", false, "if you want to use UI or not")
+	pf.Uint("interval", 300, "the interval (second) fetching database and collection sizes")
+	pf.BoolVar(&collStatsEnabled, "collections", false, "fetch collStats of every collection as well")
+	pf.Uint("coll-stats-delay", 100, "the delay (millisecond) between two collStats commands")
+	pf.IntVar(&maxCollStatsPerRound, "max-collections", 0, "the maximum number of collStats commands per round (0 means no limit)")
+	pf.StringVar(&sizesSort, "sort", "size", "sort the logged table by size or growth")
+
+	viper.BindPFlag("sizes-interval", sizesCmd.PersistentFlags().Lookup("interval"))
+	viper.BindPFlag("coll-stats-delay", sizesCmd.PersistentFlags().Lookup("coll-stats-delay"))
+
+	addURIFlag(sizesCmd)
+	rootCmd.AddCommand(sizesCmd)
+}
+
+func sizes() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := mongowrapper.CreateClient(ctx, mongoURI)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	s := storage.CreateStorage(storage.Memory)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recordSizesPeriodically(ctx, client, s, sizesInterval)
+	}()
+
+	if usingUI {
+		termui.RenderSizes(ctx)
+	} else {
+		waitForInterrupt(ctx)
+	}
+	cancel()
+	wg.Wait()
+}
+
+func recordSizesPeriodically(
+	ctx context.Context,
+	client *mongo.Client,
+	s storage.Storage,
+	interval time.Duration,
+) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// collStatsOffset rotates the collections fetched in each round when
+	// max-collections limits the number of collStats commands.
+	collStatsOffset := 0
+	for {
+		collStatsOffset = recordSizes(ctx, client, s, collStatsOffset)
+		ms, err := s.FetchLastSizeMetricsSlice()
+		if err == nil {
+			if usingUI {
+				termui.UpdateSizeMetricsSlice(ms)
+			} else {
+				logSizeMetrics(ms)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func recordSizes(ctx context.Context, client *mongo.Client, s storage.Storage, collStatsOffset int) int {
+	dbs, err := mongowrapper.ListDatabaseNames(ctx, client)
+	if err != nil {
+		logSizesError(err)
+		return collStatsOffset
+	}
+
+	namespaces := [][2]string{}
+	for _, db := range dbs {
+		stats, err := mongowrapper.GetDBStats(ctx, client, db)
+		if err != nil {
+			logSizesError(err)
+			continue
+		}
+		recordSizeMetrics(s, db, "", func(previous *metrichelper.SizeMetrics) *metrichelper.SizeMetrics {
+			return metrichelper.ExtractDBSizeMetrics(stats, previous, time.Now())
+		})
+
+		if !collStatsEnabled {
+			continue
+		}
+		collections, err := mongowrapper.ListCollectionNames(ctx, client, db)
+		if err != nil {
+			logSizesError(err)
+			continue
+		}
+		for _, collection := range collections {
+			namespaces = append(namespaces, [2]string{db, collection})
+		}
+	}
+
+	if len(namespaces) == 0 {
+		return 0
+	}
+	count := len(namespaces)
+	if maxCollStatsPerRound > 0 && maxCollStatsPerRound < count {
+		count = maxCollStatsPerRound
+	}
+	collStatsOffset = collStatsOffset % len(namespaces)
+	for i := 0; i < count; i++ {
+		ns := namespaces[(collStatsOffset+i)%len(namespaces)]
+		stats, err := mongowrapper.GetCollStats(ctx, client, ns[0], ns[1])
+		if err != nil {
+			logSizesError(err)
+		} else {
+			recordSizeMetrics(s, ns[0], ns[1], func(previous *metrichelper.SizeMetrics) *metrichelper.SizeMetrics {
+				return metrichelper.ExtractCollSizeMetrics(ns[0], ns[1], stats, previous, time.Now())
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return collStatsOffset
+		case <-time.After(collStatsDelay):
+		}
+	}
+	return collStatsOffset + count
+}
+
+// logSizesError shows an error fetching the sizes on the UI, or logs it.
+func logSizesError(err error) {
+	if usingUI {
+		termui.SetSizesStatus(err.Error())
+		return
+	}
+	logrus.Error(err)
+}
+
+func recordSizeMetrics(
+	s storage.Storage,
+	db string,
+	collection string,
+	extract func(previous *metrichelper.SizeMetrics) *metrichelper.SizeMetrics,
+) {
+	var previous *metrichelper.SizeMetrics
+	namespace := metrichelper.SizeMetrics{Database: db, Collection: collection}.Namespace()
+	if last, err := s.FetchLastSizeMetrics(namespace); err == nil {
+		previous = &last
+	}
+	if metrics := extract(previous); metrics != nil {
+		s.RecordSizeMetrics(*metrics)
+	}
+}
+
+func logSizeMetrics(ms metrichelper.SizeMetricsSlice) {
+	if sizesSort == "growth" {
+		ms.SortByGrowth()
+	} else {
+		ms.SortBySize()
+	}
+	logrus.Info("namespace data storage indexes documents growth/h")
+	for _, m := range ms {
+		logrus.Infof(
+			"    %s %s %s %s %.0f %s\n",
+			m.Namespace(),
+			metrichelper.FormatBytes(m.DataSize),
+			metrichelper.FormatBytes(m.StorageSize),
+			metrichelper.FormatBytes(m.IndexSize),
+			m.Count,
+			metrichelper.FormatBytes(m.DataGrowthPerHour),
+		)
+	}
+}
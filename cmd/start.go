@@ -1,22 +1,149 @@
 package cmd
 
 import (
+	"context"
+	"mongo-monitor/api"
+	"mongo-monitor/collector"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/storage"
+	"mongo-monitor/web"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+var listenAddress = "localhost:8080"
+var retentionAge = 24 * time.Hour
+var retentionCount = 0
+
 // runCmd represents the run command
 var runCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start monitoring.",
-	Long:  "Start monitoring.",
+	Long:  "Start monitoring in the background: collect the metrics, evaluate the alert rules, and serve a web dashboard and the metrics as JSON over HTTP.",
 	Run: func(cmd *cobra.Command, args []string) {
+		i := viper.GetInt("start-interval")
+		if i == 0 {
+			panic("The parameter interval must be greater than 0.")
+		}
+		interval = time.Duration(i) * time.Millisecond
+		oi := viper.GetInt("start-oplog-interval")
+		if oi == 0 {
+			panic("The parameter oplog-interval must be greater than 0.")
+		}
+		oplogInterval = time.Duration(oi) * time.Second
 		startMonitoring()
 	},
 }
 
 func init() {
+	pf := runCmd.PersistentFlags()
+
+	pf.Uint("interval", 1000, "the interval (millisecond) fetching serverStatus")
+	pf.Uint("oplog-interval", 60, "the interval (second) fetching the oplog window and the topology")
+	pf.StringVar(&listenAddress, "listen", "localhost:8080", "the address serving the web dashboard and the HTTP API")
+	pf.DurationVar(&retentionAge, "retention", 24*time.Hour, "how long the samples are kept, 0 keeps them all")
+	pf.IntVar(&retentionCount, "retention-count", 0, "the most samples kept, 0 keeps them all")
+
+	viper.BindPFlag("start-interval", runCmd.PersistentFlags().Lookup("interval"))
+	viper.BindPFlag("start-oplog-interval", runCmd.PersistentFlags().Lookup("oplog-interval"))
+
+	addURIFlag(runCmd)
 	rootCmd.AddCommand(runCmd)
 }
 
 func startMonitoring() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := mongowrapper.CreateClient(ctx, mongoURI)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	s := storage.CreateStorage(storage.Memory)
+	s.SetRetention(storage.Retention{Age: retentionAge, Count: retentionCount})
+	dashboard := web.NewServer()
+	startEvents(s, dashboard)
+	startAnomalyDetection()
+	startAlerting()
+	defer stopAlerting()
+	startSinks()
+	defer stopSinks()
+
+	samples := collector.NewBus()
+	consumers := sync.WaitGroup{}
+	consumeSamples(ctx, &consumers, samples, func(sample collector.Sample) {
+		observeSample(sample.Status, sample.Metrics)
+	})
+	consumeSamples(ctx, &consumers, samples, func(sample collector.Sample) {
+		if sample.Metrics != nil {
+			dashboard.Publish(*sample.Metrics)
+		}
+	})
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c := collector.New(collector.NewLiveSource(client, interval), s)
+		c.OnSample = samples.Publish
+		c.OnEvent = observeEvent
+		if err := c.Run(ctx); err != nil {
+			logrus.Error(err)
+		}
+		samples.Close()
+		cancel()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recordOplogPeriodically(ctx, client, s, oplogInterval)
+	}()
+
+	server := serveHTTP(ctx, cancel, &wg, s, dashboard)
+	waitForInterrupt(ctx)
+	cancel()
+	dashboard.Close()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	server.Shutdown(shutdownCtx)
+	wg.Wait()
+	consumers.Wait()
+}
+
+// serveHTTP serves the web dashboard and the HTTP API of s on listenAddress until it
+// is shut down, the context is cancelled when they cannot be served.
+func serveHTTP(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	wg *sync.WaitGroup,
+	s storage.Storage,
+	dashboard *web.Server,
+) *http.Server {
+	apiServer := api.NewServer(s)
+	if alertEngine != nil {
+		apiServer.Alerts = alertEngine.Alerts
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/api/", apiServer)
+	mux.Handle("/", dashboard)
+	server := &http.Server{Addr: listenAddress, Handler: mux}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logrus.Infof("serving the dashboard on http://%s/ and the API on http://%s/api/v1/", listenAddress, listenAddress)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			logrus.Error(err)
+			cancel()
+		}
+	}()
+	return server
 }
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"mongo-monitor/alert"
+	"mongo-monitor/notify"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var testNotifyTarget = "test:27017"
+var testNotifyResolved = false
+
+// testNotifyCmd will run testNotify function
+var testNotifyCmd = &cobra.Command{
+	Use:   "test-notify",
+	Short: "Send a test alert to the notifiers of the configuration file",
+	Long:  "Send a test alert to every webhook, Slack webhook and email recipient of the configuration file given by --config.",
+	Run: func(cmd *cobra.Command, args []string) {
+		testNotify()
+	},
+}
+
+func init() {
+	pf := testNotifyCmd.PersistentFlags()
+
+	pf.StringVar(&testNotifyTarget, "target", "test:27017", "the target of the test alert")
+	pf.BoolVar(&testNotifyResolved, "resolved", false, "send the test alert as resolved instead of firing")
+
+	rootCmd.AddCommand(testNotifyCmd)
+}
+
+func testNotify() {
+	d := newDispatcher()
+	if d == nil {
+		fmt.Println("No notifier is configured, see the [notify] section of --config.")
+		os.Exit(-1)
+	}
+
+	now := time.Now()
+	a := alert.Alert{
+		Rule:        "test alert",
+		Severity:    "warning",
+		Target:      testNotifyTarget,
+		State:       alert.StateFiring,
+		Expr:        "test_value > 1",
+		Since:       now,
+		ActiveSince: now,
+		Values:      map[string]float64{"test_value": 2},
+	}
+	if testNotifyResolved {
+		a.State = alert.StateResolved
+		a.Values["test_value"] = 0
+	}
+	n := notify.Notification{Target: testNotifyTarget, Alerts: []alert.Alert{a}, Time: now}
+
+	// The failures are reported below.
+	d.OnError = nil
+	errs := d.Send(context.Background(), n)
+	for _, notifier := range d.Notifiers() {
+		if err, ok := errs[notifier]; ok {
+			fmt.Printf("FAIL %s: %v\n", notifier.Name(), err)
+		} else {
+			fmt.Printf("OK   %s\n", notifier.Name())
+		}
+	}
+	if len(errs) > 0 {
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"context"
+	"sync"
+
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+)
+
+// Sample is a sample collected and its metrics, the metrics are nil for the first
+// sample and the samples following a restart.
+type Sample struct {
+	Status  *mongowrapper.ServerStatusStats
+	Metrics *metrichelper.Metrics
+}
+
+// Subscription receives the samples published on a bus. Its buffer keeps the last
+// samples, the oldest are dropped when the subscriber falls behind.
+type Subscription struct {
+	samples chan Sample
+	dropped int
+	mutex   sync.Mutex
+}
+
+// Run calls fn with every sample until the context expires, or until the bus is
+// closed and the samples left in the buffer are handled.
+func (s *Subscription) Run(ctx context.Context, fn func(Sample)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sample, ok := <-s.samples:
+			if !ok {
+				return
+			}
+			fn(sample)
+		}
+	}
+}
+
+// Dropped returns the number of samples dropped because the buffer was full.
+func (s *Subscription) Dropped() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.dropped
+}
+
+// Bus publishes the samples of the collectors to their consumers, each consumer
+// having its own buffer so a slow one delays neither the collectors nor the others.
+type Bus struct {
+	subscriptions []*Subscription
+	closed        bool
+	mutex         sync.Mutex
+}
+
+// NewBus returns a bus without subscription.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe returns a subscription to the samples published from now on, buffering
+// up to buffer samples.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	s := &Subscription{samples: make(chan Sample, buffer)}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		close(s.samples)
+		return s
+	}
+	b.subscriptions = append(b.subscriptions, s)
+	return s
+}
+
+// Publish sends a sample to every subscription without blocking, it is a SampleFunc.
+// The oldest sample of a full buffer is dropped for the new one.
+func (b *Bus) Publish(status *mongowrapper.ServerStatusStats, metrics *metrichelper.Metrics) {
+	sample := Sample{Status: status, Metrics: metrics}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return
+	}
+	// The samples are only sent with the mutex held, so a buffer has room once a
+	// sample is taken out of it.
+	for _, s := range b.subscriptions {
+		select {
+		case s.samples <- sample:
+			continue
+		default:
+		}
+		select {
+		case <-s.samples:
+			s.mutex.Lock()
+			s.dropped++
+			s.mutex.Unlock()
+		default:
+		}
+		select {
+		case s.samples <- sample:
+		default:
+		}
+	}
+}
+
+// Close ends the subscriptions once their buffers are handled, the samples published
+// after are dropped.
+func (b *Bus) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, s := range b.subscriptions {
+		close(s.samples)
+	}
+}
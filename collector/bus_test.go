@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+// publish publishes the samples whose metrics hold the query counts on b.
+func publish(b *Bus, queries ...float64) {
+	for _, q := range queries {
+		b.Publish(nil, &metrichelper.Metrics{QueryCountPerSecond: q})
+	}
+}
+
+// received runs s until the bus is closed or the context expires, and returns the
+// query counts of the samples received.
+func received(ctx context.Context, s *Subscription) []float64 {
+	queries := []float64{}
+	s.Run(ctx, func(sample Sample) {
+		queries = append(queries, sample.Metrics.QueryCountPerSecond)
+	})
+	return queries
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBusDropsTheOldestSamples(t *testing.T) {
+	b := NewBus()
+	slow := b.Subscribe(2)
+	fast := b.Subscribe(10)
+	publish(b, 1, 2, 3, 4, 5)
+	b.Close()
+
+	if got := received(context.Background(), slow); !equalFloats(got, []float64{4, 5}) {
+		t.Errorf("slow subscription received %v, want the last 2 samples", got)
+	}
+	if slow.Dropped() != 3 {
+		t.Errorf("slow subscription dropped %d samples, want 3", slow.Dropped())
+	}
+	if got := received(context.Background(), fast); !equalFloats(got, []float64{1, 2, 3, 4, 5}) {
+		t.Errorf("fast subscription received %v, want every sample", got)
+	}
+	if fast.Dropped() != 0 {
+		t.Errorf("fast subscription dropped %d samples, want none", fast.Dropped())
+	}
+}
+
+func TestBusClose(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe(10)
+	publish(b, 1, 2)
+	b.Close()
+	b.Close()
+	publish(b, 3)
+
+	if got := received(context.Background(), s); !equalFloats(got, []float64{1, 2}) {
+		t.Errorf("received %v, want the samples published before close", got)
+	}
+	if got := received(context.Background(), b.Subscribe(10)); len(got) != 0 {
+		t.Errorf("subscription after close received %v, want none", got)
+	}
+}
+
+func TestSubscriptionStopsWithContext(t *testing.T) {
+	b := NewBus()
+	defer b.Close()
+	s := b.Subscribe(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan []float64)
+	go func() {
+		done <- received(ctx, s)
+	}()
+	publish(b, 1)
+	cancel()
+
+	select {
+	case got := <-done:
+		if len(got) > 1 {
+			t.Errorf("received %v, want at most the sample published before the context expired", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once the context expired")
+	}
+	// The bus does not block on a subscription no longer read.
+	publish(b, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)
+}
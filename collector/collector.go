@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/session"
+	"mongo-monitor/storage"
+)
+
+// StatusSource provides the serverStatus samples the metrics are extracted from.
+type StatusSource interface {
+	// Next blocks until the next sample is due and returns it, io.EOF is returned
+	// once the source is exhausted.
+	Next(ctx context.Context) (*mongowrapper.ServerStatusStats, error)
+}
+
+// SampleFunc is called with every sample collected and its metrics, the metrics are
+// nil for the first sample and the samples following a restart.
+type SampleFunc func(status *mongowrapper.ServerStatusStats, metrics *metrichelper.Metrics)
+
+// EventFunc is called with every event detected in the samples.
+type EventFunc func(event metrichelper.Event)
+
+// Collector extracts the metrics of the samples of a source and records them on a storage.
+type Collector struct {
+	source    StatusSource
+	storage   storage.Storage
+	extractor *metrichelper.MetricsExtractor
+	detector  *metrichelper.EventDetector
+
+	// Recorder records every sample when it is not nil.
+	Recorder *session.Writer
+	// OnSample is called after every sample is recorded when it is not nil.
+	OnSample SampleFunc
+	// OnEvent is called after every event is recorded when it is not nil.
+	OnEvent EventFunc
+}
+
+// New returns a collector recording the metrics of source on s.
+func New(source StatusSource, s storage.Storage) *Collector {
+	return &Collector{
+		source:    source,
+		storage:   s,
+		extractor: metrichelper.NewMetricsExtractor(),
+		detector:  metrichelper.NewEventDetector(),
+	}
+}
+
+// Run collects the samples until the context expires or the source is exhausted.
+func (c *Collector) Run(ctx context.Context) error {
+	for {
+		status, err := c.source.Next(ctx)
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.collect(status); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Collector) collect(status *mongowrapper.ServerStatusStats) error {
+	if c.Recorder != nil {
+		if err := c.Recorder.Write(time.Now(), status); err != nil {
+			return err
+		}
+	}
+	// A failed serverStatus or a storage engine other than WiredTiger lacks the
+	// counters the metrics are computed from.
+	if status == nil || status.Opcounters == nil || status.Network == nil ||
+		status.WiredTiger == nil || status.WiredTiger.Transaction == nil {
+		return nil
+	}
+
+	for _, event := range c.detector.Detect(status) {
+		if err := c.storage.RecordEvent(event); err != nil {
+			return err
+		}
+		if c.OnEvent != nil {
+			c.OnEvent(event)
+		}
+	}
+
+	metrics := c.extractor.Extract(status)
+	if metrics != nil {
+		if err := c.storage.RecordMetrics(*metrics); err != nil {
+			return err
+		}
+	}
+	if c.OnSample != nil {
+		c.OnSample(status, metrics)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/storage"
+)
+
+// fakeSource returns its statuses in order, then err or io.EOF.
+type fakeSource struct {
+	statuses []*mongowrapper.ServerStatusStats
+	err      error
+}
+
+func (s *fakeSource) Next(ctx context.Context) (*mongowrapper.ServerStatusStats, error) {
+	if len(s.statuses) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	status := s.statuses[0]
+	s.statuses = s.statuses[1:]
+	return status, nil
+}
+
+var fakeStart = time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// fakeStatus returns the status of a node seconds after fakeStart with the given
+// uptime and inserts.
+func fakeStatus(seconds int, uptime float64, inserts float64) *mongowrapper.ServerStatusStats {
+	return &mongowrapper.ServerStatusStats{
+		Host:       "fake:27017",
+		Version:    "4.0.0",
+		Uptime:     uptime,
+		LocalTime:  fakeStart.Add(time.Duration(seconds) * time.Second),
+		Network:    &mongowrapper.NetworkStats{},
+		Opcounters: &mongowrapper.OpcountersStats{Insert: inserts},
+		WiredTiger: &mongowrapper.WiredTigerStats{Transaction: &mongowrapper.WTTransactionStats{}},
+	}
+}
+
+type observed struct {
+	statuses []*mongowrapper.ServerStatusStats
+	metrics  []*metrichelper.Metrics
+	events   metrichelper.EventsSlice
+}
+
+func runCollector(source StatusSource) (*observed, storage.Storage, error) {
+	s := storage.CreateStorage(storage.Memory)
+	o := &observed{}
+	c := New(source, s)
+	c.OnSample = func(status *mongowrapper.ServerStatusStats, metrics *metrichelper.Metrics) {
+		o.statuses = append(o.statuses, status)
+		o.metrics = append(o.metrics, metrics)
+	}
+	c.OnEvent = func(event metrichelper.Event) {
+		o.events = append(o.events, event)
+	}
+	err := c.Run(context.Background())
+	return o, s, err
+}
+
+func TestCollectorSamples(t *testing.T) {
+	source := &fakeSource{statuses: []*mongowrapper.ServerStatusStats{
+		fakeStatus(0, 100, 1000),
+		fakeStatus(2, 102, 1100),
+		fakeStatus(3, 103, 1400),
+	}}
+	o, s, err := runCollector(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(o.statuses) != 3 {
+		t.Fatalf("OnSample called %d times, want 3", len(o.statuses))
+	}
+	if o.metrics[0] != nil {
+		t.Errorf("OnSample got metrics %+v for the first sample, want nil", o.metrics[0])
+	}
+	for i, want := range []float64{50, 300} {
+		m := o.metrics[i+1]
+		if m == nil {
+			t.Fatalf("OnSample got no metrics for sample %d", i+1)
+		}
+		if m.InsertCountPerSecond != want {
+			t.Errorf("sample %d: InsertCountPerSecond = %v, want %v", i+1, m.InsertCountPerSecond, want)
+		}
+	}
+	if len(o.events) != 0 {
+		t.Errorf("OnEvent got %v, want no event", o.events)
+	}
+
+	last, err := s.FetchLastMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.InsertCountPerSecond != 300 || !last.EndTime.Equal(fakeStart.Add(3*time.Second)) {
+		t.Errorf("FetchLastMetrics() = %+v, want the metrics of the last sample", last)
+	}
+}
+
+func TestCollectorRestart(t *testing.T) {
+	source := &fakeSource{statuses: []*mongowrapper.ServerStatusStats{
+		fakeStatus(0, 100, 1000),
+		fakeStatus(1, 101, 1100),
+		fakeStatus(2, 1, 10),
+		fakeStatus(3, 2, 30),
+	}}
+	o, s, err := runCollector(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(o.events) != 1 || o.events[0].Kind != metrichelper.EventRestart {
+		t.Fatalf("OnEvent got %v, want a restart", o.events)
+	}
+	if event := o.events[0]; event.Target != "fake:27017" || !event.Time.Equal(fakeStart.Add(2*time.Second)) {
+		t.Errorf("OnEvent got %+v, want the restart of fake:27017 at the third sample", event)
+	}
+	if o.metrics[2] != nil {
+		t.Errorf("OnSample got metrics %+v for the sample following the restart, want nil", o.metrics[2])
+	}
+	if m := o.metrics[3]; m == nil || m.InsertCountPerSecond != 20 {
+		t.Errorf("OnSample got %+v after the restart, want 20 inserts per second", m)
+	}
+
+	events, err := s.FetchEvents("fake:27017", fakeStart, fakeStart.Add(time.Minute))
+	if err != nil || len(events) != 1 {
+		t.Errorf("FetchEvents() = %v, %v, want the restart recorded", events, err)
+	}
+}
+
+func TestCollectorSkipsIncompleteSamples(t *testing.T) {
+	incomplete := fakeStatus(1, 101, 1100)
+	incomplete.WiredTiger = nil
+	source := &fakeSource{statuses: []*mongowrapper.ServerStatusStats{
+		fakeStatus(0, 100, 1000),
+		nil,
+		incomplete,
+		fakeStatus(2, 102, 1200),
+	}}
+	o, _, err := runCollector(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(o.statuses) != 2 {
+		t.Fatalf("OnSample called %d times, want 2", len(o.statuses))
+	}
+	if m := o.metrics[1]; m == nil || m.InsertCountPerSecond != 100 {
+		t.Errorf("OnSample got %+v, want 100 inserts per second", m)
+	}
+}
+
+func TestCollectorSourceError(t *testing.T) {
+	sourceErr := errors.New("source failed")
+	source := &fakeSource{
+		statuses: []*mongowrapper.ServerStatusStats{fakeStatus(0, 100, 1000)},
+		err:      sourceErr,
+	}
+	o, _, err := runCollector(source)
+	if err != sourceErr {
+		t.Errorf("Run() = %v, want %v", err, sourceErr)
+	}
+	if len(o.statuses) != 1 {
+		t.Errorf("OnSample called %d times, want 1", len(o.statuses))
+	}
+}
+
+func TestCollectorStorageIsolated(t *testing.T) {
+	_, s, err := runCollector(&fakeSource{statuses: []*mongowrapper.ServerStatusStats{
+		fakeStatus(0, 100, 1000),
+		fakeStatus(1, 101, 1100),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FetchLastMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	other := storage.CreateStorage(storage.Memory)
+	if m, err := other.FetchLastMetrics(); err == nil {
+		t.Errorf("FetchLastMetrics() of a new storage = %+v, want no metrics", m)
+	}
+}
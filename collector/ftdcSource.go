@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"mongo-monitor/ftdc"
+	"mongo-monitor/mongowrapper"
+
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// errFTDCEnd stops reading the samples taken after the end of the source.
+var errFTDCEnd = errors.New("ftdc: end of the source")
+
+// FTDCSource replays the serverStatus samples of mongod diagnostic data taken
+// between from and to, a zero time does not limit the samples.
+type FTDCSource struct {
+	path     string
+	from     time.Time
+	to       time.Time
+	pacer    *Pacer
+	statuses chan *mongowrapper.ServerStatusStats
+	err      error
+}
+
+// NewFTDCSource returns a source replaying the FTDC file or diagnostic.data directory at path.
+func NewFTDCSource(path string, from time.Time, to time.Time, pacer *Pacer) *FTDCSource {
+	return &FTDCSource{path: path, from: from, to: to, pacer: pacer}
+}
+
+// Next waits until the next sample is due and returns it.
+func (s *FTDCSource) Next(ctx context.Context) (*mongowrapper.ServerStatusStats, error) {
+	if s.statuses == nil {
+		s.statuses = make(chan *mongowrapper.ServerStatusStats)
+		go s.read(ctx)
+	}
+	var status *mongowrapper.ServerStatusStats
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case status = <-s.statuses:
+	}
+	// statuses is closed once the samples are read, s.err is set before.
+	if status == nil {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	if err := s.pacer.Wait(ctx, status.LocalTime); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *FTDCSource) read(ctx context.Context) {
+	defer close(s.statuses)
+	err := ftdc.ReadPath(s.path, func(sample bsonx.Doc) error {
+		status, err := ftdc.ServerStatus(sample)
+		if _, ok := err.(*ftdc.NoServerStatus); ok {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.from.IsZero() && status.LocalTime.Before(s.from) {
+			return nil
+		}
+		if !s.to.IsZero() && status.LocalTime.After(s.to) {
+			return errFTDCEnd
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s.statuses <- status:
+		}
+		return nil
+	})
+	if err != errFTDCEnd && err != ctx.Err() {
+		s.err = err
+	}
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"mongo-monitor/mongowrapper"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LiveSource fetches serverStatus from a running mongod every interval.
+type LiveSource struct {
+	client   *mongo.Client
+	interval time.Duration
+	ticker   *time.Ticker
+}
+
+// NewLiveSource returns a source fetching serverStatus with client every interval.
+func NewLiveSource(client *mongo.Client, interval time.Duration) *LiveSource {
+	return &LiveSource{client: client, interval: interval}
+}
+
+// Next fetches serverStatus at once the first time, then on the ticks of the
+// interval. The ticks missed by a slow fetch are skipped. The ticker is stopped
+// once the context expires, the source is not used after.
+func (s *LiveSource) Next(ctx context.Context) (*mongowrapper.ServerStatusStats, error) {
+	if s.ticker == nil {
+		s.ticker = time.NewTicker(s.interval)
+	} else {
+		select {
+		case <-ctx.Done():
+			s.ticker.Stop()
+			return nil, ctx.Err()
+		case <-s.ticker.C:
+		}
+	}
+	status := mongowrapper.GetServerStatus(ctx, s.client)
+	if ctx.Err() != nil {
+		s.ticker.Stop()
+	}
+	return status, nil
+}
@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"context"
+	"time"
+)
+
+// Pacer spaces replayed samples like they were taken, Speed times faster, or waits
+// for a value on Steps before each sample when Steps is not nil. A Speed of 0 does
+// not wait at all.
+type Pacer struct {
+	Speed        float64
+	Steps        <-chan struct{}
+	previousTime time.Time
+}
+
+// Wait blocks until the sample taken at t is due.
+func (p *Pacer) Wait(ctx context.Context, t time.Time) error {
+	previousTime := p.previousTime
+	p.previousTime = t
+
+	var due <-chan time.Time
+	switch {
+	case p.Steps != nil:
+	case p.Speed > 0 && !previousTime.IsZero() && t.After(previousTime):
+		due = time.After(time.Duration(float64(t.Sub(previousTime)) / p.Speed))
+	default:
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.Steps:
+	case <-due:
+	}
+	return nil
+}
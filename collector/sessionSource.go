@@ -0,0 +1,31 @@
+package collector
+
+import (
+	"context"
+
+	"mongo-monitor/mongowrapper"
+	"mongo-monitor/session"
+)
+
+// SessionSource replays the samples of a session recorded by a Collector.
+type SessionSource struct {
+	reader *session.Reader
+	pacer  *Pacer
+}
+
+// NewSessionSource returns a source replaying the samples of reader paced by pacer.
+func NewSessionSource(reader *session.Reader, pacer *Pacer) *SessionSource {
+	return &SessionSource{reader: reader, pacer: pacer}
+}
+
+// Next waits until the next recorded sample is due and returns it.
+func (s *SessionSource) Next(ctx context.Context) (*mongowrapper.ServerStatusStats, error) {
+	sample, err := s.reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pacer.Wait(ctx, sample.Time); err != nil {
+		return nil, err
+	}
+	return sample.Status, nil
+}
@@ -0,0 +1,234 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"mongo-monitor/mongowrapper"
+)
+
+// syntheticRates are the average operations per second of the synthetic load.
+var syntheticRates = mongowrapper.OpcountersStats{
+	Insert:  200,
+	Query:   800,
+	Update:  150,
+	Delete:  20,
+	GetMore: 50,
+	Command: 400,
+}
+
+const (
+	// syntheticCycle is the period of the slow wave of the synthetic load.
+	syntheticCycle = 10 * time.Minute
+	// syntheticBurstChance is the chance of a burst of queries to start on each second.
+	syntheticBurstChance = 0.005
+	syntheticBurstLength = 30 * time.Second
+	syntheticCheckpoint  = 60 * time.Second
+	syntheticCacheBytes  = 1024 * 1024 * 1024
+	// syntheticCheckpointStall is how long the writes are slowed down by a checkpoint.
+	syntheticCheckpointStall = 5 * time.Second
+)
+
+// syntheticLatency is the latency of a kind of operations of the synthetic load: most
+// operations take about micros, a share of them take about slowMicros.
+type syntheticLatency struct {
+	micros     float64
+	slowMicros float64
+	slowShare  float64
+}
+
+// The reads missing the cache and the writes during a checkpoint are slow, so the
+// latencies have two modes.
+var (
+	syntheticReadLatency    = syntheticLatency{micros: 200, slowMicros: 20000, slowShare: 0.05}
+	syntheticWriteLatency   = syntheticLatency{micros: 80, slowMicros: 4000, slowShare: 0.01}
+	syntheticCommandLatency = syntheticLatency{micros: 30, slowMicros: 1000, slowShare: 0.01}
+)
+
+// SyntheticSource generates the serverStatus of a mongod under a realistic load: the
+// operations follow a slow wave with some noise and occasional bursts of queries,
+// and a checkpoint happens every minute, slowing the writes down. The same seed
+// generates the same samples.
+type SyntheticSource struct {
+	interval time.Duration
+	realtime bool
+	rand     *rand.Rand
+	// latencyRand draws the latencies, so they do not change the other counters.
+	latencyRand   *rand.Rand
+	start         time.Time
+	status        mongowrapper.ServerStatusStats
+	burstEnd      time.Time
+	checkpointEnd time.Time
+}
+
+// NewSyntheticSource returns a source generating a sample every interval of simulated
+// time, waiting for the interval between samples when realtime is true.
+func NewSyntheticSource(seed int64, interval time.Duration, realtime bool) *SyntheticSource {
+	start := time.Now().Truncate(time.Second)
+	if !realtime {
+		// Samples generated as fast as possible must not depend on the clock.
+		start = time.Unix(0, 0).UTC()
+	}
+	return &SyntheticSource{
+		interval:    interval,
+		realtime:    realtime,
+		rand:        rand.New(rand.NewSource(seed)),
+		latencyRand: rand.New(rand.NewSource(seed + 1)),
+		start:       start,
+		status: mongowrapper.ServerStatusStats{
+			Host:        "synthetic:27017",
+			Version:     "4.0.0",
+			LocalTime:   start,
+			Connections: &mongowrapper.ConnectionsStats{Current: 100, Available: 51100},
+			Network:     &mongowrapper.NetworkStats{},
+			Opcounters:  &mongowrapper.OpcountersStats{},
+			OpLatencies: &mongowrapper.OpLatenciesStats{
+				Reads:    &mongowrapper.OpLatencyStats{},
+				Writes:   &mongowrapper.OpLatencyStats{},
+				Commands: &mongowrapper.OpLatencyStats{},
+			},
+			WiredTiger: &mongowrapper.WiredTigerStats{
+				Cache:       &mongowrapper.WTCacheStats{MaxBytes: syntheticCacheBytes},
+				Transaction: &mongowrapper.WTTransactionStats{},
+			},
+		},
+	}
+}
+
+// SetHost sets the host the samples come from, synthetic:27017 by default.
+func (s *SyntheticSource) SetHost(host string) {
+	s.status.Host = host
+}
+
+// Next returns the sample following the previous one by the interval.
+func (s *SyntheticSource) Next(ctx context.Context) (*mongowrapper.ServerStatusStats, error) {
+	if s.status.Uptime > 0 {
+		if s.realtime {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(s.interval):
+			}
+		}
+		s.advance()
+	} else {
+		s.status.Uptime = 1
+	}
+
+	// The samples are kept by the storage and the UI, every sample is a copy.
+	status := s.status
+	connections := *s.status.Connections
+	network := *s.status.Network
+	opcounters := *s.status.Opcounters
+	cache := *s.status.WiredTiger.Cache
+	transaction := *s.status.WiredTiger.Transaction
+	status.Connections = &connections
+	status.Network = &network
+	status.Opcounters = &opcounters
+	status.WiredTiger = &mongowrapper.WiredTigerStats{Cache: &cache, Transaction: &transaction}
+	status.OpLatencies = &mongowrapper.OpLatenciesStats{
+		Reads:    copyOpLatency(s.status.OpLatencies.Reads),
+		Writes:   copyOpLatency(s.status.OpLatencies.Writes),
+		Commands: copyOpLatency(s.status.OpLatencies.Commands),
+	}
+	return &status, nil
+}
+
+// advance moves the simulated time forward by the interval and increases the counters.
+func (s *SyntheticSource) advance() {
+	seconds := s.interval.Seconds()
+	now := s.status.LocalTime.Add(s.interval)
+	elapsed := now.Sub(s.start)
+
+	if now.After(s.burstEnd) && s.rand.Float64() < 1-math.Pow(1-syntheticBurstChance, seconds) {
+		s.burstEnd = now.Add(syntheticBurstLength)
+	}
+	wave := 1 + 0.4*math.Sin(2*math.Pi*elapsed.Seconds()/syntheticCycle.Seconds())
+	ops := func(rate float64) float64 {
+		noise := 1 + 0.1*s.rand.NormFloat64()
+		return math.Max(0, math.Round(rate*wave*noise*seconds))
+	}
+
+	o := s.status.Opcounters
+	inserts := ops(syntheticRates.Insert)
+	queries := ops(syntheticRates.Query)
+	if now.Before(s.burstEnd) {
+		queries *= 3
+	}
+	updates := ops(syntheticRates.Update)
+	deletes := ops(syntheticRates.Delete)
+	getmores := ops(syntheticRates.GetMore)
+	commands := ops(syntheticRates.Command)
+	o.Insert += inserts
+	o.Query += queries
+	o.Update += updates
+	o.Delete += deletes
+	o.GetMore += getmores
+	o.Command += commands
+
+	requests := inserts + queries + updates + deletes + getmores + commands
+	s.status.Network.NumRequests += requests
+	s.status.Network.BytesIn += math.Round(requests * (400 + 200*s.rand.Float64()))
+	s.status.Network.BytesOut += math.Round((queries+getmores)*(1500+1000*s.rand.Float64()) + (requests-queries-getmores)*150)
+
+	s.status.Connections.Current = math.Max(1, math.Round(100*wave+10*s.rand.NormFloat64()))
+	s.status.Connections.TotalCreated += math.Max(0, math.Round(2*seconds+s.rand.NormFloat64()))
+
+	// The dirty bytes pile up with the writes and are flushed by the checkpoints.
+	checkpoints := math.Floor(elapsed.Seconds() / syntheticCheckpoint.Seconds())
+	cache := s.status.WiredTiger.Cache
+	if checkpoints > s.status.WiredTiger.Transaction.Checkpoints {
+		cache.TrackedDirtyBytes *= 0.2
+		s.checkpointEnd = now.Add(syntheticCheckpointStall)
+	}
+	cache.TrackedDirtyBytes += (inserts + updates + deletes) * (4000 + 4000*s.rand.Float64())
+	cache.TrackedDirtyBytes = math.Min(cache.TrackedDirtyBytes, 0.5*cache.MaxBytes)
+	cache.CurrentBytes = math.Min(0.8*cache.MaxBytes*(1-math.Exp(-elapsed.Seconds()/600))+cache.TrackedDirtyBytes, cache.MaxBytes)
+	s.status.WiredTiger.Transaction.Checkpoints = checkpoints
+
+	reads, writes := syntheticReadLatency, syntheticWriteLatency
+	if now.Before(s.burstEnd) {
+		reads.slowShare *= 3
+	}
+	if now.Before(s.checkpointEnd) {
+		writes.slowShare = 0.3
+	}
+	s.addLatencies(s.status.OpLatencies.Reads, queries+getmores, reads)
+	s.addLatencies(s.status.OpLatencies.Writes, inserts+updates+deletes, writes)
+	s.addLatencies(s.status.OpLatencies.Commands, commands, syntheticCommandLatency)
+
+	s.status.LocalTime = now
+	s.status.Uptime += seconds
+}
+
+// addLatencies draws the latencies of ops operations and adds them to stats, in
+// buckets starting at powers of two microseconds.
+func (s *SyntheticSource) addLatencies(stats *mongowrapper.OpLatencyStats, ops float64, latency syntheticLatency) {
+	for i := 0; i < int(ops); i++ {
+		micros := latency.micros
+		if s.latencyRand.Float64() < latency.slowShare {
+			micros = latency.slowMicros
+		}
+		micros = math.Max(1, math.Round(micros*math.Exp(0.5*s.latencyRand.NormFloat64())))
+		stats.Ops++
+		stats.Latency += micros
+
+		bucket := math.Exp2(math.Floor(math.Log2(micros)))
+		j := sort.Search(len(stats.Histogram), func(j int) bool { return stats.Histogram[j].Micros >= bucket })
+		if j == len(stats.Histogram) || stats.Histogram[j].Micros != bucket {
+			stats.Histogram = append(stats.Histogram, mongowrapper.OpLatencyBucketStats{})
+			copy(stats.Histogram[j+1:], stats.Histogram[j:])
+			stats.Histogram[j] = mongowrapper.OpLatencyBucketStats{Micros: bucket}
+		}
+		stats.Histogram[j].Count++
+	}
+}
+
+func copyOpLatency(stats *mongowrapper.OpLatencyStats) *mongowrapper.OpLatencyStats {
+	c := *stats
+	c.Histogram = append([]mongowrapper.OpLatencyBucketStats(nil), stats.Histogram...)
+	return &c
+}
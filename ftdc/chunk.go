@@ -0,0 +1,198 @@
+package ftdc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// decodeChunk decompresses the data of a metric chunk and returns every sample it
+// contains, the reference document first.
+//
+// The data is the uncompressed length followed by a zlib stream of the reference
+// document, the number of metrics, the number of deltas and the deltas themselves.
+// The deltas are unsigned varints stored metric by metric, a zero delta is followed
+// by the number of additional zero deltas.
+func decodeChunk(data []byte) ([]bsonx.Doc, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("ftdc: chunk of %d bytes is too short", len(data))
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	raw, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) < 4 {
+		return nil, fmt.Errorf("ftdc: reference document is missing")
+	}
+	refLength := int(binary.LittleEndian.Uint32(raw))
+	if refLength > len(raw) {
+		return nil, fmt.Errorf("ftdc: reference document of %d bytes exceeds the chunk", refLength)
+	}
+	ref, err := bsonx.ReadDoc(raw[:refLength])
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(raw[refLength:])
+	var metricsCount, deltasCount uint32
+	if err := binary.Read(r, binary.LittleEndian, &metricsCount); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &deltasCount); err != nil {
+		return nil, err
+	}
+
+	refMetrics := extractMetrics(ref, nil)
+	if len(refMetrics) != int(metricsCount) {
+		return nil, fmt.Errorf("ftdc: reference document has %d metrics, expected %d", len(refMetrics), metricsCount)
+	}
+
+	// Every sample has at least its start date changing, so at least a byte of delta,
+	// a larger count is corrupted and must not size the allocations below.
+	if int64(deltasCount) > int64(r.Len()) {
+		return nil, fmt.Errorf("ftdc: %d deltas exceed the %d bytes left in the chunk", deltasCount, r.Len())
+	}
+
+	// values[j][i] is the metric i of the sample j.
+	values := make([][]int64, deltasCount+1)
+	values[0] = refMetrics
+	for j := 1; j < len(values); j++ {
+		values[j] = make([]int64, metricsCount)
+	}
+	var zeros uint64
+	for i := 0; i < int(metricsCount); i++ {
+		for j := 1; j < len(values); j++ {
+			var delta uint64
+			if zeros > 0 {
+				zeros--
+			} else {
+				if delta, err = binary.ReadUvarint(r); err != nil {
+					return nil, fmt.Errorf("ftdc: reading the delta of metric %d: %v", i, err)
+				}
+				if delta == 0 {
+					if zeros, err = binary.ReadUvarint(r); err != nil {
+						return nil, fmt.Errorf("ftdc: reading the zeros of metric %d: %v", i, err)
+					}
+				}
+			}
+			values[j][i] = int64(uint64(values[j-1][i]) + delta)
+		}
+	}
+
+	samples := make([]bsonx.Doc, len(values))
+	samples[0] = ref
+	for j := 1; j < len(values); j++ {
+		sample, _ := rebuildDoc(ref, values[j])
+		samples[j] = sample
+	}
+	return samples, nil
+}
+
+// extractMetrics appends the metrics of a document in the order FTDC stores them.
+// Numbers, booleans and dates are a metric each, timestamps are two metrics and
+// every other type is skipped.
+func extractMetrics(doc bsonx.Doc, metrics []int64) []int64 {
+	for _, elem := range doc {
+		metrics = extractValueMetrics(elem.Value, metrics)
+	}
+	return metrics
+}
+
+func extractValueMetrics(v bsonx.Val, metrics []int64) []int64 {
+	switch v.Type() {
+	case bsontype.Double:
+		return append(metrics, int64(v.Double()))
+	case bsontype.Int32:
+		return append(metrics, int64(v.Int32()))
+	case bsontype.Int64:
+		return append(metrics, v.Int64())
+	case bsontype.Decimal128:
+		f, _ := strconv.ParseFloat(v.Decimal128().String(), 64)
+		return append(metrics, int64(f))
+	case bsontype.Boolean:
+		if v.Boolean() {
+			return append(metrics, 1)
+		}
+		return append(metrics, 0)
+	case bsontype.DateTime:
+		return append(metrics, v.DateTime())
+	case bsontype.Timestamp:
+		t, i := v.Timestamp()
+		return append(metrics, int64(t), int64(i))
+	case bsontype.EmbeddedDocument:
+		return extractMetrics(v.Document(), metrics)
+	case bsontype.Array:
+		for _, item := range v.Array() {
+			metrics = extractValueMetrics(item, metrics)
+		}
+	}
+	return metrics
+}
+
+// rebuildDoc returns a copy of the reference document holding the given metrics and
+// the remaining metrics. Doubles are stored as integers, so they lose their fraction.
+func rebuildDoc(ref bsonx.Doc, metrics []int64) (bsonx.Doc, []int64) {
+	doc := make(bsonx.Doc, len(ref))
+	for i, elem := range ref {
+		doc[i].Key = elem.Key
+		doc[i].Value, metrics = rebuildValue(elem.Value, metrics)
+	}
+	return doc, metrics
+}
+
+func rebuildValue(v bsonx.Val, metrics []int64) (bsonx.Val, []int64) {
+	switch v.Type() {
+	case bsontype.Double, bsontype.Decimal128:
+		return bsonx.Double(float64(metrics[0])), metrics[1:]
+	case bsontype.Int32:
+		return bsonx.Int32(int32(metrics[0])), metrics[1:]
+	case bsontype.Int64:
+		return bsonx.Int64(metrics[0]), metrics[1:]
+	case bsontype.Boolean:
+		return bsonx.Boolean(metrics[0] != 0), metrics[1:]
+	case bsontype.DateTime:
+		return bsonx.DateTime(metrics[0]), metrics[1:]
+	case bsontype.Timestamp:
+		return bsonx.Timestamp(uint32(metrics[0]), uint32(metrics[1])), metrics[2:]
+	case bsontype.EmbeddedDocument:
+		doc, rest := rebuildDoc(v.Document(), metrics)
+		return bsonx.Document(doc), rest
+	case bsontype.Array:
+		ref := v.Array()
+		arr := make(bsonx.Arr, len(ref))
+		for i, item := range ref {
+			arr[i], metrics = rebuildValue(item, metrics)
+		}
+		return bsonx.Array(arr), metrics
+	}
+	return v, metrics
+}
+
+// readDocument reads the next BSON document of r, io.EOF is returned at the end of r.
+func readDocument(r io.Reader) (bsonx.Doc, error) {
+	var length int32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if length < 5 {
+		return nil, fmt.Errorf("ftdc: invalid document length %d", length)
+	}
+	b := make([]byte, length)
+	binary.LittleEndian.PutUint32(b, uint32(length))
+	if _, err := io.ReadFull(r, b[4:]); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return bsonx.ReadDoc(b)
+}
@@ -0,0 +1,66 @@
+package ftdc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// encodeChunk returns the data of a chunk holding ref, the counts and the deltas.
+func encodeChunk(t *testing.T, ref bsonx.Doc, metricsCount, deltasCount uint32, deltas []uint64) []byte {
+	raw, err := ref.MarshalBSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.NewBuffer(raw)
+	binary.Write(payload, binary.LittleEndian, metricsCount)
+	binary.Write(payload, binary.LittleEndian, deltasCount)
+	varint := make([]byte, binary.MaxVarintLen64)
+	for _, delta := range deltas {
+		payload.Write(varint[:binary.PutUvarint(varint, delta)])
+	}
+
+	data := &bytes.Buffer{}
+	binary.Write(data, binary.LittleEndian, uint32(payload.Len()))
+	zw := zlib.NewWriter(data)
+	zw.Write(payload.Bytes())
+	zw.Close()
+	return data.Bytes()
+}
+
+func TestDecodeChunk(t *testing.T) {
+	ref := bsonx.Doc{
+		{Key: "start", Value: bsonx.DateTime(1000)},
+		{Key: "inserts", Value: bsonx.Int64(10)},
+	}
+	// start changes by 1000 twice, inserts by 5 then a run of one more zero.
+	data := encodeChunk(t, ref, 2, 2, []uint64{1000, 1000, 5, 0, 0})
+
+	samples, err := decodeChunk(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("decodeChunk() returned %d samples, want 3", len(samples))
+	}
+	wants := [][2]int64{{1000, 10}, {2000, 15}, {3000, 15}}
+	for j, want := range wants {
+		start := samples[j].Lookup("start").DateTime()
+		inserts := samples[j].Lookup("inserts").Int64()
+		if start != want[0] || inserts != want[1] {
+			t.Errorf("sample %d = (%d, %d), want (%d, %d)", j, start, inserts, want[0], want[1])
+		}
+	}
+}
+
+func TestDecodeChunkRejectsTooManyDeltas(t *testing.T) {
+	ref := bsonx.Doc{{Key: "start", Value: bsonx.DateTime(1000)}}
+	data := encodeChunk(t, ref, 1, 1<<31, []uint64{0, 1 << 31})
+
+	if _, err := decodeChunk(data); err == nil {
+		t.Error("decodeChunk() accepted more deltas than the chunk holds")
+	}
+}
@@ -0,0 +1,125 @@
+package ftdc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mongo-monitor/mongowrapper"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// The types of the documents of a FTDC file.
+const (
+	typeMetadata         = 0
+	typeMetricChunk      = 1
+	typePeriodicMetadata = 2
+)
+
+// SampleFunc is called with every sample read, returning an error stops the reading.
+type SampleFunc func(sample bsonx.Doc) error
+
+// ReadPath reads a single FTDC file, or every metrics.* file of a diagnostic.data
+// directory in the order they were written.
+func ReadPath(path string, fn SampleFunc) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return ReadFile(path, fn)
+	}
+
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	files := []string{}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasPrefix(info.Name(), "metrics.") {
+			files = append(files, info.Name())
+		}
+	}
+	// The files are named after the time they were created, metrics.interim is the
+	// sample being written and sorts last.
+	sort.Strings(files)
+	for _, file := range files {
+		if err := ReadFile(filepath.Join(path, file), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFile calls fn with every sample of the metric chunks of a FTDC file.
+// A file truncated by a running mongod is read up to its last complete document.
+func ReadFile(path string, fn SampleFunc) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Read(f, fn)
+}
+
+// Read calls fn with every sample of the metric chunks read from r.
+func Read(r io.Reader, fn SampleFunc) error {
+	br := bufio.NewReader(r)
+	for {
+		doc, err := readDocument(br)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if t, ok := doc.Lookup("type").Int32OK(); !ok || t != typeMetricChunk {
+			continue
+		}
+		_, data, ok := doc.Lookup("data").BinaryOK()
+		if !ok {
+			return fmt.Errorf("ftdc: metric chunk without data")
+		}
+		samples, err := decodeChunk(data)
+		if err != nil {
+			return err
+		}
+		for _, sample := range samples {
+			if err := fn(sample); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// NoServerStatus is returned when a sample does not contain serverStatus.
+type NoServerStatus struct{}
+
+func (e *NoServerStatus) Error() string {
+	return "ftdc: the sample does not contain serverStatus"
+}
+
+// ServerStatus decodes the serverStatus of a sample.
+func ServerStatus(sample bsonx.Doc) (*mongowrapper.ServerStatusStats, error) {
+	doc, ok := sample.Lookup("serverStatus").DocumentOK()
+	if !ok {
+		return nil, &NoServerStatus{}
+	}
+	b, err := doc.MarshalBSON()
+	if err != nil {
+		return nil, err
+	}
+	status := &mongowrapper.ServerStatusStats{}
+	if err := bson.Unmarshal(b, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,54 @@
+// Package httprecorder serves the HTTP endpoints the tests write to, recording the
+// requests and failing the first ones as scripted.
+package httprecorder
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Server records the bodies of the requests posted to it. It responds with the
+// statuses it was created with in order, then with 204 once they are used up.
+type Server struct {
+	*httptest.Server
+	statuses []int
+	requests int
+	bodies   [][]byte
+	mutex    sync.Mutex
+}
+
+// NewServer returns a started server responding with statuses first.
+func NewServer(statuses ...int) *Server {
+	s := &Server{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.requests++
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status = s.statuses[0]
+			s.statuses = s.statuses[1:]
+		}
+		if status >= 300 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.bodies = append(s.bodies, body)
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+// Received returns the number of requests, and the bodies of the ones that succeeded.
+func (s *Server) Received() (int, [][]byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests, append([][]byte{}, s.bodies...)
+}
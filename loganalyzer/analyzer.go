@@ -0,0 +1,237 @@
+package loganalyzer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+// maxLineSize is the longest log line read, big commands are logged on a single line.
+const maxLineSize = 16 * 1024 * 1024
+
+var legacyConnectionsOpenRegexp = regexp.MustCompile(`\((\d+) connections? now open\)`)
+var legacyStateTransitionRegexp = regexp.MustCompile(`(?i)transition to (\w+)`)
+
+type EventKind string
+
+const (
+	EventRestart     EventKind = "restart"
+	EventElection    EventKind = "election"
+	EventStateChange EventKind = "state_change"
+	EventError       EventKind = "error"
+)
+
+type Event struct {
+	Time    time.Time `json:"time"`
+	Kind    EventKind `json:"kind"`
+	Message string    `json:"message"`
+}
+
+type Bucket struct {
+	Start             time.Time          `json:"start"`
+	SlowOperations    map[string]float64 `json:"slowOperations"`
+	ConnectionsOpened float64            `json:"connectionsOpened"`
+	ConnectionsClosed float64            `json:"connectionsClosed"`
+	Errors            float64            `json:"errors"`
+}
+
+type Report struct {
+	Lines             int                               `json:"lines"`
+	ParsedLines       int                               `json:"parsedLines"`
+	FirstTime         time.Time                         `json:"firstTime"`
+	LastTime          time.Time                         `json:"lastTime"`
+	SlowOperations    int                               `json:"slowOperations"`
+	ConnectionsOpened int                               `json:"connectionsOpened"`
+	ConnectionsClosed int                               `json:"connectionsClosed"`
+	PeakConnections   float64                           `json:"peakConnections"`
+	ErrorCount        int                               `json:"errorCount"`
+	Events            []Event                           `json:"events"`
+	Shapes            metrichelper.QueryShapeStatsSlice `json:"shapes"`
+	BucketSize        time.Duration                     `json:"bucketSize"`
+	Buckets           []Bucket                          `json:"buckets"`
+}
+
+// secondCounts are the counters of one second of logs.
+type secondCounts struct {
+	slowOperations    map[string]float64
+	connectionsOpened float64
+	connectionsClosed float64
+	errors            float64
+}
+
+// Analyzer aggregates the slow operations, the connections, the elections and the
+// errors of mongod logs.
+type Analyzer struct {
+	report     Report
+	aggregator *metrichelper.QueryShapeAggregator
+	seconds    map[int64]*secondCounts
+	maxErrors  int
+}
+
+// NewAnalyzer returns an analyzer keeping at most maxErrors error events.
+func NewAnalyzer(maxErrors int) *Analyzer {
+	return &Analyzer{
+		aggregator: metrichelper.NewQueryShapeAggregator(),
+		seconds:    map[int64]*secondCounts{},
+		maxErrors:  maxErrors,
+	}
+}
+
+// AnalyzeReader analyzes every line of r.
+func (a *Analyzer) AnalyzeReader(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		a.Add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// Add analyzes a single log line.
+func (a *Analyzer) Add(line string) {
+	a.report.Lines++
+	l, ok := parseLine(line)
+	if !ok {
+		return
+	}
+	a.report.ParsedLines++
+	if a.report.FirstTime.IsZero() || l.Time.Before(a.report.FirstTime) {
+		a.report.FirstTime = l.Time
+	}
+	if l.Time.After(a.report.LastTime) {
+		a.report.LastTime = l.Time
+	}
+	counts := a.secondCounts(l.Time)
+
+	if op, ok := l.slowOperation(); ok {
+		a.report.SlowOperations++
+		a.aggregator.Add(*op)
+		counts.slowOperations[op.Op]++
+		return
+	}
+
+	if l.Severity == "E" || l.Severity == "F" {
+		a.report.ErrorCount++
+		counts.errors++
+		if a.report.ErrorCount <= a.maxErrors {
+			a.addEvent(l, EventError)
+		}
+		return
+	}
+
+	message := strings.ToLower(l.Message)
+	switch {
+	case l.ID == jsonIDConnectionAccepted || strings.HasPrefix(message, "connection accepted"):
+		a.report.ConnectionsOpened++
+		counts.connectionsOpened++
+		a.updatePeakConnections(l)
+	case l.ID == jsonIDConnectionEnded || strings.HasPrefix(message, "end connection") || message == "connection ended":
+		a.report.ConnectionsClosed++
+		counts.connectionsClosed++
+	case strings.Contains(message, "mongodb starting"):
+		a.addEvent(l, EventRestart)
+	case strings.Contains(message, "election succeeded"):
+		a.addEvent(l, EventElection)
+	case l.ID == jsonIDStateTransition || legacyStateTransitionRegexp.MatchString(message):
+		a.addEvent(l, EventStateChange)
+	}
+}
+
+func (a *Analyzer) secondCounts(t time.Time) *secondCounts {
+	counts, ok := a.seconds[t.Unix()]
+	if !ok {
+		counts = &secondCounts{slowOperations: map[string]float64{}}
+		a.seconds[t.Unix()] = counts
+	}
+	return counts
+}
+
+func (a *Analyzer) updatePeakConnections(l *logLine) {
+	connections := 0.0
+	if l.Attr != nil {
+		connections = jsonNumber(l.Attr["connectionCount"])
+	} else if m := legacyConnectionsOpenRegexp.FindStringSubmatch(l.Message); m != nil {
+		connections, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if connections > a.report.PeakConnections {
+		a.report.PeakConnections = connections
+	}
+}
+
+func (a *Analyzer) addEvent(l *logLine, kind EventKind) {
+	message := l.Message
+	if l.Attr != nil {
+		if newState, ok := l.Attr["newState"].(string); ok {
+			message += " " + newState
+		}
+		if errmsg, ok := l.Attr["error"].(string); ok {
+			message += ": " + errmsg
+		}
+	}
+	a.report.Events = append(a.report.Events, Event{Time: l.Time, Kind: kind, Message: message})
+}
+
+// Report returns the analysis with the time series split in buckets of the given size.
+// A bucket size of 0 splits the analyzed period in 50 buckets of whole minutes.
+func (a *Analyzer) Report(bucketSize time.Duration) *Report {
+	report := a.report
+	report.Shapes = a.aggregator.Slice()
+	sort.SliceStable(report.Events, func(i, j int) bool {
+		return report.Events[i].Time.Before(report.Events[j].Time)
+	})
+
+	if bucketSize <= 0 {
+		bucketSize = report.LastTime.Sub(report.FirstTime) / 50
+		bucketSize = (bucketSize/time.Minute + 1) * time.Minute
+	}
+	report.BucketSize = bucketSize
+
+	report.Buckets = []Bucket{}
+	if report.ParsedLines == 0 {
+		return &report
+	}
+	start := report.FirstTime.Truncate(bucketSize)
+	for t := start; !t.After(report.LastTime); t = t.Add(bucketSize) {
+		report.Buckets = append(report.Buckets, Bucket{Start: t, SlowOperations: map[string]float64{}})
+	}
+	for second, counts := range a.seconds {
+		i := int(time.Unix(second, 0).Sub(start) / bucketSize)
+		if i < 0 || i >= len(report.Buckets) {
+			continue
+		}
+		bucket := &report.Buckets[i]
+		for op, count := range counts.slowOperations {
+			bucket.SlowOperations[op] += count
+		}
+		bucket.ConnectionsOpened += counts.connectionsOpened
+		bucket.ConnectionsClosed += counts.connectionsClosed
+		bucket.Errors += counts.errors
+	}
+	return &report
+}
+
+// MetricsSlice converts the slow operations of every bucket into per second metrics,
+// so they can be charted like the live opcounters.
+func (r *Report) MetricsSlice() metrichelper.MetricsSlice {
+	ms := metrichelper.MetricsSlice{}
+	seconds := r.BucketSize.Seconds()
+	for _, bucket := range r.Buckets {
+		ms = append(ms, metrichelper.Metrics{
+			InsertCountPerSecond:  bucket.SlowOperations["insert"] / seconds,
+			QueryCountPerSecond:   bucket.SlowOperations["query"] / seconds,
+			UpdateCountPerSecond:  bucket.SlowOperations["update"] / seconds,
+			DeleteCountPerSecond:  bucket.SlowOperations["delete"] / seconds,
+			GetmoreCountPerSecond: bucket.SlowOperations["getmore"] / seconds,
+			CommandCountPerSecond: bucket.SlowOperations["command"] / seconds,
+			StartTime:             bucket.Start,
+			EndTime:               bucket.Start.Add(r.BucketSize),
+		})
+	}
+	return ms
+}
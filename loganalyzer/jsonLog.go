@@ -0,0 +1,92 @@
+package loganalyzer
+
+import (
+	"encoding/json"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+// Log ids of the structured log messages the analyzer looks for.
+const (
+	jsonIDSlowQuery          = 51803
+	jsonIDConnectionAccepted = 22943
+	jsonIDConnectionEnded    = 22944
+	jsonIDStateTransition    = 21358
+)
+
+type jsonLogLine struct {
+	T struct {
+		Date string `json:"$date"`
+	} `json:"t"`
+	S    string                 `json:"s"`
+	C    string                 `json:"c"`
+	ID   int                    `json:"id"`
+	Ctx  string                 `json:"ctx"`
+	Msg  string                 `json:"msg"`
+	Attr map[string]interface{} `json:"attr"`
+}
+
+func parseJSONLine(line string) (*logLine, bool) {
+	parsed := jsonLogLine{}
+	if err := json.Unmarshal([]byte(line), &parsed); err != nil {
+		return nil, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, parsed.T.Date)
+	if err != nil {
+		return nil, false
+	}
+	attr := parsed.Attr
+	if attr == nil {
+		attr = map[string]interface{}{}
+	}
+	return &logLine{
+		Time:      t,
+		Severity:  parsed.S,
+		Component: parsed.C,
+		Context:   parsed.Ctx,
+		ID:        parsed.ID,
+		Message:   parsed.Msg,
+		Attr:      attr,
+	}, true
+}
+
+func jsonSlowOperation(l *logLine) (*metrichelper.SlowOperation, bool) {
+	if l.ID != jsonIDSlowQuery && l.Message != "Slow query" {
+		return nil, false
+	}
+	command, _ := l.Attr["command"].(map[string]interface{})
+	opTypeName, _ := l.Attr["type"].(string)
+	name := commandName(command, opTypeName)
+	ns, _ := l.Attr["ns"].(string)
+	planSummary, _ := l.Attr["planSummary"].(string)
+	return &metrichelper.SlowOperation{
+		Time:         l.Time,
+		Ns:           ns,
+		Op:           opName(opType(name)),
+		Shape:        metrichelper.QueryShape(opTypeName, command),
+		Millis:       jsonNumber(l.Attr["durationMillis"]),
+		DocsExamined: jsonNumber(l.Attr["docsExamined"]),
+		KeysExamined: jsonNumber(l.Attr["keysExamined"]),
+		NReturned:    jsonNumber(l.Attr["nreturned"]),
+		PlanSummary:  planSummary,
+	}, true
+}
+
+// jsonNumber returns a number of the attributes, which may be written as {"$numberLong": "1"}.
+func jsonNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case map[string]interface{}:
+		for _, key := range []string{"$numberLong", "$numberInt", "$numberDouble"} {
+			if s, ok := v[key].(string); ok {
+				var f float64
+				if err := json.Unmarshal([]byte(s), &f); err == nil {
+					return f
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,238 @@
+package loganalyzer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+// legacyTimeLayout reads the iso8601-local timestamps and the iso8601-utc ones ending with Z.
+const legacyTimeLayout = "2006-01-02T15:04:05.000Z0700"
+
+var legacyLineRegexp = regexp.MustCompile(`^(\S+)\s+([IWEFD])\d?\s+(\S+)\s+\[([^\]]+)\]\s+(.*)$`)
+var legacySlowOpRegexp = regexp.MustCompile(`^(command|query|update|remove|insert|getmore)\s+(\S+)\s+(.*)\s(\d+)ms$`)
+var legacyPlanSummaryRegexp = regexp.MustCompile(`planSummary: (\w+)`)
+var legacyCounterRegexps = map[string]*regexp.Regexp{
+	"docsExamined": regexp.MustCompile(`(?:docsExamined|nscannedObjects):(\d+)`),
+	"keysExamined": regexp.MustCompile(`(?:keysExamined|nscanned):(\d+)`),
+	"nreturned":    regexp.MustCompile(`nreturned:(\d+)`),
+}
+
+func parseLegacyLine(line string) (*logLine, bool) {
+	matches := legacyLineRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+	t, err := time.Parse(legacyTimeLayout, matches[1])
+	if err != nil {
+		return nil, false
+	}
+	return &logLine{
+		Time:      t,
+		Severity:  matches[2],
+		Component: matches[3],
+		Context:   matches[4],
+		Message:   matches[5],
+	}, true
+}
+
+// legacySlowOperation parses lines like
+// command test.users command: find { find: "users", filter: { age: { $gt: 30 } } } planSummary: COLLSCAN ... nreturned:1 ... 105ms
+func legacySlowOperation(l *logLine) (*metrichelper.SlowOperation, bool) {
+	matches := legacySlowOpRegexp.FindStringSubmatch(l.Message)
+	if matches == nil {
+		return nil, false
+	}
+	op := matches[1]
+	ns := matches[2]
+	details := matches[3]
+	millis, _ := strconv.ParseFloat(matches[4], 64)
+
+	command := map[string]interface{}{}
+	name := op
+	if op == "command" {
+		// The command name precedes the document, e.g. "command: find { ... }".
+		if i := strings.Index(details, "command: "); i >= 0 {
+			rest := details[i+len("command: "):]
+			if j := strings.Index(rest, " "); j >= 0 {
+				name = rest[:j]
+				rest = rest[j+1:]
+			}
+			if document, ok := parseShellValue(rest).(map[string]interface{}); ok {
+				command = document
+			}
+		}
+	} else {
+		// Legacy op lines log the predicate as "query: { ... }".
+		if i := strings.Index(details, "query: "); i >= 0 {
+			command["filter"] = parseShellValue(details[i+len("query: "):])
+		}
+	}
+
+	planSummary := ""
+	if m := legacyPlanSummaryRegexp.FindStringSubmatch(details); m != nil {
+		planSummary = m[1]
+	}
+	counter := func(name string) float64 {
+		if m := legacyCounterRegexps[name].FindStringSubmatch(details); m != nil {
+			value, _ := strconv.ParseFloat(m[1], 64)
+			return value
+		}
+		return 0
+	}
+
+	return &metrichelper.SlowOperation{
+		Time:         l.Time,
+		Ns:           ns,
+		Op:           opName(opType(commandName(command, name))),
+		Shape:        metrichelper.QueryShape(name, command),
+		Millis:       millis,
+		DocsExamined: counter("docsExamined"),
+		KeysExamined: counter("keysExamined"),
+		NReturned:    counter("nreturned"),
+		PlanSummary:  planSummary,
+	}, true
+}
+
+// parseShellValue parses the value at the beginning of a mongo shell formatted string,
+// e.g. { find: "users", filter: { _id: ObjectId('...') } }. Only the structure matters
+// for query shapes, so every scalar is returned as its raw text.
+func parseShellValue(s string) interface{} {
+	p := shellParser{s: s}
+	return p.value()
+}
+
+type shellParser struct {
+	s   string
+	pos int
+}
+
+func (p *shellParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *shellParser) value() interface{} {
+	p.skipSpaces()
+	if p.pos >= len(p.s) {
+		return nil
+	}
+	switch p.s[p.pos] {
+	case '{':
+		return p.document()
+	case '[':
+		return p.array()
+	case '"', '\'':
+		return p.quoted()
+	}
+	return p.scalar()
+}
+
+func (p *shellParser) document() map[string]interface{} {
+	document := map[string]interface{}{}
+	p.pos++
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return document
+		}
+		if p.s[p.pos] == '}' {
+			p.pos++
+			return document
+		}
+		if p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		key := p.key()
+		if key == "" {
+			return document
+		}
+		document[key] = p.value()
+	}
+}
+
+func (p *shellParser) key() string {
+	p.skipSpaces()
+	if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+		key := p.quoted()
+		p.skipSpaces()
+		if p.pos < len(p.s) && p.s[p.pos] == ':' {
+			p.pos++
+		}
+		return key
+	}
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end < 0 {
+		p.pos = len(p.s)
+		return ""
+	}
+	key := strings.TrimSpace(p.s[p.pos : p.pos+end])
+	p.pos += end + 1
+	return key
+}
+
+func (p *shellParser) array() []interface{} {
+	array := []interface{}{}
+	p.pos++
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return array
+		}
+		if p.s[p.pos] == ']' {
+			p.pos++
+			return array
+		}
+		// The array of a truncated line or of a regex holding } is not closed, the
+		// document ends it.
+		if p.s[p.pos] == '}' {
+			return array
+		}
+		if p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		array = append(array, p.value())
+	}
+}
+
+func (p *shellParser) quoted() string {
+	quote := p.s[p.pos]
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != quote {
+		if p.s[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	end := p.pos
+	if end > len(p.s) {
+		end = len(p.s)
+	}
+	p.pos++
+	return p.s[start:end]
+}
+
+// scalar reads numbers, booleans and constructors like ObjectId('...') or new Date(1).
+func (p *shellParser) scalar() string {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			depth--
+		} else if depth == 0 && (c == ',' || c == '}' || c == ']') {
+			break
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.s[start:p.pos])
+}
@@ -0,0 +1,80 @@
+package loganalyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// parseShellValueWithin parses s, failing the test when the parser does not return.
+func parseShellValueWithin(t *testing.T, s string) interface{} {
+	done := make(chan interface{}, 1)
+	go func() {
+		done <- parseShellValue(s)
+	}()
+	select {
+	case value := <-done:
+		return value
+	case <-time.After(5 * time.Second):
+		t.Fatalf("parseShellValue(%q) did not return", s)
+		return nil
+	}
+}
+
+func TestParseShellValue(t *testing.T) {
+	tests := []struct {
+		s    string
+		want interface{}
+	}{
+		{
+			`{ find: "users", filter: { age: { $gt: 30 } } }`,
+			map[string]interface{}{"find": "users", "filter": map[string]interface{}{"age": map[string]interface{}{"$gt": "30"}}},
+		},
+		{
+			`{ _id: ObjectId('5f1d7a'), tags: [ "a", 'b', new Date(1) ] } planSummary: IXSCAN`,
+			map[string]interface{}{"_id": "ObjectId('5f1d7a')", "tags": []interface{}{"a", "b", "new Date(1)"}},
+		},
+		{
+			`{ filter: { a: { $in: [ 1, 2 } } }`,
+			map[string]interface{}{"filter": map[string]interface{}{"a": map[string]interface{}{"$in": []interface{}{"1", "2"}}}},
+		},
+		{
+			`{ filter: { a: { $in: [ 1, 2`,
+			map[string]interface{}{"filter": map[string]interface{}{"a": map[string]interface{}{"$in": []interface{}{"1", "2"}}}},
+		},
+		{`[ } ]`, []interface{}{}},
+	}
+	for _, test := range tests {
+		if got := parseShellValueWithin(t, test.s); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseShellValue(%q) = %#v, want %#v", test.s, got, test.want)
+		}
+	}
+}
+
+func TestLegacySlowOperation(t *testing.T) {
+	l, ok := parseLegacyLine(`2021-03-01T10:00:00.123+0100 I COMMAND  [conn12] command test.users command: find { find: "users", filter: { age: { $in: [ 1, 2 } } } planSummary: COLLSCAN keysExamined:0 docsExamined:1000 nreturned:1 105ms`)
+	if !ok {
+		t.Fatal("parseLegacyLine() rejected the line")
+	}
+	op, ok := l.slowOperation()
+	if !ok {
+		t.Fatal("slowOperation() found no operation")
+	}
+	if op.Ns != "test.users" || op.Op != "query" || op.Millis != 105 || op.DocsExamined != 1000 || op.NReturned != 1 || op.PlanSummary != "COLLSCAN" {
+		t.Errorf("slowOperation() = %+v", op)
+	}
+}
+
+func TestParseLegacyLineTime(t *testing.T) {
+	want := time.Date(2021, 3, 1, 9, 0, 0, 123e6, time.UTC)
+	for _, timestamp := range []string{"2021-03-01T10:00:00.123+0100", "2021-03-01T09:00:00.123Z"} {
+		l, ok := parseLegacyLine(timestamp + " I NETWORK  [listener] connection accepted")
+		if !ok {
+			t.Errorf("parseLegacyLine() rejected the line logged at %s", timestamp)
+			continue
+		}
+		if !l.Time.Equal(want) {
+			t.Errorf("parseLegacyLine() time = %v for %s, want %v", l.Time, timestamp, want)
+		}
+	}
+}
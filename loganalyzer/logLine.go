@@ -0,0 +1,82 @@
+package loganalyzer
+
+import (
+	"strings"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+// logLine is a mongod log line, either structured (4.4+) or legacy.
+type logLine struct {
+	Time      time.Time
+	Severity  string
+	Component string
+	Context   string
+	ID        int
+	Message   string
+	// Attr holds the attributes of structured logs, it is nil for legacy logs.
+	Attr map[string]interface{}
+}
+
+// parseLine parses a structured or a legacy log line.
+func parseLine(line string) (*logLine, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "{") {
+		return parseJSONLine(line)
+	}
+	return parseLegacyLine(line)
+}
+
+// slowOperation returns the slow operation reported by the line, if any.
+func (l *logLine) slowOperation() (*metrichelper.SlowOperation, bool) {
+	if l.Attr != nil {
+		return jsonSlowOperation(l)
+	}
+	return legacySlowOperation(l)
+}
+
+// opType maps a command name to the opcounters action it is counted in.
+func opType(commandName string) metrichelper.ActionType {
+	switch commandName {
+	case "find", "query", "count", "distinct", "aggregate":
+		return metrichelper.ActionQuery
+	case "insert":
+		return metrichelper.ActionInsert
+	case "update", "findAndModify", "findandmodify":
+		return metrichelper.ActionUpdate
+	case "delete", "remove":
+		return metrichelper.ActionDelete
+	case "getMore", "getmore":
+		return metrichelper.ActionGetmore
+	default:
+		return metrichelper.ActionCommand
+	}
+}
+
+// opName returns the name of the opcounters action, e.g. query.
+func opName(action metrichelper.ActionType) string {
+	switch action {
+	case metrichelper.ActionInsert:
+		return "insert"
+	case metrichelper.ActionQuery:
+		return "query"
+	case metrichelper.ActionUpdate:
+		return "update"
+	case metrichelper.ActionDelete:
+		return "delete"
+	case metrichelper.ActionGetmore:
+		return "getmore"
+	default:
+		return "command"
+	}
+}
+
+// commandName returns the name of a command document, falling back on the given name.
+func commandName(command map[string]interface{}, fallback string) string {
+	shape := metrichelper.QueryShape(fallback, command)
+	if i := strings.Index(shape, " "); i >= 0 {
+		return shape[:i]
+	}
+	return shape
+}
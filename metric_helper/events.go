@@ -0,0 +1,169 @@
+package metric_helper
+
+import (
+	"fmt"
+	"time"
+
+	"mongo-monitor/mongowrapper"
+)
+
+// EventKind is what happened to a target.
+type EventKind string
+
+const (
+	EventRestart  EventKind = "restart"
+	EventElection EventKind = "election"
+	EventState    EventKind = "state"
+	EventConfig   EventKind = "config"
+	EventFCV      EventKind = "fcv"
+	EventAlert    EventKind = "alert"
+)
+
+// Event is something that happened to a target at a time, for the spikes of the
+// metrics to be correlated with.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Target  string    `json:"target"`
+	Kind    EventKind `json:"kind"`
+	Message string    `json:"message"`
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s %s: %s", e.Time.Format(time.RFC3339), e.Kind, e.Target, e.Message)
+}
+
+type EventsSlice []Event
+
+func (es EventsSlice) Len() int {
+	return len(es)
+}
+
+func (es EventsSlice) Less(i, j int) bool {
+	return es[i].Time.Before(es[j].Time)
+}
+
+func (es EventsSlice) Swap(i, j int) {
+	es[i], es[j] = es[j], es[i]
+}
+
+// EventDetector finds the events between consecutive samples of a target.
+type EventDetector struct {
+	previousStatus *mongowrapper.ServerStatusStats
+	// states are the states of the members of the last topology by name.
+	states map[string]string
+	fcv    string
+}
+
+// NewEventDetector returns a detector waiting for its first samples.
+func NewEventDetector() *EventDetector {
+	return &EventDetector{}
+}
+
+// Detect returns the restarts, the elections, the state changes of the node and the
+// replica set config changes between the previous sample and status.
+func (d *EventDetector) Detect(status *mongowrapper.ServerStatusStats) EventsSlice {
+	previous := d.previousStatus
+	d.previousStatus = status
+	if previous == nil {
+		return nil
+	}
+	events := EventsSlice{}
+	event := func(kind EventKind, format string, args ...interface{}) {
+		events = append(events, Event{
+			Time:    status.LocalTime,
+			Target:  status.Host,
+			Kind:    kind,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if status.Uptime < previous.Uptime {
+		if status.Version != previous.Version {
+			event(EventRestart, "restarted, version %s to %s", previous.Version, status.Version)
+		} else {
+			event(EventRestart, "restarted")
+		}
+	}
+	if previous.Repl == nil || status.Repl == nil {
+		return events
+	}
+	if p := status.Repl.Primary; p != previous.Repl.Primary && p != "" {
+		if previous.Repl.Primary == "" {
+			event(EventElection, "%s elected primary", p)
+		} else {
+			event(EventElection, "primary changed from %s to %s", previous.Repl.Primary, p)
+		}
+	}
+	if state := replState(status.Repl); state != replState(previous.Repl) {
+		event(EventState, "%s to %s", replState(previous.Repl), state)
+	}
+	if v := status.Repl.SetVersion; v != previous.Repl.SetVersion && previous.Repl.SetVersion != 0 {
+		event(EventConfig, "replica set config version %d to %d", previous.Repl.SetVersion, v)
+	}
+	return events
+}
+
+// replState returns the state of a node from the replication stats of serverStatus.
+func replState(repl *mongowrapper.ReplStatusStats) string {
+	switch {
+	case repl.IsMaster:
+		return "PRIMARY"
+	case repl.Secondary:
+		return "SECONDARY"
+	}
+	return "OTHER"
+}
+
+// DetectTopology returns the state changes of the other members of the replica set
+// between the previous topology and t, the node reports its own state changes in
+// its samples.
+func (d *EventDetector) DetectTopology(t Topology) EventsSlice {
+	previous := d.states
+	d.states = map[string]string{}
+	for _, m := range t.Members {
+		d.states[m.Name] = m.State
+	}
+	if previous == nil {
+		return nil
+	}
+	events := EventsSlice{}
+	for _, m := range t.Members {
+		if m.Self {
+			continue
+		}
+		message := ""
+		if state, ok := previous[m.Name]; !ok {
+			message = fmt.Sprintf("joined as %s", m.State)
+		} else if state != m.State {
+			message = fmt.Sprintf("%s to %s", state, m.State)
+		}
+		if message != "" {
+			events = append(events, Event{Time: t.Time, Target: m.Name, Kind: EventState, Message: message + ", seen from " + t.Host})
+		}
+	}
+	for name := range previous {
+		if _, ok := d.states[name]; !ok {
+			events = append(events, Event{Time: t.Time, Target: name, Kind: EventState, Message: "left the replica set, seen from " + t.Host})
+		}
+	}
+	return events
+}
+
+// DetectFCV returns the change of the featureCompatibilityVersion of host since the
+// previous version, version is empty when it is unknown.
+func (d *EventDetector) DetectFCV(host string, t time.Time, version string) EventsSlice {
+	previous := d.fcv
+	if version == "" {
+		return nil
+	}
+	d.fcv = version
+	if previous == "" || previous == version {
+		return nil
+	}
+	return EventsSlice{{
+		Time:    t,
+		Target:  host,
+		Kind:    EventFCV,
+		Message: fmt.Sprintf("featureCompatibilityVersion %s to %s", previous, version),
+	}}
+}
@@ -0,0 +1,22 @@
+package metric_helper
+
+import "fmt"
+
+// FormatBytes formats bytes into a human readable string, e.g. 1.50GB.
+func FormatBytes(bytes float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	sign := ""
+	if bytes < 0 {
+		sign = "-"
+		bytes = -bytes
+	}
+	i := 0
+	for bytes >= 1024 && i < len(units)-1 {
+		bytes /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%s%.0f%s", sign, bytes, units[i])
+	}
+	return fmt.Sprintf("%s%.2f%s", sign, bytes, units[i])
+}
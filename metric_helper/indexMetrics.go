@@ -0,0 +1,105 @@
+package metric_helper
+
+import (
+	"mongo-monitor/mongowrapper"
+	"sort"
+	"time"
+)
+
+type IndexMetrics struct {
+	Database   string    `json:"database"`
+	Collection string    `json:"collection"`
+	Name       string    `json:"name"`
+	SizeBytes  float64   `json:"sizeBytes"`
+	Ops        float64   `json:"ops"`
+	OpsPerHour float64   `json:"opsPerHour"`
+	Since      time.Time `json:"since"`
+	// IdleSince is the time since which the index has not been used as far as
+	// we know: accesses.since for an index never used, otherwise the time we
+	// first saw the current ops counter.
+	IdleSince time.Time `json:"idleSince"`
+	Time      time.Time `json:"time"`
+}
+
+// ID returns the identifier of the index, e.g. db.collection/name_1.
+func (m IndexMetrics) ID() string {
+	return m.Database + "." + m.Collection + "/" + m.Name
+}
+
+// Unused reports whether the index has not been accessed since the given time.
+// The _id index is never reported as unused since it cannot be dropped.
+func (m IndexMetrics) Unused(since time.Time) bool {
+	if m.Name == "_id_" {
+		return false
+	}
+	return !m.IdleSince.After(since)
+}
+
+type IndexMetricsSlice []IndexMetrics
+
+// SortBySize sorts the slice by index size, biggest first.
+func (ms IndexMetricsSlice) SortBySize() {
+	sort.SliceStable(ms, func(i, j int) bool {
+		return ms[i].SizeBytes > ms[j].SizeBytes
+	})
+}
+
+// SortByOps sorts the slice by accesses, least used first.
+func (ms IndexMetricsSlice) SortByOps() {
+	sort.SliceStable(ms, func(i, j int) bool {
+		return ms[i].Ops < ms[j].Ops
+	})
+}
+
+// Unused returns the indexes which have not been accessed since the given time.
+func (ms IndexMetricsSlice) Unused(since time.Time) IndexMetricsSlice {
+	unused := IndexMetricsSlice{}
+	for _, m := range ms {
+		if m.Unused(since) {
+			unused = append(unused, m)
+		}
+	}
+	return unused
+}
+
+// ExtractIndexMetrics converts $indexStats and the index size from collStats into
+// index metrics, using the previous sample of the same index to track its usage.
+func ExtractIndexMetrics(
+	db string,
+	collection string,
+	stats mongowrapper.IndexStats,
+	sizeBytes float64,
+	previous *IndexMetrics,
+	now time.Time,
+) *IndexMetrics {
+	metrics := &IndexMetrics{
+		Database:   db,
+		Collection: collection,
+		Name:       stats.Name,
+		SizeBytes:  sizeBytes,
+		Ops:        stats.Accesses.Ops,
+		Since:      stats.Accesses.Since,
+		Time:       now,
+	}
+
+	switch {
+	case metrics.Ops == 0:
+		metrics.IdleSince = metrics.Since
+	// The counters are reset when the server restarts or the index is rebuilt.
+	case previous == nil || !previous.Since.Equal(metrics.Since) || previous.Ops != metrics.Ops:
+		metrics.IdleSince = now
+	default:
+		metrics.IdleSince = previous.IdleSince
+	}
+
+	if previous != nil && previous.Since.Equal(metrics.Since) {
+		hours := now.Sub(previous.Time).Hours()
+		if hours > 0 {
+			metrics.OpsPerHour = (metrics.Ops - previous.Ops) / hours
+		}
+	} else if hours := now.Sub(metrics.Since).Hours(); hours > 0 {
+		metrics.OpsPerHour = metrics.Ops / hours
+	}
+
+	return metrics
+}
@@ -0,0 +1,143 @@
+package metric_helper
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"mongo-monitor/mongowrapper"
+)
+
+// LatencyOps are the kinds of operations mongod reports the latencies of.
+var LatencyOps = []string{"reads", "writes", "commands"}
+
+// LatencyHistogram are the operations per second by latency bucket, the bucket i
+// holds the operations that took from 2^i to 2^(i+1) microseconds.
+type LatencyHistogram []float64
+
+// LatencyBucketMicros returns the lower bound of the bucket i in microseconds.
+func LatencyBucketMicros(i int) float64 {
+	return math.Exp2(float64(i))
+}
+
+// FormatLatency formats a latency in microseconds as µs, ms or s.
+func FormatLatency(micros float64) string {
+	switch {
+	case micros >= 1e6:
+		return fmt.Sprintf("%gs", math.Round(micros/1e5)/10)
+	case micros >= 1e3:
+		return fmt.Sprintf("%gms", math.Round(micros/1e2)/10)
+	}
+	return fmt.Sprintf("%gµs", micros)
+}
+
+// Total returns the operations per second of every bucket.
+func (h LatencyHistogram) Total() float64 {
+	total := 0.0
+	for _, ops := range h {
+		total += ops
+	}
+	return total
+}
+
+// Percentile returns the upper bound in microseconds of the bucket holding the p-th
+// percentile of the operations, p being between 0 and 100, and 0 without operation.
+func (h LatencyHistogram) Percentile(p float64) float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	seen := 0.0
+	for i, ops := range h {
+		if seen += ops; seen >= total*p/100 {
+			return LatencyBucketMicros(i + 1)
+		}
+	}
+	return LatencyBucketMicros(len(h))
+}
+
+// Add returns the sum of the operations of h and other by bucket.
+func (h LatencyHistogram) Add(other LatencyHistogram) LatencyHistogram {
+	n := len(h)
+	if len(other) > n {
+		n = len(other)
+	}
+	sum := make(LatencyHistogram, n)
+	copy(sum, h)
+	for i, ops := range other {
+		sum[i] += ops
+	}
+	return sum
+}
+
+// latencyBucket returns the bucket of the operations that took at least micros.
+func latencyBucket(micros float64) int {
+	if micros < 1 {
+		return 0
+	}
+	return int(math.Floor(math.Log2(micros)))
+}
+
+// getLatencies returns the histograms of the operations between the previous sample
+// and status by kind of operations, nil when mongod reports no histogram.
+func (e *MetricsExtractor) getLatencies(status *mongowrapper.ServerStatusStats) map[string]LatencyHistogram {
+	previous, current := e.previousStatus.OpLatencies, status.OpLatencies
+	if previous == nil || current == nil {
+		return nil
+	}
+	latencies := map[string]LatencyHistogram{}
+	for _, op := range LatencyOps {
+		h := getLatencyHistogram(
+			opLatency(previous, op),
+			opLatency(current, op),
+			e.previousStatus.LocalTime,
+			status.LocalTime,
+		)
+		if h != nil {
+			latencies[op] = h
+		}
+	}
+	if len(latencies) == 0 {
+		return nil
+	}
+	return latencies
+}
+
+func opLatency(latencies *mongowrapper.OpLatenciesStats, op string) *mongowrapper.OpLatencyStats {
+	switch op {
+	case "reads":
+		return latencies.Reads
+	case "writes":
+		return latencies.Writes
+	case "commands":
+		return latencies.Commands
+	}
+	return nil
+}
+
+// getLatencyHistogram returns the operations per second by bucket between two
+// samples. The buckets of mongod are finer than powers of two and only listed once
+// they have operations, they are merged into the buckets of LatencyHistogram.
+func getLatencyHistogram(
+	previous *mongowrapper.OpLatencyStats,
+	current *mongowrapper.OpLatencyStats,
+	previousTime time.Time,
+	currentTime time.Time,
+) LatencyHistogram {
+	if previous == nil || current == nil || len(current.Histogram) == 0 {
+		return nil
+	}
+	previousCounts := map[float64]float64{}
+	for _, b := range previous.Histogram {
+		previousCounts[b.Micros] = b.Count
+	}
+	h := LatencyHistogram{}
+	for _, b := range current.Histogram {
+		i := latencyBucket(b.Micros)
+		for len(h) <= i {
+			h = append(h, 0)
+		}
+		h[i] += math.Max(0, getPerSecond(previousCounts[b.Micros], b.Count, previousTime, currentTime))
+	}
+	return h
+}
@@ -39,6 +39,8 @@ type BytesPerSecondRecord struct {
 }
 
 type Metrics struct {
+	// Host is the target the metrics are extracted from.
+	Host                     string
 	InsertCountPerSecond     float64
 	QueryCountPerSecond      float64
 	UpdateCountPerSecond     float64
@@ -48,8 +50,11 @@ type Metrics struct {
 	NetworkInBytesPerSecond  float64
 	NetworkOutBytesPerSecond float64
 	CheckpointCountPerSecond float64
-	StartTime                time.Time
-	EndTime                  time.Time
+	// Latencies are the latency histograms by kind of operations, see LatencyOps,
+	// nil when mongod reports none.
+	Latencies map[string]LatencyHistogram
+	StartTime time.Time
+	EndTime   time.Time
 }
 
 type MetricsSlice []Metrics
@@ -66,100 +71,126 @@ func (ms MetricsSlice) Swap(i, j int) {
 	ms[i], ms[j] = ms[j], ms[i]
 }
 
-var previousStatus *mongowrapper.ServerStatusStats
+// MetricsExtractor turns consecutive serverStatus samples into metrics.
+type MetricsExtractor struct {
+	previousStatus *mongowrapper.ServerStatusStats
+}
 
+// NewMetricsExtractor returns an extractor waiting for its first sample.
+func NewMetricsExtractor() *MetricsExtractor {
+	return &MetricsExtractor{}
+}
+
+var defaultExtractor = NewMetricsExtractor()
+
+// ExtractMetrics extracts the metrics of status with the extractor shared by the program.
 func ExtractMetrics(status *mongowrapper.ServerStatusStats) *Metrics {
+	return defaultExtractor.Extract(status)
+}
+
+// Extract returns the metrics between the previous sample and status, nil is returned
+// for the first sample.
+func (e *MetricsExtractor) Extract(status *mongowrapper.ServerStatusStats) *Metrics {
 	var metrics Metrics
-	if previousStatus == nil {
-		previousStatus = status
+	// The counters restart from zero when mongod restarts.
+	if e.previousStatus == nil || status.Uptime < e.previousStatus.Uptime {
+		e.previousStatus = status
 		return nil
 	}
 	metrics = Metrics{
-		InsertCountPerSecond:     getCountPerSecondByAction(ActionInsert, status).Count,
-		QueryCountPerSecond:      getCountPerSecondByAction(ActionQuery, status).Count,
-		UpdateCountPerSecond:     getCountPerSecondByAction(ActionUpdate, status).Count,
-		DeleteCountPerSecond:     getCountPerSecondByAction(ActionDelete, status).Count,
-		GetmoreCountPerSecond:    getCountPerSecondByAction(ActionGetmore, status).Count,
-		CommandCountPerSecond:    getCountPerSecondByAction(ActionCommand, status).Count,
-		NetworkInBytesPerSecond:  getBytesPerSecondByAction(DataNetworkIn, status).Bytes,
-		NetworkOutBytesPerSecond: getBytesPerSecondByAction(DataNetworkOut, status).Bytes,
-		CheckpointCountPerSecond: getCountPerSecondByAction(ActionCheckpoint, status).Count,
-		StartTime:                previousStatus.LocalTime,
+		Host:                     status.Host,
+		InsertCountPerSecond:     e.getCountPerSecondByAction(ActionInsert, status).Count,
+		QueryCountPerSecond:      e.getCountPerSecondByAction(ActionQuery, status).Count,
+		UpdateCountPerSecond:     e.getCountPerSecondByAction(ActionUpdate, status).Count,
+		DeleteCountPerSecond:     e.getCountPerSecondByAction(ActionDelete, status).Count,
+		GetmoreCountPerSecond:    e.getCountPerSecondByAction(ActionGetmore, status).Count,
+		CommandCountPerSecond:    e.getCountPerSecondByAction(ActionCommand, status).Count,
+		NetworkInBytesPerSecond:  e.getBytesPerSecondByAction(DataNetworkIn, status).Bytes,
+		NetworkOutBytesPerSecond: e.getBytesPerSecondByAction(DataNetworkOut, status).Bytes,
+		CheckpointCountPerSecond: e.getCountPerSecondByAction(ActionCheckpoint, status).Count,
+		Latencies:                e.getLatencies(status),
+		StartTime:                e.previousStatus.LocalTime,
 		EndTime:                  status.LocalTime,
 	}
-	previousStatus = status
+	e.previousStatus = status
 	return &metrics
 }
 
-func getCountPerSecondByAction(
+func (e *MetricsExtractor) getCountPerSecondByAction(
 	actionType ActionType,
 	status *mongowrapper.ServerStatusStats,
 ) *CountPerSecondRecord {
-	previousTime := previousStatus.LocalTime
+	previousTime := e.previousStatus.LocalTime
 	currentTime := status.LocalTime
 	var previousCount, currentCount float64
 
 	switch actionType {
 	case ActionInsert:
-		previousCount = previousStatus.Opcounters.Insert
+		previousCount = e.previousStatus.Opcounters.Insert
 		currentCount = status.Opcounters.Insert
 	case ActionQuery:
-		previousCount = previousStatus.Opcounters.Query
+		previousCount = e.previousStatus.Opcounters.Query
 		currentCount = status.Opcounters.Query
 	case ActionUpdate:
-		previousCount = previousStatus.Opcounters.Update
+		previousCount = e.previousStatus.Opcounters.Update
 		currentCount = status.Opcounters.Update
 	case ActionDelete:
-		previousCount = previousStatus.Opcounters.Delete
+		previousCount = e.previousStatus.Opcounters.Delete
 		currentCount = status.Opcounters.Delete
 	case ActionGetmore:
-		previousCount = previousStatus.Opcounters.GetMore
+		previousCount = e.previousStatus.Opcounters.GetMore
 		currentCount = status.Opcounters.GetMore
 	case ActionCommand:
-		previousCount = previousStatus.Opcounters.Command
+		previousCount = e.previousStatus.Opcounters.Command
 		currentCount = status.Opcounters.Command
 	case ActionCheckpoint:
-		previousCount = previousStatus.WiredTiger.Transaction.Checkpoints
+		previousCount = e.previousStatus.WiredTiger.Transaction.Checkpoints
 		currentCount = status.WiredTiger.Transaction.Checkpoints
 	}
-	timeDiff := currentTime.Unix() - previousTime.Unix()
-	var countPerSecond float64
-	if timeDiff == 0 {
-		countPerSecond = currentCount - previousCount
-	} else {
-		countPerSecond = (currentCount - previousCount) / float64(currentTime.Unix()-previousTime.Unix())
-	}
-
 	return &CountPerSecondRecord{
 		ActionType: actionType,
 		StartTime:  previousTime,
 		EndTime:    currentTime,
-		Count:      countPerSecond,
+		Count:      getPerSecond(previousCount, currentCount, previousTime, currentTime),
 	}
 }
 
-func getBytesPerSecondByAction(
+func (e *MetricsExtractor) getBytesPerSecondByAction(
 	dataType DataType,
 	status *mongowrapper.ServerStatusStats,
 ) *BytesPerSecondRecord {
-	previousTime := previousStatus.LocalTime
+	previousTime := e.previousStatus.LocalTime
 	currentTime := status.LocalTime
 	var previousBytes, currentBytes float64
 
 	switch dataType {
 	case DataNetworkIn:
-		previousBytes = previousStatus.Network.BytesIn
+		previousBytes = e.previousStatus.Network.BytesIn
 		currentBytes = status.Network.BytesIn
 	case DataNetworkOut:
-		previousBytes = previousStatus.Network.BytesOut
+		previousBytes = e.previousStatus.Network.BytesOut
 		currentBytes = status.Network.BytesOut
 	}
-	bytesPerSecond := (currentBytes - previousBytes) / float64(currentTime.Unix()-previousTime.Unix())
-
 	return &BytesPerSecondRecord{
 		DataType:  dataType,
 		StartTime: previousTime,
 		EndTime:   currentTime,
-		Bytes:     bytesPerSecond,
+		Bytes:     getPerSecond(previousBytes, currentBytes, previousTime, currentTime),
+	}
+}
+
+// getPerSecond returns the rate of a counter between two samples, 0 when they are
+// not taken one after the other.
+func getPerSecond(
+	previousValue float64,
+	currentValue float64,
+	previousTime time.Time,
+	currentTime time.Time,
+) float64 {
+	// The samples may be less than a second apart, or taken at the same time.
+	seconds := currentTime.Sub(previousTime).Seconds()
+	if seconds <= 0 {
+		return 0
 	}
+	return (currentValue - previousValue) / seconds
 }
@@ -0,0 +1,29 @@
+package metric_helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetPerSecond(t *testing.T) {
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		previous float64
+		current  float64
+		elapsed  time.Duration
+		want     float64
+	}{
+		{"one second", 100, 150, time.Second, 50},
+		{"sub-second", 100, 150, 500 * time.Millisecond, 100},
+		{"non-integer", 0, 30, 1500 * time.Millisecond, 20},
+		{"same time", 100, 150, 0, 0},
+		{"clock going back", 100, 150, -time.Second, 0},
+	}
+	for _, test := range tests {
+		got := getPerSecond(test.previous, test.current, start, start.Add(test.elapsed))
+		if got != test.want {
+			t.Errorf("%s: getPerSecond() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package metric_helper
+
+import (
+	"fmt"
+	"mongo-monitor/mongowrapper"
+	"sort"
+	"strings"
+)
+
+type Operation struct {
+	OpID           interface{}
+	Ns             string
+	Op             string
+	SecsRunning    float64
+	PlanSummary    string
+	Client         string
+	Desc           string
+	WaitingForLock bool
+	LockWaitMicros float64
+}
+
+type OperationSortKey int
+
+const (
+	SortOperationsBySecsRunning OperationSortKey = iota
+	SortOperationsByLockWait
+	SortOperationsByNs
+	SortOperationsByOp
+)
+
+// String returns the column name of the sort key.
+func (k OperationSortKey) String() string {
+	switch k {
+	case SortOperationsByLockWait:
+		return "lock wait"
+	case SortOperationsByNs:
+		return "ns"
+	case SortOperationsByOp:
+		return "op"
+	default:
+		return "secs_running"
+	}
+}
+
+// Next returns the sort key following k, wrapping around.
+func (k OperationSortKey) Next() OperationSortKey {
+	return (k + 1) % (SortOperationsByOp + 1)
+}
+
+type Operations []Operation
+
+// ExtractOperations converts the $currentOp documents into operations.
+func ExtractOperations(ops []mongowrapper.CurrentOp) Operations {
+	operations := make(Operations, 0, len(ops))
+	for i := range ops {
+		operations = append(operations, Operation{
+			OpID:           ops[i].OpID,
+			Ns:             ops[i].Ns,
+			Op:             ops[i].Op,
+			SecsRunning:    ops[i].MicrosecsRunning / 1000000,
+			PlanSummary:    ops[i].PlanSummary,
+			Client:         ops[i].ClientAddress(),
+			Desc:           ops[i].Desc,
+			WaitingForLock: ops[i].WaitingForLock,
+			LockWaitMicros: ops[i].LockWaitMicros(),
+		})
+	}
+	return operations
+}
+
+// Filter returns the operations whose ns, op, plan summary, client or desc contain the keyword.
+func (ops Operations) Filter(keyword string) Operations {
+	if keyword == "" {
+		return ops
+	}
+	filtered := Operations{}
+	for _, op := range ops {
+		for _, field := range []string{op.Ns, op.Op, op.PlanSummary, op.Client, op.Desc} {
+			if strings.Contains(field, keyword) {
+				filtered = append(filtered, op)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// Sort sorts the operations by the given key, longest running and most waiting first.
+func (ops Operations) Sort(key OperationSortKey) {
+	sort.SliceStable(ops, func(i, j int) bool {
+		switch key {
+		case SortOperationsByLockWait:
+			return ops[i].LockWaitMicros > ops[j].LockWaitMicros
+		case SortOperationsByNs:
+			return ops[i].Ns < ops[j].Ns
+		case SortOperationsByOp:
+			return ops[i].Op < ops[j].Op
+		default:
+			return ops[i].SecsRunning > ops[j].SecsRunning
+		}
+	})
+}
+
+// OpIDString returns the opid as a string, e.g. 1234 or shard01:1234.
+func (op Operation) OpIDString() string {
+	return fmt.Sprintf("%v", op.OpID)
+}
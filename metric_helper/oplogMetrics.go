@@ -0,0 +1,85 @@
+package metric_helper
+
+import (
+	"mongo-monitor/mongowrapper"
+	"time"
+)
+
+const bytesPerGB = 1024 * 1024 * 1024
+
+type OplogMetrics struct {
+	Host              string
+	WindowHours       float64
+	UsedBytes         float64
+	MaxBytes          float64
+	GBPerHour         float64
+	LaggingMember     string
+	MaxLagSeconds     float64
+	HoursUntilFallOff float64
+	Time              time.Time
+}
+
+// UsedGB returns the size of the oplog in GB.
+func (m OplogMetrics) UsedGB() float64 {
+	return m.UsedBytes / bytesPerGB
+}
+
+// MaxGB returns the maximum size of the oplog in GB.
+func (m OplogMetrics) MaxGB() float64 {
+	return m.MaxBytes / bytesPerGB
+}
+
+// ExtractOplogMetrics computes the oplog window, the oplog churn and how long the most
+// lagging secondary can keep up before the oplog rolls over its last applied entry.
+// replStatus may be nil when the node is not a member of a replica set.
+func ExtractOplogMetrics(
+	host string,
+	oplog *mongowrapper.OplogStats,
+	replStatus *mongowrapper.ReplSetStatus,
+) *OplogMetrics {
+	if oplog == nil || oplog.CollStats == nil {
+		return nil
+	}
+
+	windowSeconds := float64(oplog.LastTs.T) - float64(oplog.FirstTs.T)
+	metrics := OplogMetrics{
+		Host:        host,
+		WindowHours: windowSeconds / 3600,
+		UsedBytes:   oplog.CollStats.Size,
+		MaxBytes:    oplog.CollStats.MaxSize,
+		Time:        oplog.LocalTime,
+	}
+	if metrics.WindowHours > 0 {
+		metrics.GBPerHour = metrics.UsedGB() / metrics.WindowHours
+	}
+	metrics.HoursUntilFallOff = metrics.WindowHours
+
+	if replStatus == nil {
+		return &metrics
+	}
+	primary := replStatus.Primary()
+	if primary == nil {
+		return &metrics
+	}
+	for _, member := range replStatus.Members {
+		if member.StateStr != "SECONDARY" {
+			continue
+		}
+		lag := float64(primary.Optime.Ts.T) - float64(member.Optime.Ts.T)
+		if lag < 0 {
+			lag = 0
+		}
+		if metrics.LaggingMember == "" || lag > metrics.MaxLagSeconds {
+			metrics.LaggingMember = member.Name
+			metrics.MaxLagSeconds = lag
+		}
+	}
+	if metrics.LaggingMember != "" {
+		metrics.HoursUntilFallOff = metrics.WindowHours - metrics.MaxLagSeconds/3600
+		if metrics.HoursUntilFallOff < 0 {
+			metrics.HoursUntilFallOff = 0
+		}
+	}
+
+	return &metrics
+}
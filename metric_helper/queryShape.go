@@ -0,0 +1,281 @@
+package metric_helper
+
+import (
+	"mongo-monitor/mongowrapper"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// commandNames are the command keys recognized when naming a query shape, in priority order.
+var commandNames = []string{
+	"find", "aggregate", "count", "distinct", "findAndModify", "findandmodify",
+	"update", "delete", "insert", "getMore", "mapReduce", "geoNear",
+}
+
+// filterKeys are the command keys holding the predicate of a query shape, in priority order.
+var filterKeys = []string{"filter", "q", "query", "pipeline"}
+
+type SlowOperation struct {
+	Time         time.Time
+	Ns           string
+	Op           string
+	Shape        string
+	Millis       float64
+	DocsExamined float64
+	KeysExamined float64
+	NReturned    float64
+	PlanSummary  string
+}
+
+// ExtractProfileOperation converts a system.profile document into a slow operation.
+func ExtractProfileOperation(entry mongowrapper.ProfileEntry) SlowOperation {
+	command := entry.Command.Map()
+	if len(entry.Command) == 0 {
+		command = entry.Query.Map()
+	}
+	return SlowOperation{
+		Time:         entry.Ts,
+		Ns:           entry.Ns,
+		Op:           entry.Op,
+		Shape:        QueryShape(entry.Op, command),
+		Millis:       entry.Millis,
+		DocsExamined: entry.DocsExamined,
+		KeysExamined: entry.KeysExamined,
+		NReturned:    entry.NReturned,
+		PlanSummary:  entry.PlanSummary,
+	}
+}
+
+// QueryShape returns the shape of a command: its name, the keys and operators of its
+// predicate and its sort, with every value replaced by 1, e.g.
+// find {age: {$gt: 1}, name: 1} sort {age: 1}.
+func QueryShape(op string, command map[string]interface{}) string {
+	name := op
+	for _, commandName := range commandNames {
+		if _, ok := command[commandName]; ok {
+			name = commandName
+			break
+		}
+	}
+
+	predicate := interface{}(nil)
+	for _, key := range filterKeys {
+		if value, ok := command[key]; ok {
+			predicate = value
+			break
+		}
+	}
+	// Commands sent as update/delete batches keep the predicates in their statements.
+	for _, key := range []string{"updates", "deletes"} {
+		if predicate != nil {
+			break
+		}
+		if statements, ok := asArray(command[key]); ok && len(statements) > 0 {
+			if statement, ok := asMap(statements[0]); ok {
+				predicate = statement["q"]
+			}
+		}
+	}
+
+	shape := name
+	if predicate != nil {
+		shape += " " + NormalizeShape(predicate)
+	}
+	if sortSpec, ok := command["sort"]; ok {
+		shape += " sort " + NormalizeShape(sortSpec)
+	}
+	return shape
+}
+
+// NormalizeShape replaces every value of a document by 1 and sorts its keys, so that
+// queries only differing by their values share the same shape. Arrays of documents,
+// e.g. $or clauses or aggregation pipelines, are normalized element by element.
+func NormalizeShape(value interface{}) string {
+	if m, ok := asMap(value); ok {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, 0, len(keys))
+		for _, key := range keys {
+			fields = append(fields, key+": "+NormalizeShape(m[key]))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	if a, ok := asArray(value); ok {
+		elements := []string{}
+		for _, element := range a {
+			if _, ok := asMap(element); !ok {
+				return "1"
+			}
+			elements = append(elements, NormalizeShape(element))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	return "1"
+}
+
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case primitive.D:
+		return v.Map(), true
+	case primitive.M:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+func asArray(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case primitive.A:
+		return v, true
+	case []interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+type QueryShapeStats struct {
+	Ns           string    `json:"ns"`
+	Op           string    `json:"op"`
+	Shape        string    `json:"shape"`
+	Count        float64   `json:"count"`
+	TotalMillis  float64   `json:"totalMillis"`
+	AvgMillis    float64   `json:"avgMillis"`
+	MaxMillis    float64   `json:"maxMillis"`
+	DocsExamined float64   `json:"docsExamined"`
+	KeysExamined float64   `json:"keysExamined"`
+	NReturned    float64   `json:"nreturned"`
+	PlanSummary  string    `json:"planSummary"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+// ExaminedPerReturned returns the number of documents examined per document returned.
+func (s QueryShapeStats) ExaminedPerReturned() float64 {
+	if s.NReturned == 0 {
+		return s.DocsExamined
+	}
+	return s.DocsExamined / s.NReturned
+}
+
+type QueryShapeSortKey int
+
+const (
+	SortShapesByTotalMillis QueryShapeSortKey = iota
+	SortShapesByCount
+	SortShapesByAvgMillis
+	SortShapesByMaxMillis
+	SortShapesByExamined
+)
+
+// String returns the column name of the sort key.
+func (k QueryShapeSortKey) String() string {
+	switch k {
+	case SortShapesByCount:
+		return "count"
+	case SortShapesByAvgMillis:
+		return "avg ms"
+	case SortShapesByMaxMillis:
+		return "max ms"
+	case SortShapesByExamined:
+		return "examined/returned"
+	default:
+		return "total ms"
+	}
+}
+
+// Next returns the sort key following k, wrapping around.
+func (k QueryShapeSortKey) Next() QueryShapeSortKey {
+	return (k + 1) % (SortShapesByExamined + 1)
+}
+
+type QueryShapeStatsSlice []QueryShapeStats
+
+// Sort sorts the shapes by the given key, most expensive first.
+func (ss QueryShapeStatsSlice) Sort(key QueryShapeSortKey) {
+	value := func(s QueryShapeStats) float64 {
+		switch key {
+		case SortShapesByCount:
+			return s.Count
+		case SortShapesByAvgMillis:
+			return s.AvgMillis
+		case SortShapesByMaxMillis:
+			return s.MaxMillis
+		case SortShapesByExamined:
+			return s.ExaminedPerReturned()
+		default:
+			return s.TotalMillis
+		}
+	}
+	sort.SliceStable(ss, func(i, j int) bool {
+		return value(ss[i]) > value(ss[j])
+	})
+}
+
+// QueryShapeAggregator aggregates slow operations by namespace and query shape.
+type QueryShapeAggregator struct {
+	stats map[string]*QueryShapeStats
+	mutex sync.Mutex
+}
+
+// NewQueryShapeAggregator returns an empty aggregator.
+func NewQueryShapeAggregator() *QueryShapeAggregator {
+	return &QueryShapeAggregator{stats: map[string]*QueryShapeStats{}}
+}
+
+// Add aggregates a slow operation into the stats of its shape.
+func (a *QueryShapeAggregator) Add(op SlowOperation) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	key := op.Ns + " " + op.Shape
+	s, ok := a.stats[key]
+	if !ok {
+		s = &QueryShapeStats{
+			Ns:        op.Ns,
+			Op:        op.Op,
+			Shape:     op.Shape,
+			FirstSeen: op.Time,
+		}
+		a.stats[key] = s
+	}
+	s.Count++
+	s.TotalMillis += op.Millis
+	s.AvgMillis = s.TotalMillis / s.Count
+	if op.Millis > s.MaxMillis {
+		s.MaxMillis = op.Millis
+	}
+	s.DocsExamined += op.DocsExamined
+	s.KeysExamined += op.KeysExamined
+	s.NReturned += op.NReturned
+	if op.PlanSummary != "" {
+		s.PlanSummary = op.PlanSummary
+	}
+	if op.Time.Before(s.FirstSeen) {
+		s.FirstSeen = op.Time
+	}
+	if op.Time.After(s.LastSeen) {
+		s.LastSeen = op.Time
+	}
+}
+
+// Slice returns a copy of the stats of every shape.
+func (a *QueryShapeAggregator) Slice() QueryShapeStatsSlice {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	ss := make(QueryShapeStatsSlice, 0, len(a.stats))
+	for _, s := range a.stats {
+		ss = append(ss, *s)
+	}
+	ss.Sort(SortShapesByTotalMillis)
+	return ss
+}
@@ -16,9 +16,9 @@ type CommandCountPerSecondRecord struct {
 var records []CommandCountPerSecondRecord
 
 func recordCommandCountPerSecond(status *mongowrapper.ServerStatusStats) CommandCountPerSecondRecord {
-	previousUnixTime := previousStatus.LocalTime
+	previousUnixTime := defaultExtractor.previousStatus.LocalTime
 	currentUnixTime := status.LocalTime
-	previousInsertCount := previousStatus.Opcounters.Command
+	previousInsertCount := defaultExtractor.previousStatus.Opcounters.Command
 	currentInsertCount := status.Opcounters.Command
 	insertCountPerSecond := (currentInsertCount - previousInsertCount) / float64(currentUnixTime.Unix()-previousUnixTime.Unix())
 	logrus.Info(previousUnixTime, currentUnixTime, previousInsertCount, currentInsertCount, insertCountPerSecond)
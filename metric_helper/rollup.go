@@ -0,0 +1,67 @@
+package metric_helper
+
+import (
+	"time"
+)
+
+// Rollup returns the rates of ms averaged over the steps starting at from, ms being
+// sorted by EndTime. A rolled up metrics starts at its step and ends with the last
+// sample of the step, a step without sample is missing.
+func (ms MetricsSlice) Rollup(from time.Time, step time.Duration) MetricsSlice {
+	rollup := MetricsSlice{}
+	if step <= 0 {
+		return append(rollup, ms...)
+	}
+
+	var sum Metrics
+	count := 0
+	var bucket int64 = -1
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		n := float64(count)
+		sum.InsertCountPerSecond /= n
+		sum.QueryCountPerSecond /= n
+		sum.UpdateCountPerSecond /= n
+		sum.DeleteCountPerSecond /= n
+		sum.GetmoreCountPerSecond /= n
+		sum.CommandCountPerSecond /= n
+		sum.NetworkInBytesPerSecond /= n
+		sum.NetworkOutBytesPerSecond /= n
+		sum.CheckpointCountPerSecond /= n
+		for _, h := range sum.Latencies {
+			for i := range h {
+				h[i] /= n
+			}
+		}
+		rollup = append(rollup, sum)
+	}
+	for _, m := range ms {
+		if b := int64(m.EndTime.Sub(from) / step); b != bucket {
+			flush()
+			bucket = b
+			sum = Metrics{Host: m.Host, StartTime: from.Add(time.Duration(b) * step)}
+			count = 0
+		}
+		sum.InsertCountPerSecond += m.InsertCountPerSecond
+		sum.QueryCountPerSecond += m.QueryCountPerSecond
+		sum.UpdateCountPerSecond += m.UpdateCountPerSecond
+		sum.DeleteCountPerSecond += m.DeleteCountPerSecond
+		sum.GetmoreCountPerSecond += m.GetmoreCountPerSecond
+		sum.CommandCountPerSecond += m.CommandCountPerSecond
+		sum.NetworkInBytesPerSecond += m.NetworkInBytesPerSecond
+		sum.NetworkOutBytesPerSecond += m.NetworkOutBytesPerSecond
+		sum.CheckpointCountPerSecond += m.CheckpointCountPerSecond
+		for op, h := range m.Latencies {
+			if sum.Latencies == nil {
+				sum.Latencies = map[string]LatencyHistogram{}
+			}
+			sum.Latencies[op] = sum.Latencies[op].Add(h)
+		}
+		sum.EndTime = m.EndTime
+		count++
+	}
+	flush()
+	return rollup
+}
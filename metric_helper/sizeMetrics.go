@@ -0,0 +1,101 @@
+package metric_helper
+
+import (
+	"mongo-monitor/mongowrapper"
+	"sort"
+	"time"
+)
+
+type SizeMetrics struct {
+	Database    string
+	Collection  string
+	DataSize    float64
+	StorageSize float64
+	IndexSize   float64
+	Count       float64
+	// DataGrowthPerHour is the change of DataSize per hour since the previous sample.
+	DataGrowthPerHour float64
+	Time              time.Time
+}
+
+// Namespace returns "db" for database sizes and "db.collection" for collection sizes.
+func (m SizeMetrics) Namespace() string {
+	if m.Collection == "" {
+		return m.Database
+	}
+	return m.Database + "." + m.Collection
+}
+
+type SizeMetricsSlice []SizeMetrics
+
+// SortBySize sorts the slice by data size, biggest first.
+func (ms SizeMetricsSlice) SortBySize() {
+	sort.SliceStable(ms, func(i, j int) bool {
+		return ms[i].DataSize > ms[j].DataSize
+	})
+}
+
+// SortByGrowth sorts the slice by data growth, fastest growing first.
+func (ms SizeMetricsSlice) SortByGrowth() {
+	sort.SliceStable(ms, func(i, j int) bool {
+		return ms[i].DataGrowthPerHour > ms[j].DataGrowthPerHour
+	})
+}
+
+// ExtractDBSizeMetrics converts dbStats into size metrics, computing the growth
+// against the previous sample of the same database when there is one.
+func ExtractDBSizeMetrics(
+	stats *mongowrapper.DBStats,
+	previous *SizeMetrics,
+	now time.Time,
+) *SizeMetrics {
+	if stats == nil {
+		return nil
+	}
+	metrics := &SizeMetrics{
+		Database:    stats.DB,
+		DataSize:    stats.DataSize,
+		StorageSize: stats.StorageSize,
+		IndexSize:   stats.IndexSize,
+		Count:       stats.Objects,
+		Time:        now,
+	}
+	metrics.DataGrowthPerHour = getGrowthPerHour(previous, metrics)
+	return metrics
+}
+
+// ExtractCollSizeMetrics converts collStats into size metrics, computing the growth
+// against the previous sample of the same collection when there is one.
+func ExtractCollSizeMetrics(
+	db string,
+	collection string,
+	stats *mongowrapper.CollStats,
+	previous *SizeMetrics,
+	now time.Time,
+) *SizeMetrics {
+	if stats == nil {
+		return nil
+	}
+	metrics := &SizeMetrics{
+		Database:    db,
+		Collection:  collection,
+		DataSize:    stats.Size,
+		StorageSize: stats.StorageSize,
+		IndexSize:   stats.TotalIndexSize,
+		Count:       stats.Count,
+		Time:        now,
+	}
+	metrics.DataGrowthPerHour = getGrowthPerHour(previous, metrics)
+	return metrics
+}
+
+func getGrowthPerHour(previous *SizeMetrics, current *SizeMetrics) float64 {
+	if previous == nil {
+		return 0
+	}
+	hours := current.Time.Sub(previous.Time).Hours()
+	if hours <= 0 {
+		return previous.DataGrowthPerHour
+	}
+	return (current.DataSize - previous.DataSize) / hours
+}
@@ -0,0 +1,77 @@
+package metric_helper
+
+import (
+	"mongo-monitor/mongowrapper"
+	"sort"
+	"time"
+)
+
+type NamespaceMetrics struct {
+	Ns string
+	// The lock times are the milliseconds spent holding locks per second,
+	// 1000 means the namespace kept a lock for the whole interval.
+	ReadLockMillisPerSecond  float64
+	WriteLockMillisPerSecond float64
+	TotalMillisPerSecond     float64
+	ReadOpsPerSecond         float64
+	WriteOpsPerSecond        float64
+	OpsPerSecond             float64
+	StartTime                time.Time
+	EndTime                  time.Time
+}
+
+type NamespaceMetricsSlice []NamespaceMetrics
+
+// SortByHotness sorts the slice by the time spent in the namespace, hottest first.
+func (ms NamespaceMetricsSlice) SortByHotness() {
+	sort.SliceStable(ms, func(i, j int) bool {
+		if ms[i].TotalMillisPerSecond == ms[j].TotalMillisPerSecond {
+			return ms[i].OpsPerSecond > ms[j].OpsPerSecond
+		}
+		return ms[i].TotalMillisPerSecond > ms[j].TotalMillisPerSecond
+	})
+}
+
+// TopExtractor turns consecutive top samples into per namespace rates.
+type TopExtractor struct {
+	previousTop *mongowrapper.TopStats
+}
+
+// NewTopExtractor returns an extractor waiting for its first sample.
+func NewTopExtractor() *TopExtractor {
+	return &TopExtractor{}
+}
+
+// Extract computes the per namespace rates since the previous top sample, sorted by
+// hotness. It returns nil for the first sample.
+func (e *TopExtractor) Extract(top *mongowrapper.TopStats) NamespaceMetricsSlice {
+	if e.previousTop == nil {
+		e.previousTop = top
+		return nil
+	}
+
+	previousTime := e.previousTop.LocalTime
+	currentTime := top.LocalTime
+	ms := NamespaceMetricsSlice{}
+	for ns, current := range top.Totals {
+		// Namespaces dropped and created again between two samples restart from 0.
+		previous, ok := e.previousTop.Totals[ns]
+		if !ok || current.Total.Count < previous.Total.Count {
+			previous = mongowrapper.TopNamespaceStats{}
+		}
+		ms = append(ms, NamespaceMetrics{
+			Ns:                       ns,
+			ReadLockMillisPerSecond:  getPerSecond(previous.ReadLock.Time, current.ReadLock.Time, previousTime, currentTime) / 1000,
+			WriteLockMillisPerSecond: getPerSecond(previous.WriteLock.Time, current.WriteLock.Time, previousTime, currentTime) / 1000,
+			TotalMillisPerSecond:     getPerSecond(previous.Total.Time, current.Total.Time, previousTime, currentTime) / 1000,
+			ReadOpsPerSecond:         getPerSecond(previous.ReadLock.Count, current.ReadLock.Count, previousTime, currentTime),
+			WriteOpsPerSecond:        getPerSecond(previous.WriteLock.Count, current.WriteLock.Count, previousTime, currentTime),
+			OpsPerSecond:             getPerSecond(previous.Total.Count, current.Total.Count, previousTime, currentTime),
+			StartTime:                previousTime,
+			EndTime:                  currentTime,
+		})
+	}
+	e.previousTop = top
+	ms.SortByHotness()
+	return ms
+}
@@ -0,0 +1,33 @@
+package metric_helper
+
+import (
+	"testing"
+	"time"
+
+	"mongo-monitor/mongowrapper"
+)
+
+func topSample(at time.Time, totals map[string]float64) *mongowrapper.TopStats {
+	top := &mongowrapper.TopStats{Totals: map[string]mongowrapper.TopNamespaceStats{}, LocalTime: at}
+	for ns, micros := range totals {
+		top.Totals[ns] = mongowrapper.TopNamespaceStats{Total: mongowrapper.TopUsageStats{Time: micros, Count: micros / 100}}
+	}
+	return top
+}
+
+func TestTopExtractor(t *testing.T) {
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	a, b := NewTopExtractor(), NewTopExtractor()
+	if ms := a.Extract(topSample(start, map[string]float64{"test.users": 0, "test.orders": 0})); ms != nil {
+		t.Errorf("first sample = %v, want nil", ms)
+	}
+	// Another extractor, like the one of another node, keeps its own samples.
+	if ms := b.Extract(topSample(start, map[string]float64{"test.users": 5e6})); ms != nil {
+		t.Errorf("first sample of another extractor = %v, want nil", ms)
+	}
+
+	ms := a.Extract(topSample(start.Add(2*time.Second), map[string]float64{"test.users": 1e6, "test.orders": 3e6}))
+	if len(ms) != 2 || ms[0].Ns != "test.orders" || ms[0].TotalMillisPerSecond != 1500 || ms[1].TotalMillisPerSecond != 500 {
+		t.Errorf("Extract() = %+v, want test.orders at 1500ms/s then test.users at 500ms/s", ms)
+	}
+}
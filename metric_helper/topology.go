@@ -0,0 +1,56 @@
+package metric_helper
+
+import (
+	"mongo-monitor/mongowrapper"
+	"time"
+)
+
+// TopologyMember is a member of the replica set of a target.
+type TopologyMember struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Self  bool   `json:"self"`
+	// LagSeconds is how far the member is behind the primary, zero for the primary.
+	LagSeconds float64   `json:"lagSeconds"`
+	OptimeDate time.Time `json:"optimeDate"`
+}
+
+// Topology is the replica set of a target, a standalone target is its only member.
+type Topology struct {
+	Host    string           `json:"host"`
+	SetName string           `json:"setName,omitempty"`
+	Primary string           `json:"primary,omitempty"`
+	Members []TopologyMember `json:"members"`
+	Time    time.Time        `json:"time"`
+}
+
+// ExtractTopology returns the topology seen from host at t. replStatus may be nil
+// when the node is not a member of a replica set.
+func ExtractTopology(host string, t time.Time, replStatus *mongowrapper.ReplSetStatus) *Topology {
+	topology := &Topology{Host: host, Time: t}
+	if replStatus == nil {
+		topology.Members = []TopologyMember{{Name: host, State: "STANDALONE", Self: true}}
+		return topology
+	}
+
+	topology.SetName = replStatus.Set
+	primary := replStatus.Primary()
+	if primary != nil {
+		topology.Primary = primary.Name
+	}
+	topology.Members = make([]TopologyMember, len(replStatus.Members))
+	for i, member := range replStatus.Members {
+		topology.Members[i] = TopologyMember{
+			Name:       member.Name,
+			State:      member.StateStr,
+			Self:       member.Self,
+			OptimeDate: member.OptimeDate,
+		}
+		if primary != nil && member.StateStr == "SECONDARY" {
+			if lag := float64(primary.Optime.Ts.T) - float64(member.Optime.Ts.T); lag > 0 {
+				topology.Members[i].LagSeconds = lag
+			}
+		}
+	}
+	return topology
+}
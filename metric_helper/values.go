@@ -0,0 +1,87 @@
+package metric_helper
+
+import (
+	"mongo-monitor/mongowrapper"
+	"sort"
+)
+
+// MetricValues returns the values of a sample by metric name, the names rules and
+// exports refer to. metrics may be nil, the rates are missing in that case.
+func MetricValues(status *mongowrapper.ServerStatusStats, metrics *Metrics) map[string]float64 {
+	values := map[string]float64{
+		"uptime_seconds": status.Uptime,
+	}
+	if c := status.Connections; c != nil {
+		values["connections_current"] = c.Current
+		values["connections_available"] = c.Available
+		if c.Current+c.Available > 0 {
+			values["connections_used_pct"] = 100 * c.Current / (c.Current + c.Available)
+		}
+	}
+	if status.WiredTiger != nil && status.WiredTiger.Cache != nil {
+		cache := status.WiredTiger.Cache
+		values["wt_cache_bytes"] = cache.CurrentBytes
+		values["wt_cache_dirty_bytes"] = cache.TrackedDirtyBytes
+		values["wt_cache_max_bytes"] = cache.MaxBytes
+		if cache.MaxBytes > 0 {
+			values["wt_cache_used_pct"] = 100 * cache.CurrentBytes / cache.MaxBytes
+			values["wt_cache_dirty_pct"] = 100 * cache.TrackedDirtyBytes / cache.MaxBytes
+		}
+	}
+	if metrics != nil {
+		for name, value := range metrics.Values() {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// Values returns the rates by metric name.
+func (m *Metrics) Values() map[string]float64 {
+	return map[string]float64{
+		"insert_per_second":            m.InsertCountPerSecond,
+		"query_per_second":             m.QueryCountPerSecond,
+		"update_per_second":            m.UpdateCountPerSecond,
+		"delete_per_second":            m.DeleteCountPerSecond,
+		"getmore_per_second":           m.GetmoreCountPerSecond,
+		"command_per_second":           m.CommandCountPerSecond,
+		"network_in_bytes_per_second":  m.NetworkInBytesPerSecond,
+		"network_out_bytes_per_second": m.NetworkOutBytesPerSecond,
+		"checkpoint_per_second":        m.CheckpointCountPerSecond,
+	}
+}
+
+// Values returns the oplog metrics by metric name, the lag is missing when the node
+// has no secondary.
+func (m *OplogMetrics) Values() map[string]float64 {
+	values := map[string]float64{
+		"oplog_window_hours":         m.WindowHours,
+		"oplog_used_bytes":           m.UsedBytes,
+		"oplog_max_bytes":            m.MaxBytes,
+		"oplog_gb_per_hour":          m.GBPerHour,
+		"oplog_hours_until_fall_off": m.HoursUntilFallOff,
+	}
+	if m.LaggingMember != "" {
+		values["repl_lag_seconds"] = m.MaxLagSeconds
+	}
+	return values
+}
+
+// MetricNames returns the sorted names of the metrics of MetricValues and of
+// OplogMetrics.Values.
+func MetricNames() []string {
+	// A sample having every section, so every metric is present.
+	status := &mongowrapper.ServerStatusStats{
+		Connections: &mongowrapper.ConnectionsStats{Available: 1},
+		WiredTiger:  &mongowrapper.WiredTigerStats{Cache: &mongowrapper.WTCacheStats{MaxBytes: 1}},
+	}
+	names := []string{}
+	for name := range MetricValues(status, &Metrics{}) {
+		names = append(names, name)
+	}
+	for name := range (&OplogMetrics{LaggingMember: "member"}).Values() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
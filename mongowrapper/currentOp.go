@@ -0,0 +1,117 @@
+package mongowrapper
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// LockStats are the lock statistics of an operation for one resource.
+type LockStats struct {
+	AcquireWaitCount    map[string]float64 `bson:"acquireWaitCount"`
+	TimeAcquiringMicros map[string]float64 `bson:"timeAcquiringMicros"`
+}
+
+// CurrentOp keeps a document returned by the $currentOp aggregation stage.
+type CurrentOp struct {
+	// OpID is an int32 on mongod and a "shard:opid" string on mongos.
+	OpID             interface{}          `bson:"opid"`
+	Active           bool                 `bson:"active"`
+	Desc             string               `bson:"desc"`
+	Ns               string               `bson:"ns"`
+	Op               string               `bson:"op"`
+	SecsRunning      float64              `bson:"secs_running"`
+	MicrosecsRunning float64              `bson:"microsecs_running"`
+	PlanSummary      string               `bson:"planSummary"`
+	Client           string               `bson:"client"`
+	ClientS          string               `bson:"client_s"`
+	AppName          string               `bson:"appName"`
+	WaitingForLock   bool                 `bson:"waitingForLock"`
+	NumYields        float64              `bson:"numYields"`
+	LockStats        map[string]LockStats `bson:"lockStats"`
+	Command          bson.Raw             `bson:"command"`
+}
+
+// ClientAddress returns the address of the client which sent the operation.
+func (op *CurrentOp) ClientAddress() string {
+	if op.Client != "" {
+		return op.Client
+	}
+	return op.ClientS
+}
+
+// LockWaitMicros returns the total time the operation spent waiting for locks.
+func (op *CurrentOp) LockWaitMicros() float64 {
+	total := 0.0
+	for _, stats := range op.LockStats {
+		for _, micros := range stats.TimeAcquiringMicros {
+			total += micros
+		}
+	}
+	return total
+}
+
+// GetCurrentOps returns the operations currently running, idle connections excluded.
+func GetCurrentOps(ctx context.Context, client *mongo.Client) ([]CurrentOp, error) {
+	currentOpStage := bsonx.Doc{{Key: "$currentOp", Value: bsonx.Document(bsonx.Doc{
+		{Key: "allUsers", Value: bsonx.Boolean(true)},
+		{Key: "idleConnections", Value: bsonx.Boolean(false)},
+	})}}
+	cursor, err := client.Database("admin").RunCommandCursor(
+		ctx,
+		bsonx.Doc{
+			{Key: "aggregate", Value: bsonx.Int32(1)},
+			{Key: "pipeline", Value: bsonx.Array(bsonx.Arr{bsonx.Document(currentOpStage)})},
+			{Key: "cursor", Value: bsonx.Document(bsonx.Doc{})},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ops := []CurrentOp{}
+	for cursor.Next(ctx) {
+		op := CurrentOp{}
+		if err := cursor.Decode(&op); err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, cursor.Err()
+}
+
+// KillOp kills the operation with the given opid.
+func KillOp(ctx context.Context, client *mongo.Client, opID interface{}) error {
+	var op bsonx.Val
+	switch id := opID.(type) {
+	case int32:
+		op = bsonx.Int32(id)
+	case int64:
+		op = bsonx.Int64(id)
+	case float64:
+		op = bsonx.Double(id)
+	case string:
+		op = bsonx.String(id)
+	default:
+		return &UnsupportedOpID{OpID: opID}
+	}
+	return client.Database("admin").RunCommand(
+		ctx,
+		bsonx.Doc{
+			{Key: "killOp", Value: bsonx.Int32(1)},
+			{Key: "op", Value: op},
+		},
+	).Err()
+}
+
+// UnsupportedOpID is returned when an opid has a type killOp does not accept.
+type UnsupportedOpID struct {
+	OpID interface{}
+}
+
+func (e *UnsupportedOpID) Error() string {
+	return "Unsupported opid type"
+}
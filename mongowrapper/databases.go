@@ -0,0 +1,83 @@
+package mongowrapper
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// DBStats keeps the data returned by the dbStats command.
+type DBStats struct {
+	DB          string  `bson:"db"`
+	Collections float64 `bson:"collections"`
+	Objects     float64 `bson:"objects"`
+	DataSize    float64 `bson:"dataSize"`
+	StorageSize float64 `bson:"storageSize"`
+	IndexSize   float64 `bson:"indexSize"`
+}
+
+// CollStats keeps the data returned by the collStats command.
+type CollStats struct {
+	Ns             string             `bson:"ns"`
+	Count          float64            `bson:"count"`
+	Size           float64            `bson:"size"`
+	StorageSize    float64            `bson:"storageSize"`
+	TotalIndexSize float64            `bson:"totalIndexSize"`
+	IndexSizes     map[string]float64 `bson:"indexSizes"`
+}
+
+// ListDatabaseNames returns the names of all databases.
+func ListDatabaseNames(ctx context.Context, client *mongo.Client) ([]string, error) {
+	return client.ListDatabaseNames(ctx, bsonx.Doc{})
+}
+
+// ListCollectionNames returns the names of the collections of a database, views excluded.
+func ListCollectionNames(ctx context.Context, client *mongo.Client, db string) ([]string, error) {
+	cursor, err := client.Database(db).ListCollections(
+		ctx,
+		bsonx.Doc{{Key: "type", Value: bsonx.String("collection")}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	names := []string{}
+	for cursor.Next(ctx) {
+		var collection struct {
+			Name string `bson:"name"`
+		}
+		if err := cursor.Decode(&collection); err != nil {
+			return nil, err
+		}
+		names = append(names, collection.Name)
+	}
+	return names, cursor.Err()
+}
+
+// GetDBStats returns the dbStats of a database.
+func GetDBStats(ctx context.Context, client *mongo.Client, db string) (*DBStats, error) {
+	stats := &DBStats{}
+	result := client.Database(db).RunCommand(
+		ctx,
+		bsonx.Doc{{Key: "dbStats", Value: bsonx.Int32(1)}},
+	)
+	if err := result.Decode(stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetCollStats returns the collStats of a collection.
+func GetCollStats(ctx context.Context, client *mongo.Client, db string, collection string) (*CollStats, error) {
+	stats := &CollStats{}
+	result := client.Database(db).RunCommand(
+		ctx,
+		bsonx.Doc{{Key: "collStats", Value: bsonx.String(collection)}},
+	)
+	if err := result.Decode(stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
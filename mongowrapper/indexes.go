@@ -0,0 +1,44 @@
+package mongowrapper
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// IndexAccessesStats are the usage counters of an index.
+type IndexAccessesStats struct {
+	Ops   float64   `bson:"ops"`
+	Since time.Time `bson:"since"`
+}
+
+// IndexStats keeps a document returned by the $indexStats aggregation stage.
+type IndexStats struct {
+	Name     string             `bson:"name"`
+	Host     string             `bson:"host"`
+	Accesses IndexAccessesStats `bson:"accesses"`
+}
+
+// GetIndexStats returns the $indexStats of every index of a collection.
+func GetIndexStats(ctx context.Context, client *mongo.Client, db string, collection string) ([]IndexStats, error) {
+	cursor, err := client.Database(db).Collection(collection).Aggregate(
+		ctx,
+		[]bsonx.Doc{{{Key: "$indexStats", Value: bsonx.Document(bsonx.Doc{})}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	stats := []IndexStats{}
+	for cursor.Next(ctx) {
+		stat := IndexStats{}
+		if err := cursor.Decode(&stat); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, cursor.Err()
+}
@@ -0,0 +1,23 @@
+package mongowrapper
+
+// OpLatencyBucketStats is a bucket of a latency histogram, the operations that took
+// at least Micros microseconds and less than the next bucket.
+type OpLatencyBucketStats struct {
+	Micros float64 `bson:"micros"`
+	Count  float64 `bson:"count"`
+}
+
+// OpLatencyStats are the latencies of a kind of operations since mongod started,
+// Histogram is only reported when serverStatus is asked for it.
+type OpLatencyStats struct {
+	Latency   float64                `bson:"latency"`
+	Ops       float64                `bson:"ops"`
+	Histogram []OpLatencyBucketStats `bson:"histogram"`
+}
+
+// OpLatenciesStats opLatencies stats
+type OpLatenciesStats struct {
+	Reads    *OpLatencyStats `bson:"reads"`
+	Writes   *OpLatencyStats `bson:"writes"`
+	Commands *OpLatencyStats `bson:"commands"`
+}
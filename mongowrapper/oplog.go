@@ -0,0 +1,77 @@
+package mongowrapper
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// OplogCollStats are the collStats of local.oplog.rs.
+type OplogCollStats struct {
+	Size        float64 `bson:"size"`
+	Count       float64 `bson:"count"`
+	StorageSize float64 `bson:"storageSize"`
+	MaxSize     float64 `bson:"maxSize"`
+}
+
+// OplogStats keeps the boundaries and the size of the oplog.
+type OplogStats struct {
+	FirstTs   primitive.Timestamp
+	LastTs    primitive.Timestamp
+	CollStats *OplogCollStats
+	LocalTime time.Time
+}
+
+type oplogEntry struct {
+	Ts primitive.Timestamp `bson:"ts"`
+}
+
+// GetOplogStats returns the first/last entry timestamps and the collStats of the oplog.
+func GetOplogStats(ctx context.Context, client *mongo.Client) (*OplogStats, error) {
+	local := client.Database("local")
+	oplog := local.Collection("oplog.rs")
+
+	first, err := findOplogEntry(ctx, oplog, 1)
+	if err != nil {
+		return nil, err
+	}
+	last, err := findOplogEntry(ctx, oplog, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	collStats := &OplogCollStats{}
+	result := local.RunCommand(
+		ctx,
+		bsonx.Doc{{Key: "collStats", Value: bsonx.String("oplog.rs")}},
+	)
+	if err := result.Decode(collStats); err != nil {
+		return nil, err
+	}
+
+	return &OplogStats{
+		FirstTs:   first.Ts,
+		LastTs:    last.Ts,
+		CollStats: collStats,
+		LocalTime: time.Now(),
+	}, nil
+}
+
+func findOplogEntry(ctx context.Context, oplog *mongo.Collection, natural int32) (*oplogEntry, error) {
+	entry := &oplogEntry{}
+	err := oplog.FindOne(
+		ctx,
+		bsonx.Doc{},
+		options.FindOne().
+			SetSort(bsonx.Doc{{Key: "$natural", Value: bsonx.Int32(natural)}}).
+			SetProjection(bsonx.Doc{{Key: "ts", Value: bsonx.Int32(1)}}),
+	).Decode(entry)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
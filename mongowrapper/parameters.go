@@ -0,0 +1,32 @@
+package mongowrapper
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// featureCompatibilityVersionParameter is the featureCompatibilityVersion returned by getParameter.
+type featureCompatibilityVersionParameter struct {
+	FeatureCompatibilityVersion struct {
+		Version string `bson:"version"`
+	} `bson:"featureCompatibilityVersion"`
+}
+
+// GetFeatureCompatibilityVersion returns the featureCompatibilityVersion of the node,
+// mongod reports it from 3.6 on.
+func GetFeatureCompatibilityVersion(ctx context.Context, client *mongo.Client) (string, error) {
+	parameter := &featureCompatibilityVersionParameter{}
+	result := client.Database("admin").RunCommand(
+		ctx,
+		bsonx.Doc{
+			{Key: "getParameter", Value: bsonx.Int32(1)},
+			{Key: "featureCompatibilityVersion", Value: bsonx.Int32(1)},
+		},
+	)
+	if err := result.Decode(parameter); err != nil {
+		return "", err
+	}
+	return parameter.FeatureCompatibilityVersion.Version, nil
+}
@@ -0,0 +1,110 @@
+package mongowrapper
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// ProfilingLevel keeps the data returned by the profile command.
+type ProfilingLevel struct {
+	Was    int32 `bson:"was"`
+	SlowMs int32 `bson:"slowms"`
+}
+
+// ProfileEntry keeps a document of the system.profile collection.
+type ProfileEntry struct {
+	Op           string    `bson:"op"`
+	Ns           string    `bson:"ns"`
+	Command      bson.D    `bson:"command"`
+	Query        bson.D    `bson:"query"`
+	Millis       float64   `bson:"millis"`
+	DocsExamined float64   `bson:"docsExamined"`
+	KeysExamined float64   `bson:"keysExamined"`
+	NReturned    float64   `bson:"nreturned"`
+	NModified    float64   `bson:"nModified"`
+	PlanSummary  string    `bson:"planSummary"`
+	Client       string    `bson:"client"`
+	AppName      string    `bson:"appName"`
+	Ts           time.Time `bson:"ts"`
+	// Raw is the document as read, the profiler writes no _id to tell the documents
+	// of the same millisecond apart.
+	Raw bson.Raw `bson:"-"`
+}
+
+// GetProfilingLevel returns the profiling level and slowms of a database.
+func GetProfilingLevel(ctx context.Context, client *mongo.Client, db string) (*ProfilingLevel, error) {
+	level := &ProfilingLevel{}
+	err := client.Database(db).RunCommand(
+		ctx,
+		bsonx.Doc{{Key: "profile", Value: bsonx.Int32(-1)}},
+	).Decode(level)
+	if err != nil {
+		return nil, err
+	}
+	return level, nil
+}
+
+// SetProfilingLevel sets the profiling level and slowms of a database and returns the previous ones.
+func SetProfilingLevel(ctx context.Context, client *mongo.Client, db string, level int32, slowMs int32) (*ProfilingLevel, error) {
+	previous := &ProfilingLevel{}
+	err := client.Database(db).RunCommand(
+		ctx,
+		bsonx.Doc{
+			{Key: "profile", Value: bsonx.Int32(level)},
+			{Key: "slowms", Value: bsonx.Int32(slowMs)},
+		},
+	).Decode(previous)
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// GetLastProfileTime returns the time of the newest system.profile document of a
+// database, the zero time when there is none.
+func GetLastProfileTime(ctx context.Context, client *mongo.Client, db string) (time.Time, error) {
+	entry := ProfileEntry{}
+	err := client.Database(db).Collection("system.profile").FindOne(
+		ctx,
+		bsonx.Doc{},
+		options.FindOne().SetSort(bsonx.Doc{{Key: "ts", Value: bsonx.Int32(-1)}}),
+	).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return entry.Ts, nil
+}
+
+// GetProfileEntriesSince returns the system.profile documents written at or after the
+// given time, oldest first. The documents written at since are returned again, the
+// caller tells them apart with their Raw bytes.
+func GetProfileEntriesSince(ctx context.Context, client *mongo.Client, db string, since time.Time) ([]ProfileEntry, error) {
+	cursor, err := client.Database(db).Collection("system.profile").Find(
+		ctx,
+		bsonx.Doc{{Key: "ts", Value: bsonx.Document(bsonx.Doc{{Key: "$gte", Value: bsonx.Time(since)}})}},
+		options.Find().SetSort(bsonx.Doc{{Key: "ts", Value: bsonx.Int32(1)}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []ProfileEntry{}
+	for cursor.Next(ctx) {
+		entry := ProfileEntry{}
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, err
+		}
+		entry.Raw = append(bson.Raw{}, cursor.Current...)
+		entries = append(entries, entry)
+	}
+	return entries, cursor.Err()
+}
@@ -0,0 +1,69 @@
+package mongowrapper
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// ReplStatusStats are the replication stats reported by serverStatus.
+type ReplStatusStats struct {
+	SetName   string   `bson:"setName"`
+	IsMaster  bool     `bson:"ismaster"`
+	Secondary bool     `bson:"secondary"`
+	Primary   string   `bson:"primary"`
+	Me        string   `bson:"me"`
+	Hosts     []string `bson:"hosts"`
+	// SetVersion is the version of the replica set config.
+	SetVersion int64 `bson:"setVersion"`
+}
+
+// ReplSetOptime is the optime of a replica set member.
+type ReplSetOptime struct {
+	Ts primitive.Timestamp `bson:"ts"`
+}
+
+// ReplSetMember is a member returned by replSetGetStatus.
+type ReplSetMember struct {
+	ID         int           `bson:"_id"`
+	Name       string        `bson:"name"`
+	State      int           `bson:"state"`
+	StateStr   string        `bson:"stateStr"`
+	Optime     ReplSetOptime `bson:"optime"`
+	OptimeDate time.Time     `bson:"optimeDate"`
+	Self       bool          `bson:"self"`
+}
+
+// ReplSetStatus keeps the data returned by the replSetGetStatus command.
+type ReplSetStatus struct {
+	Set     string          `bson:"set"`
+	Date    time.Time       `bson:"date"`
+	MyState int             `bson:"myState"`
+	Members []ReplSetMember `bson:"members"`
+}
+
+// Primary returns the primary member of the replica set, or nil if there is none.
+func (rs *ReplSetStatus) Primary() *ReplSetMember {
+	for i := range rs.Members {
+		if rs.Members[i].StateStr == "PRIMARY" {
+			return &rs.Members[i]
+		}
+	}
+	return nil
+}
+
+// GetReplSetStatus returns the replica set status info.
+func GetReplSetStatus(ctx context.Context, client *mongo.Client) (*ReplSetStatus, error) {
+	status := &ReplSetStatus{}
+	result := client.Database("admin").RunCommand(
+		ctx,
+		bsonx.Doc{{Key: "replSetGetStatus", Value: bsonx.Int32(1)}},
+	)
+	if err := result.Decode(status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
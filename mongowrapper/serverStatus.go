@@ -10,6 +10,7 @@ import (
 
 // ServerStatus keeps the data returned by the serverStatus() method.
 type ServerStatusStats struct {
+	Host           string    `bson:"host"`
 	Version        string    `bson:"version"`
 	Uptime         float64   `bson:"uptime"`
 	UptimeEstimate float64   `bson:"uptimeEstimate"`
@@ -28,12 +29,14 @@ type ServerStatusStats struct {
 
 	// Locks LockStatsMap `bson:"locks,omitempty"`
 
-	Network *NetworkStats `bson:"network"`
-	// OpLatencies    *OpLatenciesStat     `bson:"opLatencies"`
-	Opcounters *OpcountersStats `bson:"opcounters"`
+	Network     *NetworkStats     `bson:"network"`
+	OpLatencies *OpLatenciesStats `bson:"opLatencies"`
+	Opcounters  *OpcountersStats  `bson:"opcounters"`
 	// OpcountersRepl *OpcountersReplStats `bson:"opcountersRepl"`
 	Metrics *MetricsStats `bson:"metrics"`
 
+	Repl *ReplStatusStats `bson:"repl"`
+
 	// StorageEngine *StorageEngineStats `bson:"storageEngine"`
 	// InMemory      *WiredTigerStats    `bson:"inMemory"`
 	// RocksDb       *RocksDbStats       `bson:"rocksdb"`
@@ -46,9 +49,9 @@ func GetServerStatus(ctx context.Context, client *mongo.Client) *ServerStatusSta
 	result := client.Database("admin").RunCommand(
 		ctx,
 		bsonx.Doc{
-			{"serverStatus", bsonx.Int32(1)},
-			{"recordStats", bsonx.Int32(0)},
-			{"opLatencies", bsonx.Document(bsonx.MDoc{"histograms": bsonx.Boolean(true)})},
+			{Key: "serverStatus", Value: bsonx.Int32(1)},
+			{Key: "recordStats", Value: bsonx.Int32(0)},
+			{Key: "opLatencies", Value: bsonx.Document(bsonx.MDoc{"histograms": bsonx.Boolean(true)})},
 		},
 	)
 	result.Decode(serverStatus)
@@ -0,0 +1,71 @@
+package mongowrapper
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// TopUsageStats is the time (microsecond) spent and the number of operations.
+type TopUsageStats struct {
+	Time  float64 `bson:"time"`
+	Count float64 `bson:"count"`
+}
+
+// TopNamespaceStats are the usage stats of a namespace reported by the top command.
+type TopNamespaceStats struct {
+	Total     TopUsageStats `bson:"total"`
+	ReadLock  TopUsageStats `bson:"readLock"`
+	WriteLock TopUsageStats `bson:"writeLock"`
+	Queries   TopUsageStats `bson:"queries"`
+	GetMore   TopUsageStats `bson:"getmore"`
+	Insert    TopUsageStats `bson:"insert"`
+	Update    TopUsageStats `bson:"update"`
+	Remove    TopUsageStats `bson:"remove"`
+	Commands  TopUsageStats `bson:"commands"`
+}
+
+// TopStats keeps the data returned by the top command.
+type TopStats struct {
+	Totals    map[string]TopNamespaceStats
+	LocalTime time.Time
+}
+
+// GetTopStats returns the usage stats of every namespace.
+func GetTopStats(ctx context.Context, client *mongo.Client) (*TopStats, error) {
+	var result struct {
+		Totals bson.Raw `bson:"totals"`
+	}
+	err := client.Database("admin").RunCommand(
+		ctx,
+		bsonx.Doc{{Key: "top", Value: bsonx.Int32(1)}},
+	).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := result.Totals.Elements()
+	if err != nil {
+		return nil, err
+	}
+	stats := &TopStats{
+		Totals:    map[string]TopNamespaceStats{},
+		LocalTime: time.Now(),
+	}
+	for _, element := range elements {
+		// totals also holds a "note" string next to the namespaces.
+		if element.Value().Type != bsontype.EmbeddedDocument {
+			continue
+		}
+		nsStats := TopNamespaceStats{}
+		if err := element.Value().Unmarshal(&nsStats); err != nil {
+			return nil, err
+		}
+		stats.Totals[element.Key()] = nsStats
+	}
+	return stats, nil
+}
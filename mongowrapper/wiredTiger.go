@@ -14,10 +14,19 @@ type WTTransactionStats struct {
 	RolledBack           float64 `bson:"transactions rolled back"`
 }
 
+// WTCacheStats stats
+type WTCacheStats struct {
+	CurrentBytes      float64 `bson:"bytes currently in the cache"`
+	MaxBytes          float64 `bson:"maximum bytes configured"`
+	TrackedDirtyBytes float64 `bson:"tracked dirty bytes in the cache"`
+	PagesReadInto     float64 `bson:"pages read into cache"`
+	PagesWrittenFrom  float64 `bson:"pages written from cache"`
+}
+
 // WiredTiger stats
 type WiredTigerStats struct {
 	// BlockManager           *WTBlockManagerStats           `bson:"block-manager"`
-	// Cache                  *WTCacheStats                  `bson:"cache"`
+	Cache *WTCacheStats `bson:"cache"`
 	// Log                    *WTLogStats                    `bson:"log"`
 	// Session                *WTSessionStats                `bson:"session"`
 	Transaction *WTTransactionStats `bson:"transaction"`
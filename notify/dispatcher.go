@@ -0,0 +1,261 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mongo-monitor/alert"
+)
+
+// Config configures the notifiers as written in the configuration file:
+//
+//	[notify]
+//	group_wait = "10s"
+//	min_interval = "5m"
+//
+//	[[notify.slack]]
+//	url = "https://hooks.slack.com/services/..."
+type Config struct {
+	// GroupWait is how long the alerts of a target are collected before they are
+	// sent together.
+	GroupWait string `mapstructure:"group_wait"`
+	// MinInterval is the shortest time between two notifications of a target, the
+	// alerts changing meanwhile are sent together once it is elapsed.
+	MinInterval string `mapstructure:"min_interval"`
+	// Retries is the number of times a failed notification is sent again.
+	Retries      int    `mapstructure:"retries"`
+	RetryBackoff string `mapstructure:"retry_backoff"`
+
+	Webhooks []WebhookConfig `mapstructure:"webhooks"`
+	Slack    []SlackConfig   `mapstructure:"slack"`
+	Email    []EmailConfig   `mapstructure:"email"`
+}
+
+// Notifiers returns the notifiers configured.
+func (c Config) Notifiers() ([]Notifier, error) {
+	notifiers := []Notifier{}
+	for _, config := range c.Webhooks {
+		n, err := NewWebhookNotifier(config)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	for _, config := range c.Slack {
+		n, err := NewSlackNotifier(config)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	for _, config := range c.Email {
+		n, err := NewEmailNotifier(config)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+// pendingGroup are the alerts of a target waiting to be sent.
+type pendingGroup struct {
+	alerts map[string]alert.Alert
+	timer  *time.Timer
+}
+
+// Dispatcher groups the alerts changing state by target and sends them to every
+// notifier, at most once per MinInterval and target.
+type Dispatcher struct {
+	notifiers    []Notifier
+	groupWait    time.Duration
+	minInterval  time.Duration
+	retries      int
+	retryBackoff time.Duration
+
+	pending  map[string]*pendingGroup
+	lastSent map[string]time.Time
+	// closed is set by Close, the alerts added afterwards are dropped.
+	closed bool
+	mutex  sync.Mutex
+	// wg counts the groups being sent, it is only added to with mutex held and
+	// before closed is set.
+	wg sync.WaitGroup
+
+	// OnError is called with the notifications that failed after every retry when it is not nil.
+	OnError func(notifier Notifier, n Notification, err error)
+}
+
+// NewDispatcher returns a dispatcher sending to the notifiers configured, nil is
+// returned when no notifier is configured.
+func NewDispatcher(config Config) (*Dispatcher, error) {
+	notifiers, err := config.Notifiers()
+	if err != nil || len(notifiers) == 0 {
+		return nil, err
+	}
+	d := &Dispatcher{
+		notifiers:    notifiers,
+		groupWait:    10 * time.Second,
+		minInterval:  time.Minute,
+		retries:      3,
+		retryBackoff: 2 * time.Second,
+		pending:      map[string]*pendingGroup{},
+		lastSent:     map[string]time.Time{},
+	}
+	durations := []struct {
+		value string
+		d     *time.Duration
+	}{
+		{config.GroupWait, &d.groupWait},
+		{config.MinInterval, &d.minInterval},
+		{config.RetryBackoff, &d.retryBackoff},
+	}
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+		if *duration.d, err = time.ParseDuration(duration.value); err != nil {
+			return nil, fmt.Errorf("notify: %v", err)
+		}
+	}
+	if config.Retries > 0 {
+		d.retries = config.Retries
+	}
+	return d, nil
+}
+
+// Notifiers returns the notifiers of the dispatcher.
+func (d *Dispatcher) Notifiers() []Notifier {
+	return d.notifiers
+}
+
+// Add queues an alert that changed state, only the firing and resolved alerts are
+// sent. The alerts added once the dispatcher is closed are dropped.
+func (d *Dispatcher) Add(a alert.Alert) {
+	if a.State != alert.StateFiring && a.State != alert.StateResolved {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return
+	}
+
+	group, ok := d.pending[a.Target]
+	if !ok {
+		group = &pendingGroup{alerts: map[string]alert.Alert{}}
+		d.pending[a.Target] = group
+		wait := d.groupWait
+		if next := d.lastSent[a.Target].Add(d.minInterval); time.Until(next) > wait {
+			wait = time.Until(next)
+		}
+		target := a.Target
+		group.timer = time.AfterFunc(wait, func() { d.flush(target) })
+	}
+	// A rule firing and resolving within the wait is sent in its last state.
+	group.alerts[a.Rule] = a
+}
+
+func (d *Dispatcher) flush(target string) {
+	d.mutex.Lock()
+	group := d.take(target)
+	d.mutex.Unlock()
+	if group != nil {
+		d.sendGroup(target, group)
+	}
+}
+
+// take removes the pending group of target and adds it to wg, nil is returned when
+// there is none. The mutex must be held.
+func (d *Dispatcher) take(target string) *pendingGroup {
+	group, ok := d.pending[target]
+	if !ok {
+		return nil
+	}
+	delete(d.pending, target)
+	d.lastSent[target] = time.Now()
+	d.wg.Add(1)
+	return group
+}
+
+// sendGroup sends the alerts of a group taken from the pending ones.
+func (d *Dispatcher) sendGroup(target string, group *pendingGroup) {
+	defer d.wg.Done()
+
+	n := Notification{Target: target, Time: time.Now()}
+	for _, a := range group.alerts {
+		n.Alerts = append(n.Alerts, a)
+	}
+	sort.Slice(n.Alerts, func(i, j int) bool {
+		return n.Alerts[i].Rule < n.Alerts[j].Rule
+	})
+	d.Send(context.Background(), n)
+}
+
+// Send sends a notification to every notifier, retrying the failed ones, and
+// returns the errors of the notifiers that failed after every retry.
+func (d *Dispatcher) Send(ctx context.Context, n Notification) map[Notifier]error {
+	errs := map[Notifier]error{}
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, notifier := range d.notifiers {
+		wg.Add(1)
+		go func(notifier Notifier) {
+			defer wg.Done()
+			if err := d.sendWithRetries(ctx, notifier, n); err != nil {
+				mutex.Lock()
+				errs[notifier] = err
+				mutex.Unlock()
+				if d.OnError != nil {
+					d.OnError(notifier, n, err)
+				}
+			}
+		}(notifier)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (d *Dispatcher) sendWithRetries(ctx context.Context, notifier Notifier, n Notification) error {
+	backoff := d.retryBackoff
+	var err error
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
+		err = notifier.Notify(attemptCtx, n)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Close sends the alerts waiting for their group or for the rate limit right away,
+// and waits for the notifications being sent. The alerts added afterwards are dropped.
+func (d *Dispatcher) Close() {
+	d.mutex.Lock()
+	groups := map[string]*pendingGroup{}
+	for target := range d.pending {
+		group := d.take(target)
+		// A timer already fired finds no group to flush.
+		group.timer.Stop()
+		groups[target] = group
+	}
+	d.closed = true
+	d.mutex.Unlock()
+	for target, group := range groups {
+		d.sendGroup(target, group)
+	}
+	d.wg.Wait()
+}
@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"mongo-monitor/alert"
+	"mongo-monitor/internal/httprecorder"
+)
+
+// notifications decodes the notifications server received.
+func notifications(t *testing.T, server *httprecorder.Server) []Notification {
+	_, bodies := server.Received()
+	ns := []Notification{}
+	for _, body := range bodies {
+		n := Notification{}
+		if err := json.Unmarshal(body, &n); err != nil {
+			t.Fatal(err)
+		}
+		ns = append(ns, n)
+	}
+	return ns
+}
+
+func newTestDispatcher(t *testing.T, url string, groupWait string) *Dispatcher {
+	d, err := NewDispatcher(Config{
+		GroupWait:    groupWait,
+		MinInterval:  "1h",
+		Retries:      2,
+		RetryBackoff: "1ms",
+		Webhooks:     []WebhookConfig{{URL: url}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func testAlert(target, rule string, state alert.State) alert.Alert {
+	return alert.Alert{
+		Rule:     rule,
+		Severity: "warning",
+		Target:   target,
+		State:    state,
+		Expr:     rule + " > 1",
+		Since:    time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC),
+		Values:   map[string]float64{rule: 2},
+	}
+}
+
+func TestDispatcherGroupsByTarget(t *testing.T) {
+	server := httprecorder.NewServer()
+	defer server.Close()
+	d := newTestDispatcher(t, server.URL, "20ms")
+
+	d.Add(testAlert("a:27017", "lag", alert.StateFiring))
+	d.Add(testAlert("a:27017", "cache", alert.StateFiring))
+	d.Add(testAlert("a:27017", "cache", alert.StateResolved))
+	d.Add(testAlert("a:27017", "conns", alert.StatePending))
+	d.Add(testAlert("b:27017", "lag", alert.StateFiring))
+	time.Sleep(100 * time.Millisecond)
+	d.Close()
+
+	notifications := notifications(t, server)
+	if len(notifications) != 2 {
+		t.Fatalf("webhook received %d notifications, want 2", len(notifications))
+	}
+	for _, n := range notifications {
+		if n.Target != "a:27017" {
+			continue
+		}
+		if len(n.Alerts) != 2 || n.Alerts[0].Rule != "cache" || n.Alerts[0].State != alert.StateResolved {
+			t.Errorf("notification of a:27017 = %+v, want cache resolved and lag firing", n.Alerts)
+		}
+	}
+}
+
+func TestDispatcherCloseSendsPending(t *testing.T) {
+	server := httprecorder.NewServer()
+	defer server.Close()
+	d := newTestDispatcher(t, server.URL, "1h")
+
+	d.Add(testAlert("a:27017", "lag", alert.StateFiring))
+	d.Close()
+	if n := notifications(t, server); len(n) != 1 {
+		t.Fatalf("webhook received %d notifications on close, want 1", len(n))
+	}
+
+	d.Add(testAlert("a:27017", "cache", alert.StateFiring))
+	d.Close()
+	if n := notifications(t, server); len(n) != 1 {
+		t.Errorf("webhook received %d notifications, want the alerts added after close dropped", len(n))
+	}
+}
+
+func TestDispatcherAddDuringClose(t *testing.T) {
+	server := httprecorder.NewServer()
+	defer server.Close()
+	d := newTestDispatcher(t, server.URL, "1ms")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				d.Add(testAlert(string(rune('a'+i))+":27017", "lag", alert.StateFiring))
+			}
+		}(i)
+	}
+	d.Close()
+	wg.Wait()
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	server := httprecorder.NewServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer server.Close()
+	d := newTestDispatcher(t, server.URL, "1h")
+
+	errs := d.Send(context.Background(), Notification{Target: "a:27017"})
+	if len(errs) != 0 {
+		t.Errorf("Send() = %v, want success on the last retry", errs)
+	}
+
+	failing := httprecorder.NewServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer failing.Close()
+	d = newTestDispatcher(t, failing.URL, "1h")
+	failed := 0
+	d.OnError = func(notifier Notifier, n Notification, err error) { failed++ }
+	if errs := d.Send(context.Background(), Notification{Target: "a:27017"}); len(errs) != 1 || failed != 1 {
+		t.Errorf("Send() = %v, want the error once the retries are exhausted", errs)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var body slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewSlackNotifier(SlackConfig{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := Notification{Target: "a:27017", Alerts: []alert.Alert{testAlert("a:27017", "lag", alert.StateFiring)}}
+	if err := s.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if body.Text != n.Title() || len(body.Attachments) != 1 {
+		t.Errorf("slack received %+v, want the title and an attachment per alert", body)
+	}
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// EmailConfig configures the SMTP server and the recipients as written in the
+// configuration file.
+type EmailConfig struct {
+	// Addr is the host:port of the SMTP server.
+	Addr     string   `mapstructure:"addr"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+}
+
+// EmailNotifier mails the notifications.
+type EmailNotifier struct {
+	config EmailConfig
+}
+
+// NewEmailNotifier returns a notifier mailing the recipients of config.
+func NewEmailNotifier(config EmailConfig) (*EmailNotifier, error) {
+	if config.Addr == "" || config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("email: addr, from and to are required")
+	}
+	return &EmailNotifier{config: config}, nil
+}
+
+func (e *EmailNotifier) Name() string {
+	return "email " + strings.Join(e.config.To, ",")
+}
+
+// Notify sends the notification as a plain text mail. The server is authenticated
+// with PLAIN when a username is configured, which requires TLS unless the server is
+// local.
+func (e *EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	host, _, err := net.SplitHostPort(e.config.Addr)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, host)
+	}
+
+	msg := bytes.Buffer{}
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Title())
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", strings.Replace(n.Text(), "\n", "\r\n", -1))
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", e.config.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// The SMTP client does not take a context, the connection is closed with ctx instead.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	err = e.send(conn, host, auth, msg.Bytes())
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// send sends a mail on conn like smtp.SendMail does.
+func (e *EmailNotifier) send(conn net.Conn, host string, auth smtp.Auth, msg []byte) error {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("email: %s does not support authentication", e.config.Addr)
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.config.From); err != nil {
+		return err
+	}
+	for _, to := range e.config.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"mongo-monitor/alert"
+)
+
+// fakeSMTP accepts a connection on a local port and answers the commands of a mail
+// with success, sending the session it saw on the channel once it is over.
+func fakeSMTP(t *testing.T) (string, <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := make(chan []string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		lines := []string{}
+		r := bufio.NewReader(conn)
+		reply := func(s string) { fmt.Fprintf(conn, "%s\r\n", s) }
+		reply("220 fake ESMTP")
+		for data := false; ; {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			if data {
+				if line == "." {
+					data = false
+					reply("250 queued")
+				}
+				continue
+			}
+			switch strings.ToUpper(strings.Fields(line + " x")[0]) {
+			case "EHLO":
+				reply("250 fake")
+			case "DATA":
+				data = true
+				reply("354 go ahead")
+			case "QUIT":
+				reply("221 bye")
+				session <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		session <- lines
+	}()
+	return l.Addr().String(), session
+}
+
+func TestEmailNotifier(t *testing.T) {
+	addr, session := fakeSMTP(t)
+	e, err := NewEmailNotifier(EmailConfig{
+		Addr: addr,
+		From: "monitor@example.com",
+		To:   []string{"dba@example.com", "ops@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := Notification{
+		Target: "a:27017",
+		Time:   time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC),
+		Alerts: []alert.Alert{testAlert("a:27017", "lag", alert.StateFiring)},
+	}
+	if err := e.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	text := strings.Join(<-session, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<monitor@example.com>",
+		"RCPT TO:<dba@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"Subject: " + n.Title(),
+		FormatAlert(n.Alerts[0]),
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("SMTP session lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestEmailNotifierTimeout(t *testing.T) {
+	// The server accepts the connection and never greets.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	closed := make(chan struct{})
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// The read ends once the notifier gives up and closes the connection.
+		conn.Read(make([]byte, 1))
+		close(closed)
+	}()
+
+	e, err := NewEmailNotifier(EmailConfig{Addr: l.Addr().String(), From: "a@example.com", To: []string{"b@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := e.Notify(ctx, Notification{Target: "a:27017"}); err != context.DeadlineExceeded {
+		t.Errorf("Notify() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Notify() took %v, want it to end with the context", elapsed)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("the connection is still open after Notify returned")
+	}
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mongo-monitor/alert"
+)
+
+// Notification groups the alerts of a target that changed state.
+type Notification struct {
+	Target string        `json:"target"`
+	Alerts []alert.Alert `json:"alerts"`
+	Time   time.Time     `json:"time"`
+}
+
+// Notifier sends notifications somewhere.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Firing returns the alerts of the notification that are firing.
+func (n Notification) Firing() []alert.Alert {
+	return n.withState(alert.StateFiring)
+}
+
+// Resolved returns the alerts of the notification that are resolved.
+func (n Notification) Resolved() []alert.Alert {
+	return n.withState(alert.StateResolved)
+}
+
+func (n Notification) withState(state alert.State) []alert.Alert {
+	alerts := []alert.Alert{}
+	for _, a := range n.Alerts {
+		if a.State == state {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
+
+// Title returns a one line summary of the notification.
+func (n Notification) Title() string {
+	firing := len(n.Firing())
+	resolved := len(n.Resolved())
+	switch {
+	case firing > 0 && resolved > 0:
+		return fmt.Sprintf("[mongo-monitor] %d firing, %d resolved on %s", firing, resolved, n.Target)
+	case firing > 0:
+		return fmt.Sprintf("[mongo-monitor] %d firing on %s", firing, n.Target)
+	default:
+		return fmt.Sprintf("[mongo-monitor] %d resolved on %s", resolved, n.Target)
+	}
+}
+
+// Text returns the notification as plain text, one alert per line.
+func (n Notification) Text() string {
+	lines := []string{}
+	for _, a := range n.Alerts {
+		lines = append(lines, FormatAlert(a))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatAlert returns a one line description of an alert.
+func FormatAlert(a alert.Alert) string {
+	names := make([]string, 0, len(a.Values))
+	for name := range a.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = fmt.Sprintf("%s=%.4g", name, a.Values[name])
+	}
+	return fmt.Sprintf(
+		"%s [%s] %s: %s since %s (%s)",
+		strings.ToUpper(string(a.State)),
+		a.Severity,
+		a.Rule,
+		a.Expr,
+		a.Since.Format(time.RFC3339),
+		strings.Join(values, " "),
+	)
+}
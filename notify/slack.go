@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"mongo-monitor/alert"
+)
+
+// SlackConfig configures a Slack incoming webhook as written in the configuration file.
+type SlackConfig struct {
+	URL      string `mapstructure:"url"`
+	Channel  string `mapstructure:"channel"`
+	Username string `mapstructure:"username"`
+}
+
+// SlackNotifier posts the notifications to a Slack compatible incoming webhook.
+type SlackNotifier struct {
+	config SlackConfig
+	client *http.Client
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color    string `json:"color"`
+	Title    string `json:"title"`
+	Text     string `json:"text"`
+	Fallback string `json:"fallback"`
+	Ts       int64  `json:"ts"`
+}
+
+// NewSlackNotifier returns a notifier posting to the incoming webhook of config.
+func NewSlackNotifier(config SlackConfig) (*SlackNotifier, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("slack: url is required")
+	}
+	return &SlackNotifier{config: config, client: &http.Client{Timeout: defaultTimeout}}, nil
+}
+
+func (s *SlackNotifier) Name() string {
+	return "slack " + s.config.Channel
+}
+
+// Notify posts the notification with an attachment per alert, colored by state.
+func (s *SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	message := slackMessage{
+		Channel:  s.config.Channel,
+		Username: s.config.Username,
+		Text:     n.Title(),
+	}
+	for _, a := range n.Alerts {
+		color := "warning"
+		switch {
+		case a.State == alert.StateResolved:
+			color = "good"
+		case a.Severity == "critical":
+			color = "danger"
+		}
+		message.Attachments = append(message.Attachments, slackAttachment{
+			Color:    color,
+			Title:    fmt.Sprintf("%s: %s", a.State, a.Rule),
+			Text:     FormatAlert(a),
+			Fallback: FormatAlert(a),
+			Ts:       a.Since.Unix(),
+		})
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.config.URL, "application/json", nil, body)
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// defaultTimeout is the timeout of the HTTP requests of the notifiers.
+const defaultTimeout = 10 * time.Second
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value, e.g. "text": {{json .Title}}.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// WebhookConfig configures a webhook as written in the configuration file.
+type WebhookConfig struct {
+	URL string `mapstructure:"url"`
+	// Template is a text/template of the body executed with the Notification, the
+	// notification is encoded as JSON when it is empty.
+	Template    string            `mapstructure:"template"`
+	ContentType string            `mapstructure:"content_type"`
+	Headers     map[string]string `mapstructure:"headers"`
+}
+
+// WebhookNotifier posts the notifications to a URL.
+type WebhookNotifier struct {
+	config   WebhookConfig
+	template *template.Template
+	client   *http.Client
+}
+
+// NewWebhookNotifier returns a notifier posting to the URL of config.
+func NewWebhookNotifier(config WebhookConfig) (*WebhookNotifier, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("webhook: url is required")
+	}
+	if config.ContentType == "" {
+		config.ContentType = "application/json"
+	}
+	w := &WebhookNotifier{config: config, client: &http.Client{Timeout: defaultTimeout}}
+	if config.Template != "" {
+		t, err := template.New("webhook").Funcs(templateFuncs).Parse(config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: %v", err)
+		}
+		w.template = t
+	}
+	return w, nil
+}
+
+func (w *WebhookNotifier) Name() string {
+	return "webhook " + w.config.URL
+}
+
+// Notify posts the notification rendered by the template.
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	var body []byte
+	if w.template == nil {
+		b, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		body = b
+	} else {
+		buf := bytes.Buffer{}
+		if err := w.template.Execute(&buf, n); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+	return post(ctx, w.client, w.config.URL, w.config.ContentType, w.config.Headers, body)
+}
+
+// post sends body to url and fails on the statuses other than 2xx.
+func post(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	contentType string,
+	headers map[string]string,
+	body []byte,
+) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s responded %s: %s", url, resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
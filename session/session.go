@@ -0,0 +1,69 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"mongo-monitor/mongowrapper"
+)
+
+// maxLineSize is the longest sample read, serverStatus is about a few hundred KB at most.
+const maxLineSize = 16 * 1024 * 1024
+
+// Sample is a serverStatus sample and the time it was fetched.
+type Sample struct {
+	Time   time.Time                       `json:"time"`
+	Status *mongowrapper.ServerStatusStats `json:"status"`
+}
+
+// Writer writes samples as newline delimited JSON.
+type Writer struct {
+	encoder *json.Encoder
+	mutex   sync.Mutex
+}
+
+// NewWriter returns a writer writing the samples to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{encoder: json.NewEncoder(w)}
+}
+
+// Write writes a sample fetched at t.
+func (w *Writer) Write(t time.Time, status *mongowrapper.ServerStatusStats) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.encoder.Encode(Sample{Time: t, Status: status})
+}
+
+// Reader reads the samples written by a Writer.
+type Reader struct {
+	scanner *bufio.Scanner
+}
+
+// NewReader returns a reader reading the samples from r.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next sample, io.EOF is returned after the last one.
+func (r *Reader) Next() (*Sample, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		sample := &Sample{}
+		if err := json.Unmarshal(line, sample); err != nil {
+			return nil, err
+		}
+		return sample, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,305 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures the sinks as written in the configuration file:
+//
+//	[sinks]
+//	flush_interval = "10s"
+//
+//	[[sinks.influxdb]]
+//	url = "http://localhost:8086/write?db=mongo"
+type Config struct {
+	// BatchSize is the most points written at once, a full batch is written without
+	// waiting for FlushInterval.
+	BatchSize     int    `mapstructure:"batch_size"`
+	FlushInterval string `mapstructure:"flush_interval"`
+	// MaxBuffer is the most points kept for a sink that is down, the oldest are
+	// dropped beyond.
+	MaxBuffer int `mapstructure:"max_buffer"`
+	// Retries is the number of times a failed batch is written again before it is
+	// kept for the next flush. The batches rejected by the sink are dropped instead.
+	Retries      int    `mapstructure:"retries"`
+	RetryBackoff string `mapstructure:"retry_backoff"`
+	// ExportScores writes the anomaly scores, the values suffixed with _score, too.
+	ExportScores bool `mapstructure:"export_scores"`
+
+	InfluxDB []InfluxConfig   `mapstructure:"influxdb"`
+	Graphite []GraphiteConfig `mapstructure:"graphite"`
+	Statsd   []StatsdConfig   `mapstructure:"statsd"`
+	OTLP     []OTLPConfig     `mapstructure:"otlp"`
+}
+
+// Sinks returns the sinks configured.
+func (c Config) Sinks() ([]Sink, error) {
+	sinks := []Sink{}
+	for _, config := range c.InfluxDB {
+		s, err := NewInfluxSink(config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	for _, config := range c.Graphite {
+		s, err := NewGraphiteSink(config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	for _, config := range c.Statsd {
+		s, err := NewStatsdSink(config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	for _, config := range c.OTLP {
+		s, err := NewOTLPSink(config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// buffer are the points waiting to be written to a sink.
+type buffer struct {
+	sink    Sink
+	points  []Point
+	dropped int
+	full    chan struct{}
+	mutex   sync.Mutex
+}
+
+// Dispatcher buffers the points and writes them in batches to every sink, each
+// sink having its own buffer so a sink that is down does not delay the others.
+type Dispatcher struct {
+	buffers       []*buffer
+	batchSize     int
+	flushInterval time.Duration
+	maxBuffer     int
+	retries       int
+	retryBackoff  time.Duration
+	exportScores  bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// OnError is called with the batches that failed after every retry when it is
+	// not nil, the points are kept for the next flush.
+	OnError func(sink Sink, points int, err error)
+	// OnReject is called with the batches the sink rejected when it is not nil, the
+	// points are dropped.
+	OnReject func(sink Sink, points int, err error)
+}
+
+// NewDispatcher returns a dispatcher writing to the sinks configured, nil is
+// returned when no sink is configured. Start must be called before points are added.
+func NewDispatcher(config Config) (*Dispatcher, error) {
+	sinks, err := config.Sinks()
+	if err != nil || len(sinks) == 0 {
+		return nil, err
+	}
+	d := &Dispatcher{
+		batchSize:     500,
+		flushInterval: 10 * time.Second,
+		maxBuffer:     10000,
+		retries:       3,
+		retryBackoff:  time.Second,
+		exportScores:  config.ExportScores,
+	}
+	durations := []struct {
+		value string
+		d     *time.Duration
+	}{
+		{config.FlushInterval, &d.flushInterval},
+		{config.RetryBackoff, &d.retryBackoff},
+	}
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+		if *duration.d, err = time.ParseDuration(duration.value); err != nil {
+			return nil, fmt.Errorf("sinks: %v", err)
+		}
+	}
+	if config.BatchSize > 0 {
+		d.batchSize = config.BatchSize
+	}
+	if config.MaxBuffer > 0 {
+		d.maxBuffer = config.MaxBuffer
+	}
+	if config.Retries > 0 {
+		d.retries = config.Retries
+	}
+	if d.maxBuffer < d.batchSize {
+		d.maxBuffer = d.batchSize
+	}
+	for _, s := range sinks {
+		d.buffers = append(d.buffers, &buffer{sink: s, full: make(chan struct{}, 1)})
+	}
+	return d, nil
+}
+
+// Sinks returns the sinks of the dispatcher.
+func (d *Dispatcher) Sinks() []Sink {
+	sinks := make([]Sink, len(d.buffers))
+	for i, b := range d.buffers {
+		sinks[i] = b.sink
+	}
+	return sinks
+}
+
+// Start writes the points to the sinks until Close is called.
+func (d *Dispatcher) Start() {
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for _, b := range d.buffers {
+		d.wg.Add(1)
+		go func(b *buffer) {
+			defer d.wg.Done()
+			d.run(b)
+		}(b)
+	}
+}
+
+// Add queues a point for every sink.
+func (d *Dispatcher) Add(p Point) {
+	if !d.exportScores {
+		p.Values = withoutScores(p.Values)
+	}
+	for _, b := range d.buffers {
+		b.mutex.Lock()
+		b.points = append(b.points, p)
+		if over := len(b.points) - d.maxBuffer; over > 0 {
+			b.points = b.points[over:]
+			b.dropped += over
+		}
+		full := len(b.points) >= d.batchSize
+		b.mutex.Unlock()
+		if full {
+			select {
+			case b.full <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// withoutScores returns the values that are not anomaly scores.
+func withoutScores(values map[string]float64) map[string]float64 {
+	kept := make(map[string]float64, len(values))
+	for name, value := range values {
+		if !strings.HasSuffix(name, "_score") {
+			kept[name] = value
+		}
+	}
+	return kept
+}
+
+// Dropped returns the number of points dropped for sink because its buffer was full.
+func (d *Dispatcher) Dropped(s Sink) int {
+	for _, b := range d.buffers {
+		if b.sink == s {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+			return b.dropped
+		}
+	}
+	return 0
+}
+
+func (d *Dispatcher) run(b *buffer) {
+	ticker := time.NewTicker(d.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.full:
+		}
+		d.flush(d.ctx, b, d.retries)
+	}
+}
+
+// flush writes the buffered points of b in batches, and stops at the first batch
+// failing after retries, which is kept with the points following it. A batch the
+// sink rejects is dropped.
+func (d *Dispatcher) flush(ctx context.Context, b *buffer, retries int) {
+	for {
+		b.mutex.Lock()
+		n := len(b.points)
+		if n > d.batchSize {
+			n = d.batchSize
+		}
+		batch := b.points[:n:n]
+		dropped := b.dropped
+		b.mutex.Unlock()
+		if n == 0 {
+			return
+		}
+		err := d.write(ctx, b.sink, batch, retries)
+		if err != nil && retryable(err) {
+			if d.OnError != nil {
+				d.OnError(b.sink, len(batch), err)
+			}
+			return
+		}
+		if err != nil && d.OnReject != nil {
+			d.OnReject(b.sink, len(batch), err)
+		}
+
+		// The points dropped while the batch was written were its first points.
+		b.mutex.Lock()
+		if written := n - (b.dropped - dropped); written > 0 {
+			b.points = b.points[written:]
+		}
+		b.mutex.Unlock()
+	}
+}
+
+func (d *Dispatcher) write(ctx context.Context, s Sink, batch []Point, retries int) error {
+	backoff := d.retryBackoff
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
+		err = s.Write(attemptCtx, batch)
+		cancel()
+		if err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// Close stops the dispatcher after writing the buffered points once more.
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.wg.Wait()
+	wg := sync.WaitGroup{}
+	for _, b := range d.buffers {
+		wg.Add(1)
+		go func(b *buffer) {
+			defer wg.Done()
+			d.flush(context.Background(), b, 0)
+		}(b)
+	}
+	wg.Wait()
+}
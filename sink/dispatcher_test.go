@@ -0,0 +1,158 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"mongo-monitor/internal/httprecorder"
+)
+
+// received returns the number of requests server received and the lines written.
+func received(server *httprecorder.Server) (int, []string) {
+	requests, bodies := server.Received()
+	lines := []string{}
+	for _, body := range bodies {
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return requests, lines
+}
+
+func newTestDispatcher(t *testing.T, url string, exportScores bool) *Dispatcher {
+	d, err := NewDispatcher(Config{
+		BatchSize:     2,
+		FlushInterval: "1h",
+		Retries:       2,
+		RetryBackoff:  "1ms",
+		ExportScores:  exportScores,
+		InfluxDB:      []InfluxConfig{{URL: url}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func testPoint(seconds int) Point {
+	return Point{
+		Target: "a:27017",
+		Time:   testTime.Add(time.Duration(seconds) * time.Second),
+		Values: map[string]float64{"query_per_second": float64(seconds), "query_per_second_score": 1.5},
+	}
+}
+
+func TestDispatcherRetriesServerErrors(t *testing.T) {
+	server := httprecorder.NewServer(http.StatusServiceUnavailable, http.StatusInternalServerError)
+	defer server.Close()
+	d := newTestDispatcher(t, server.URL, false)
+	failed := 0
+	d.OnError = func(s Sink, points int, err error) { failed++ }
+	d.Start()
+
+	d.Add(testPoint(1))
+	d.Add(testPoint(2))
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if _, lines := received(server); len(lines) > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	d.Close()
+
+	requests, lines := received(server)
+	if requests != 3 || len(lines) != 2 || failed != 0 {
+		t.Errorf("%d requests wrote %v with %d errors, want the batch written on the third request", requests, lines, failed)
+	}
+}
+
+func TestDispatcherKeepsFailedBatches(t *testing.T) {
+	server := httprecorder.NewServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer server.Close()
+	d := newTestDispatcher(t, server.URL, false)
+	errs := make(chan int, 1)
+	d.OnError = func(s Sink, points int, err error) { errs <- points }
+	d.Start()
+
+	d.Add(testPoint(1))
+	d.Add(testPoint(2))
+	select {
+	case points := <-errs:
+		if points != 2 {
+			t.Errorf("OnError got %d points, want 2", points)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError was not called once the retries were exhausted")
+	}
+	d.Close()
+
+	// Close writes the batch kept once more.
+	if _, lines := received(server); len(lines) != 2 {
+		t.Errorf("sink received %v after close, want the 2 points kept", lines)
+	}
+}
+
+func TestDispatcherDropsRejectedBatches(t *testing.T) {
+	server := httprecorder.NewServer(http.StatusBadRequest)
+	defer server.Close()
+	d := newTestDispatcher(t, server.URL, false)
+	rejected, failed := 0, 0
+	d.OnReject = func(s Sink, points int, err error) { rejected += points }
+	d.OnError = func(s Sink, points int, err error) { failed++ }
+	d.Start()
+
+	d.Add(testPoint(1))
+	d.Add(testPoint(2))
+	// The point following the batch is added once the batch is dropped, Close writes it.
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		b := d.buffers[0]
+		b.mutex.Lock()
+		waiting := len(b.points)
+		b.mutex.Unlock()
+		if waiting == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	d.Add(testPoint(3))
+	d.Close()
+
+	requests, lines := received(server)
+	if requests != 2 {
+		t.Errorf("sink received %d requests, want the rejected batch not retried", requests)
+	}
+	if rejected != 2 || failed != 0 {
+		t.Errorf("OnReject got %d points and OnError %d batches, want 2 points rejected", rejected, failed)
+	}
+	if len(lines) != 1 || !strings.Contains(lines[0], "query_per_second=3") {
+		t.Errorf("sink received %v, want the point following the rejected batch", lines)
+	}
+}
+
+func TestDispatcherScores(t *testing.T) {
+	for _, exportScores := range []bool{false, true} {
+		server := httprecorder.NewServer()
+		d := newTestDispatcher(t, server.URL, exportScores)
+		d.Start()
+		p := testPoint(1)
+		d.Add(p)
+		d.Close()
+		server.Close()
+
+		_, lines := received(server)
+		if len(lines) != 1 {
+			t.Fatalf("sink received %v, want one line", lines)
+		}
+		if exported := strings.Contains(lines[0], "_score"); exported != exportScores {
+			t.Errorf("export_scores %v wrote %q", exportScores, lines[0])
+		}
+		if len(p.Values) != 2 {
+			t.Errorf("Add() changed the values of the point to %v", p.Values)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// GraphiteConfig configures a Graphite server as written in the configuration file.
+type GraphiteConfig struct {
+	// Addr is the host:port of the plaintext protocol, usually 2003.
+	Addr   string `mapstructure:"addr"`
+	Prefix string `mapstructure:"prefix"`
+}
+
+// GraphiteSink sends the points in the Graphite plaintext protocol over TCP, as
+// prefix.target.metric value timestamp.
+type GraphiteSink struct {
+	config GraphiteConfig
+}
+
+// NewGraphiteSink returns a sink sending to the address of config.
+func NewGraphiteSink(config GraphiteConfig) (*GraphiteSink, error) {
+	if config.Addr == "" {
+		return nil, fmt.Errorf("graphite: addr is required")
+	}
+	if config.Prefix == "" {
+		config.Prefix = "mongo_monitor"
+	}
+	return &GraphiteSink{config: config}, nil
+}
+
+func (s *GraphiteSink) Name() string {
+	return "graphite " + s.config.Addr
+}
+
+// Write sends the points on a new connection, so a restarted server is reached again.
+func (s *GraphiteSink) Write(ctx context.Context, points []Point) error {
+	dialer := net.Dialer{Timeout: defaultTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	w := bufio.NewWriter(conn)
+	for _, p := range points {
+		timestamp := strconv.FormatInt(p.Time.Unix(), 10)
+		for _, name := range p.metricNames() {
+			fmt.Fprintf(
+				w,
+				"%s %s %s\n",
+				metricPath(s.config.Prefix, p.Target, name),
+				strconv.FormatFloat(p.Values[name], 'f', -1, 64),
+				timestamp,
+			)
+		}
+	}
+	return w.Flush()
+}
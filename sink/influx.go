@@ -0,0 +1,98 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// InfluxConfig configures an InfluxDB database as written in the configuration file.
+type InfluxConfig struct {
+	// URL is the write endpoint, like http://localhost:8086/write?db=mongo.
+	URL         string `mapstructure:"url"`
+	Measurement string `mapstructure:"measurement"`
+	Username    string `mapstructure:"username"`
+	Password    string `mapstructure:"password"`
+	// Token authenticates to InfluxDB 2, instead of the username and password.
+	Token string `mapstructure:"token"`
+}
+
+// InfluxSink posts the points in the InfluxDB line protocol.
+type InfluxSink struct {
+	config InfluxConfig
+	client *http.Client
+}
+
+// NewInfluxSink returns a sink writing to the URL of config.
+func NewInfluxSink(config InfluxConfig) (*InfluxSink, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("influxdb: url is required")
+	}
+	if config.Measurement == "" {
+		config.Measurement = "mongodb"
+	}
+	return &InfluxSink{config: config, client: &http.Client{Timeout: defaultTimeout}}, nil
+}
+
+func (s *InfluxSink) Name() string {
+	return "influxdb " + s.config.URL
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// appendLine appends a point as a line of the line protocol, with a nanosecond timestamp.
+func appendLine(buf *bytes.Buffer, measurement string, p Point) {
+	buf.WriteString(measurementEscaper.Replace(measurement))
+	buf.WriteString(",target=")
+	buf.WriteString(tagEscaper.Replace(p.Target))
+	for i, name := range p.metricNames() {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(tagEscaper.Replace(name))
+		buf.WriteByte('=')
+		buf.WriteString(strconv.FormatFloat(p.Values[name], 'g', -1, 64))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(p.Time.UnixNano(), 10))
+	buf.WriteByte('\n')
+}
+
+// Write posts the points in one request.
+func (s *InfluxSink) Write(ctx context.Context, points []Point) error {
+	buf := bytes.Buffer{}
+	for _, p := range points {
+		if len(p.Values) > 0 {
+			appendLine(&buf, s.config.Measurement, p)
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, &buf)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.config.Token)
+	} else if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(s.config.URL, resp)
+}
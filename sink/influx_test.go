@@ -0,0 +1,61 @@
+package sink
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func TestInfluxSinkWrite(t *testing.T) {
+	var body, contentType, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body, contentType, auth = string(b), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s, err := NewInfluxSink(InfluxConfig{URL: server.URL, Measurement: "mongo db", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Write(context.Background(), []Point{
+		{Target: "a:27017", Time: testTime, Values: map[string]float64{"query_per_second": 12.5, "insert_per_second": 3}},
+		{Target: "b,1 =x", Time: testTime.Add(time.Second), Values: map[string]float64{"uptime_seconds": 1e9}},
+		{Target: "c:27017", Time: testTime},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `mongo\ db,target=a:27017 insert_per_second=3,query_per_second=12.5 1614592800000000000` + "\n" +
+		`mongo\ db,target=b\,1\ \=x uptime_seconds=1e+09 1614592801000000000` + "\n"
+	if body != want {
+		t.Errorf("InfluxDB received\n%s\nwant\n%s", body, want)
+	}
+	if contentType != "text/plain; charset=utf-8" || auth != "Token secret" {
+		t.Errorf("InfluxDB received Content-Type %q and Authorization %q", contentType, auth)
+	}
+}
+
+func TestInfluxSinkStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "partial write: field type conflict", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	s, _ := NewInfluxSink(InfluxConfig{URL: server.URL})
+	err := s.Write(context.Background(), []Point{{Target: "a:27017", Time: testTime, Values: map[string]float64{"x": 1}}})
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.Code != http.StatusBadRequest || string(statusErr.Message) != "partial write: field type conflict" {
+		t.Errorf("Write() = %#v, want a StatusError 400", err)
+	}
+	if retryable(err) {
+		t.Error("a 400 is retryable, want it rejected")
+	}
+}
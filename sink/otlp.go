@@ -0,0 +1,227 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// otelMetric is how a value is exported, following the naming of the OpenTelemetry
+// semantic conventions.
+type otelMetric struct {
+	name        string
+	unit        string
+	description string
+	// attribute tells the values of a same metric apart, like the operation.
+	attributeKey   string
+	attributeValue string
+	// scale converts the value to the unit, like a percentage to a ratio.
+	scale float64
+}
+
+var otelMetrics = map[string]otelMetric{
+	"insert_per_second":            {"mongodb.operation.rate", "{operations}/s", "The rate of operations executed.", "operation", "insert", 1},
+	"query_per_second":             {"mongodb.operation.rate", "{operations}/s", "The rate of operations executed.", "operation", "query", 1},
+	"update_per_second":            {"mongodb.operation.rate", "{operations}/s", "The rate of operations executed.", "operation", "update", 1},
+	"delete_per_second":            {"mongodb.operation.rate", "{operations}/s", "The rate of operations executed.", "operation", "delete", 1},
+	"getmore_per_second":           {"mongodb.operation.rate", "{operations}/s", "The rate of operations executed.", "operation", "getmore", 1},
+	"command_per_second":           {"mongodb.operation.rate", "{operations}/s", "The rate of operations executed.", "operation", "command", 1},
+	"network_in_bytes_per_second":  {"mongodb.network.io.rate", "By/s", "The rate of bytes received and transmitted.", "direction", "receive", 1},
+	"network_out_bytes_per_second": {"mongodb.network.io.rate", "By/s", "The rate of bytes received and transmitted.", "direction", "transmit", 1},
+	"checkpoint_per_second":        {"mongodb.wiredtiger.checkpoint.rate", "{checkpoints}/s", "The rate of WiredTiger checkpoints.", "", "", 1},
+	"connections_current":          {"mongodb.connection.count", "{connections}", "The number of connections.", "type", "current", 1},
+	"connections_available":        {"mongodb.connection.count", "{connections}", "The number of connections.", "type", "available", 1},
+	"connections_used_pct":         {"mongodb.connection.utilization", "1", "The ratio of the connections in use.", "", "", 0.01},
+	"wt_cache_bytes":               {"mongodb.wiredtiger.cache.usage", "By", "The bytes in the WiredTiger cache.", "state", "used", 1},
+	"wt_cache_dirty_bytes":         {"mongodb.wiredtiger.cache.usage", "By", "The bytes in the WiredTiger cache.", "state", "dirty", 1},
+	"wt_cache_max_bytes":           {"mongodb.wiredtiger.cache.limit", "By", "The size of the WiredTiger cache.", "", "", 1},
+	"wt_cache_used_pct":            {"mongodb.wiredtiger.cache.utilization", "1", "The ratio of the WiredTiger cache used.", "state", "used", 0.01},
+	"wt_cache_dirty_pct":           {"mongodb.wiredtiger.cache.utilization", "1", "The ratio of the WiredTiger cache used.", "state", "dirty", 0.01},
+	"uptime_seconds":               {"mongodb.uptime", "s", "The time since mongod started.", "", "", 1},
+}
+
+// otelMetricOf returns how the value name is exported, the anomaly scores and the
+// values without a conventional name are exported under mongo_monitor.
+func otelMetricOf(name string) otelMetric {
+	if m, ok := otelMetrics[name]; ok {
+		return m
+	}
+	if strings.HasSuffix(name, "_score") {
+		return otelMetric{
+			"mongo_monitor.anomaly.score", "1", "The number of deviations a metric is away from its baseline.",
+			"metric", strings.TrimSuffix(name, "_score"), 1,
+		}
+	}
+	return otelMetric{name: "mongo_monitor." + name, unit: "1", scale: 1}
+}
+
+// OTLPConfig configures an OpenTelemetry collector as written in the configuration file.
+type OTLPConfig struct {
+	// URL is the OTLP/HTTP metrics endpoint, http://localhost:4318/v1/metrics by default.
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// OTLPSink posts the points as OTLP/HTTP protobuf metrics, a resource per target.
+type OTLPSink struct {
+	config OTLPConfig
+	client *http.Client
+}
+
+// NewOTLPSink returns a sink posting to the URL of config.
+func NewOTLPSink(config OTLPConfig) (*OTLPSink, error) {
+	if config.URL == "" {
+		config.URL = "http://localhost:4318/v1/metrics"
+	}
+	return &OTLPSink{config: config, client: &http.Client{Timeout: defaultTimeout}}, nil
+}
+
+func (s *OTLPSink) Name() string {
+	return "otlp " + s.config.URL
+}
+
+// Write posts the points in one ExportMetricsServiceRequest.
+func (s *OTLPSink) Write(ctx context.Context, points []Point) error {
+	body := encodeMetricsRequest(points)
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(s.config.URL, resp)
+}
+
+// encodeMetricsRequest encodes an ExportMetricsServiceRequest of
+// opentelemetry/proto/collector/metrics/v1, grouping the points by target.
+func encodeMetricsRequest(points []Point) []byte {
+	targets := []string{}
+	byTarget := map[string][]Point{}
+	for _, p := range points {
+		if _, ok := byTarget[p.Target]; !ok {
+			targets = append(targets, p.Target)
+		}
+		byTarget[p.Target] = append(byTarget[p.Target], p)
+	}
+
+	request := &protoBuffer{}
+	for _, target := range targets {
+		request.messageField(1, encodeResourceMetrics(byTarget[target]))
+	}
+	return request.b
+}
+
+// encodeResourceMetrics encodes the ResourceMetrics of the points of a target, the
+// resource being described by the last points knowing the version and replica set.
+func encodeResourceMetrics(points []Point) *protoBuffer {
+	described := Point{Target: points[0].Target}
+	for _, p := range points {
+		if p.Version != "" {
+			described.Version = p.Version
+		}
+		if p.ReplicaSet != "" {
+			described.ReplicaSet = p.ReplicaSet
+		}
+	}
+	resource := &protoBuffer{}
+	for _, attribute := range resourceAttributes(described) {
+		resource.messageField(1, attribute)
+	}
+
+	scope := &protoBuffer{}
+	scope.stringField(1, "mongo-monitor")
+
+	scopeMetrics := &protoBuffer{}
+	scopeMetrics.messageField(1, scope)
+	names := []string{}
+	dataPoints := map[string]*protoBuffer{}
+	metrics := map[string]otelMetric{}
+	for _, p := range points {
+		for _, name := range p.metricNames() {
+			m := otelMetricOf(name)
+			gauge, ok := dataPoints[m.name]
+			if !ok {
+				gauge = &protoBuffer{}
+				dataPoints[m.name] = gauge
+				metrics[m.name] = m
+				names = append(names, m.name)
+			}
+			dataPoint := &protoBuffer{}
+			dataPoint.fixed64Field(3, uint64(p.Time.UnixNano()))
+			dataPoint.doubleField(4, p.Values[name]*m.scale)
+			if m.attributeKey != "" {
+				dataPoint.messageField(7, encodeKeyValue(m.attributeKey, m.attributeValue))
+			}
+			gauge.messageField(1, dataPoint)
+		}
+	}
+	for _, name := range names {
+		m := metrics[name]
+		metric := &protoBuffer{}
+		metric.stringField(1, m.name)
+		metric.stringField(2, m.description)
+		metric.stringField(3, m.unit)
+		metric.messageField(5, dataPoints[name])
+		scopeMetrics.messageField(2, metric)
+	}
+
+	resourceMetrics := &protoBuffer{}
+	resourceMetrics.messageField(1, resource)
+	resourceMetrics.messageField(2, scopeMetrics)
+	return resourceMetrics
+}
+
+// resourceAttributes returns the attributes describing the target of p.
+func resourceAttributes(p Point) []*protoBuffer {
+	host, port, err := net.SplitHostPort(p.Target)
+	if err != nil {
+		host = p.Target
+	}
+	attributes := []*protoBuffer{
+		encodeKeyValue("db.system", "mongodb"),
+		encodeKeyValue("host.name", host),
+		encodeKeyValue("server.address", host),
+	}
+	if n, err := strconv.ParseInt(port, 10, 64); err == nil {
+		attributes = append(attributes, encodeIntKeyValue("server.port", n))
+	}
+	if p.ReplicaSet != "" {
+		attributes = append(attributes, encodeKeyValue("mongodb.replica_set.name", p.ReplicaSet))
+	}
+	if p.Version != "" {
+		attributes = append(attributes, encodeKeyValue("mongodb.version", p.Version))
+	}
+	return attributes
+}
+
+// encodeKeyValue encodes a KeyValue with a string AnyValue.
+func encodeKeyValue(key string, value string) *protoBuffer {
+	anyValue := &protoBuffer{}
+	anyValue.stringField(1, value)
+	kv := &protoBuffer{}
+	kv.stringField(1, key)
+	kv.messageField(2, anyValue)
+	return kv
+}
+
+// encodeIntKeyValue encodes a KeyValue with an int AnyValue.
+func encodeIntKeyValue(key string, value int64) *protoBuffer {
+	anyValue := &protoBuffer{}
+	anyValue.tag(3, wireVarint)
+	anyValue.varint(uint64(value))
+	kv := &protoBuffer{}
+	kv.stringField(1, key)
+	kv.messageField(2, anyValue)
+	return kv
+}
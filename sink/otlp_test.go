@@ -0,0 +1,158 @@
+package sink
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// protoFields decodes the fields of a protobuf message by field number, the varints
+// and fixed64 as numbers and the length delimited fields as bytes.
+func protoFields(t *testing.T, b []byte) map[int][]interface{} {
+	fields := map[int][]interface{}{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("invalid tag in %x", b)
+		}
+		b = b[n:]
+		field := int(key >> 3)
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("invalid varint in %x", b)
+			}
+			fields[field] = append(fields[field], v)
+			b = b[n:]
+		case wireFixed64:
+			fields[field] = append(fields[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || int(length) > len(b[n:]) {
+				t.Fatalf("invalid length in %x", b)
+			}
+			fields[field] = append(fields[field], b[n:n+int(length)])
+			b = b[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func protoMessage(t *testing.T, fields map[int][]interface{}, field int) map[int][]interface{} {
+	if len(fields[field]) != 1 {
+		t.Fatalf("field %d has %d values, want 1", field, len(fields[field]))
+	}
+	return protoFields(t, fields[field][0].([]byte))
+}
+
+func protoString(fields map[int][]interface{}, field int) string {
+	if len(fields[field]) == 0 {
+		return ""
+	}
+	return string(fields[field][0].([]byte))
+}
+
+func TestOTLPSinkWrite(t *testing.T) {
+	var body []byte
+	var contentType, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		contentType, auth = r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	s, err := NewOTLPSink(OTLPConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Write(context.Background(), []Point{{
+		Target:     "a:27017",
+		Time:       testTime,
+		Version:    "4.0.0",
+		ReplicaSet: "rs0",
+		Values: map[string]float64{
+			"query_per_second":  12.5,
+			"insert_per_second": 3,
+			"wt_cache_used_pct": 80,
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/x-protobuf" || auth != "Bearer secret" {
+		t.Errorf("collector received Content-Type %q and Authorization %q", contentType, auth)
+	}
+
+	request := protoFields(t, body)
+	if len(request[1]) != 1 {
+		t.Fatalf("request has %d ResourceMetrics, want 1", len(request[1]))
+	}
+	resourceMetrics := protoMessage(t, request, 1)
+
+	attributes := map[string]interface{}{}
+	for _, kv := range protoMessage(t, resourceMetrics, 1)[1] {
+		fields := protoFields(t, kv.([]byte))
+		value := protoMessage(t, fields, 2)
+		if len(value[1]) > 0 {
+			attributes[protoString(fields, 1)] = protoString(value, 1)
+		} else {
+			attributes[protoString(fields, 1)] = value[3][0]
+		}
+	}
+	wantAttributes := map[string]interface{}{
+		"db.system":                "mongodb",
+		"host.name":                "a",
+		"server.address":           "a",
+		"server.port":              uint64(27017),
+		"mongodb.replica_set.name": "rs0",
+		"mongodb.version":          "4.0.0",
+	}
+	for key, want := range wantAttributes {
+		if attributes[key] != want {
+			t.Errorf("resource attribute %s = %v, want %v", key, attributes[key], want)
+		}
+	}
+
+	scopeMetrics := protoMessage(t, resourceMetrics, 2)
+	if scope := protoMessage(t, scopeMetrics, 1); protoString(scope, 1) != "mongo-monitor" {
+		t.Errorf("scope name = %q, want mongo-monitor", protoString(scope, 1))
+	}
+	// The values by metric name and operation or state attribute.
+	values := map[string]float64{}
+	for _, m := range scopeMetrics[2] {
+		metric := protoFields(t, m.([]byte))
+		name := protoString(metric, 1)
+		for _, dp := range protoMessage(t, metric, 5)[1] {
+			dataPoint := protoFields(t, dp.([]byte))
+			if dataPoint[3][0].(uint64) != uint64(testTime.UnixNano()) {
+				t.Errorf("%s time = %v, want %v", name, dataPoint[3][0], testTime.UnixNano())
+			}
+			attribute := ""
+			if len(dataPoint[7]) > 0 {
+				attribute = protoString(protoMessage(t, protoFields(t, dataPoint[7][0].([]byte)), 2), 1)
+			}
+			values[name+"/"+attribute] = math.Float64frombits(dataPoint[4][0].(uint64))
+		}
+	}
+	wantValues := map[string]float64{
+		"mongodb.operation.rate/insert":             3,
+		"mongodb.operation.rate/query":              12.5,
+		"mongodb.wiredtiger.cache.utilization/used": 0.8,
+	}
+	if len(values) != len(wantValues) {
+		t.Errorf("collector received %v, want %v", values, wantValues)
+	}
+	for name, want := range wantValues {
+		if values[name] != want {
+			t.Errorf("%s = %v, want %v", name, values[name], want)
+		}
+	}
+}
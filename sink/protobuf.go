@@ -0,0 +1,59 @@
+package sink
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The protobuf wire types used by the OTLP messages.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// protoBuffer encodes a protobuf message, fields are appended in any order.
+type protoBuffer struct {
+	b []byte
+}
+
+func (p *protoBuffer) tag(field int, wireType int) {
+	p.varint(uint64(field<<3 | wireType))
+}
+
+func (p *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		p.b = append(p.b, byte(v)|0x80)
+		v >>= 7
+	}
+	p.b = append(p.b, byte(v))
+}
+
+func (p *protoBuffer) bytesField(field int, b []byte) {
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(b)))
+	p.b = append(p.b, b...)
+}
+
+// stringField appends a string field, the empty strings are the default and omitted.
+func (p *protoBuffer) stringField(field int, s string) {
+	if s != "" {
+		p.bytesField(field, []byte(s))
+	}
+}
+
+// messageField appends the message encoded by m, even when it is empty.
+func (p *protoBuffer) messageField(field int, m *protoBuffer) {
+	p.bytesField(field, m.b)
+}
+
+func (p *protoBuffer) fixed64Field(field int, v uint64) {
+	p.tag(field, wireFixed64)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	p.b = append(p.b, b[:]...)
+}
+
+func (p *protoBuffer) doubleField(field int, v float64) {
+	p.fixed64Field(field, math.Float64bits(v))
+}
@@ -0,0 +1,88 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTimeout is the timeout of a write to a sink.
+const defaultTimeout = 10 * time.Second
+
+// Point is the value of the metrics of a target at a time.
+type Point struct {
+	Target string
+	Time   time.Time
+	// Version and ReplicaSet describe the target, they may be empty.
+	Version    string
+	ReplicaSet string
+	Values     map[string]float64
+}
+
+// Sink writes points to a time series database.
+type Sink interface {
+	Name() string
+	// Write writes a batch of points, a batch failing is written again as a whole.
+	Write(ctx context.Context, points []Point) error
+}
+
+// StatusError is the error of a write the sink responded to with a status other
+// than 2xx.
+type StatusError struct {
+	URL     string
+	Status  string
+	Code    int
+	Message []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded %s: %s", e.URL, e.Status, e.Message)
+}
+
+// checkResponse returns a StatusError when resp is not 2xx.
+func checkResponse(url string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	return &StatusError{URL: url, Status: resp.Status, Code: resp.StatusCode, Message: bytes.TrimSpace(message)}
+}
+
+// retryable reports whether writing a batch again may succeed: the network errors and
+// the statuses 5xx, 408 and 429 are retryable, the sink rejecting the batch with
+// another 4xx would reject it again.
+func retryable(err error) bool {
+	e, ok := err.(*StatusError)
+	if !ok {
+		return true
+	}
+	return e.Code >= 500 || e.Code == http.StatusRequestTimeout || e.Code == http.StatusTooManyRequests
+}
+
+// metricNames returns the names of the values of p, sorted so the output is stable.
+func (p Point) metricNames() []string {
+	names := make([]string, 0, len(p.Values))
+	for name := range p.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pathReplacer turns a target into a single component of a dotted metric path.
+var pathReplacer = strings.NewReplacer(".", "_", ":", "_", " ", "_", "/", "_")
+
+// metricPath returns prefix.target.metric with target as one path component.
+func metricPath(prefix string, target string, metric string) string {
+	parts := []string{}
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	return strings.Join(append(parts, pathReplacer.Replace(target), metric), ".")
+}
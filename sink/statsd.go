@@ -0,0 +1,81 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// maxPacketSize keeps the StatsD packets below the usual MTU.
+const maxPacketSize = 1432
+
+// StatsdConfig configures a StatsD server as written in the configuration file.
+type StatsdConfig struct {
+	// Addr is the host:port of the server, usually 8125.
+	Addr   string `mapstructure:"addr"`
+	Prefix string `mapstructure:"prefix"`
+}
+
+// StatsdSink sends the values as gauges over UDP, as prefix.target.metric:value|g.
+// StatsD timestamps the values itself, the time of the points is lost.
+type StatsdSink struct {
+	config StatsdConfig
+}
+
+// NewStatsdSink returns a sink sending to the address of config.
+func NewStatsdSink(config StatsdConfig) (*StatsdSink, error) {
+	if config.Addr == "" {
+		return nil, fmt.Errorf("statsd: addr is required")
+	}
+	if config.Prefix == "" {
+		config.Prefix = "mongo_monitor"
+	}
+	return &StatsdSink{config: config}, nil
+}
+
+func (s *StatsdSink) Name() string {
+	return "statsd " + s.config.Addr
+}
+
+// Write sends the values in as few packets as possible.
+func (s *StatsdSink) Write(ctx context.Context, points []Point) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "udp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	packet := bytes.Buffer{}
+	send := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, p := range points {
+		for _, name := range p.metricNames() {
+			path := metricPath(s.config.Prefix, p.Target, name)
+			value := p.Values[name]
+			line := path + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|g"
+			if value < 0 {
+				// A signed gauge is a change of the gauge, it is set to zero first.
+				line = path + ":0|g\n" + line
+			}
+			if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacketSize {
+				if err := send(); err != nil {
+					return err
+				}
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		}
+	}
+	return send()
+}
@@ -2,12 +2,42 @@ package storage
 
 import (
 	metrichelper "mongo-monitor/metric_helper"
+	"time"
 )
 
 type Storage interface {
 	FetchLastMetrics() (metrichelper.Metrics, error)
 	FetchLastFewMetricsSlice(count int) (metrichelper.MetricsSlice, error)
 	RecordMetrics(metrichelper.Metrics) error
+	FetchTargets() ([]string, error)
+	FetchLastHostMetrics(host string) (metrichelper.Metrics, error)
+	FetchLastFewHostMetricsSlice(host string, count int) (metrichelper.MetricsSlice, error)
+	FetchMetricsSlice(host string, from time.Time, to time.Time) (metrichelper.MetricsSlice, error)
+	FetchMetricsRollup(host string, from time.Time, to time.Time, step time.Duration) (metrichelper.MetricsSlice, error)
+	FetchLastOplogMetrics(host string) (metrichelper.OplogMetrics, error)
+	RecordOplogMetrics(metrichelper.OplogMetrics) error
+	FetchLastSizeMetrics(namespace string) (metrichelper.SizeMetrics, error)
+	FetchLastSizeMetricsSlice() (metrichelper.SizeMetricsSlice, error)
+	RecordSizeMetrics(metrichelper.SizeMetrics) error
+	FetchLastIndexMetrics(id string) (metrichelper.IndexMetrics, error)
+	FetchLastIndexMetricsSlice() (metrichelper.IndexMetricsSlice, error)
+	RecordIndexMetrics(metrichelper.IndexMetrics) error
+	FetchLastTopMetrics() (metrichelper.NamespaceMetricsSlice, error)
+	RecordTopMetrics(metrichelper.NamespaceMetricsSlice) error
+	FetchLastTopology(host string) (metrichelper.Topology, error)
+	RecordTopology(metrichelper.Topology) error
+	FetchEvents(host string, from time.Time, to time.Time) (metrichelper.EventsSlice, error)
+	RecordEvent(metrichelper.Event) error
+	SetRetention(Retention)
+}
+
+// Retention bounds the metrics kept by RecordMetrics, the oldest are dropped. The
+// zero values keep every metric.
+type Retention struct {
+	// Age is how long before the last metrics the metrics are kept.
+	Age time.Duration
+	// Count is the most metrics kept, of every host together.
+	Count int
 }
 
 type Driver int
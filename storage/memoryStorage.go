@@ -2,18 +2,64 @@ package storage
 
 import (
 	metrichelper "mongo-monitor/metric_helper"
+	"sort"
 	"sync"
+	"time"
 )
 
-type MemoryStorage struct{}
+// MemoryStorage keeps the records in memory, every instance has its own records.
+type MemoryStorage struct {
+	recordsWM         recordsWithMutex
+	oplogRecordsWM    oplogRecordsWithMutex
+	sizeRecordsWM     sizeRecordsWithMutex
+	indexRecordsWM    indexRecordsWithMutex
+	topRecordsWM      topRecordsWithMutex
+	topologyRecordsWM topologyRecordsWithMutex
+	eventRecordsWM    eventRecordsWithMutex
+}
 
 type recordsWithMutex struct {
-	records []metrichelper.Metrics
+	// records are ordered by end time.
+	records   []metrichelper.Metrics
+	hosts     []string
+	retention Retention
+	mutex     sync.Mutex
+}
+
+type oplogRecordsWithMutex struct {
+	records map[string][]metrichelper.OplogMetrics
 	mutex   sync.Mutex
 }
 
-var recordsWM = recordsWithMutex{
-	records: []metrichelper.Metrics{},
+// maxSizeRecords is the most size metrics kept per namespace, the oldest are dropped.
+const maxSizeRecords = 1000
+
+type sizeRecordsWithMutex struct {
+	records    map[string][]metrichelper.SizeMetrics
+	namespaces []string
+	mutex      sync.Mutex
+}
+
+type indexRecordsWithMutex struct {
+	records map[string][]metrichelper.IndexMetrics
+	ids     []string
+	mutex   sync.Mutex
+}
+
+type topRecordsWithMutex struct {
+	// last is the last top sample, only the collections hot now are shown.
+	last  metrichelper.NamespaceMetricsSlice
+	mutex sync.Mutex
+}
+
+type topologyRecordsWithMutex struct {
+	records map[string]metrichelper.Topology
+	mutex   sync.Mutex
+}
+
+type eventRecordsWithMutex struct {
+	records metrichelper.EventsSlice
+	mutex   sync.Mutex
 }
 
 type DataNotFound struct{}
@@ -23,9 +69,9 @@ func (e *DataNotFound) Error() string {
 }
 
 func (storage *MemoryStorage) FetchLastMetrics() (metrichelper.Metrics, error) {
-	recordsWM.mutex.Lock()
-	records := recordsWM.records
-	recordsWM.mutex.Unlock()
+	storage.recordsWM.mutex.Lock()
+	records := storage.recordsWM.records
+	storage.recordsWM.mutex.Unlock()
 	if len(records) < 1 {
 		return metrichelper.Metrics{}, &DataNotFound{}
 	}
@@ -33,9 +79,9 @@ func (storage *MemoryStorage) FetchLastMetrics() (metrichelper.Metrics, error) {
 }
 
 func (storage *MemoryStorage) FetchLastFewMetricsSlice(count int) (metrichelper.MetricsSlice, error) {
-	recordsWM.mutex.Lock()
-	records := recordsWM.records
-	recordsWM.mutex.Unlock()
+	storage.recordsWM.mutex.Lock()
+	records := storage.recordsWM.records
+	storage.recordsWM.mutex.Unlock()
 	if len(records) < 1 {
 		return metrichelper.MetricsSlice{}, &DataNotFound{}
 	}
@@ -43,13 +89,337 @@ func (storage *MemoryStorage) FetchLastFewMetricsSlice(count int) (metrichelper.
 	return records[start:], nil
 }
 
+// RecordMetrics records metrics ordered by end time, and drops the metrics beyond the
+// retention.
 func (storage *MemoryStorage) RecordMetrics(metrics metrichelper.Metrics) error {
-	recordsWM.mutex.Lock()
-	recordsWM.records = append(recordsWM.records, metrics)
-	recordsWM.mutex.Unlock()
+	storage.recordsWM.mutex.Lock()
+	defer storage.recordsWM.mutex.Unlock()
+	if !containsString(storage.recordsWM.hosts, metrics.Host) {
+		storage.recordsWM.hosts = append(storage.recordsWM.hosts, metrics.Host)
+	}
+	records := storage.recordsWM.records
+	if n := len(records); n == 0 || !records[n-1].EndTime.After(metrics.EndTime) {
+		records = append(records, metrics)
+	} else {
+		// The metrics of another host may end a bit earlier. The fetched slices share
+		// the records, so they are inserted in a copy.
+		i := sort.Search(n, func(i int) bool {
+			return records[i].EndTime.After(metrics.EndTime)
+		})
+		sorted := make([]metrichelper.Metrics, 0, n+1)
+		sorted = append(sorted, records[:i]...)
+		sorted = append(sorted, metrics)
+		records = append(sorted, records[i:]...)
+	}
+	storage.recordsWM.records = storage.recordsWM.retention.trim(records)
+	return nil
+}
+
+// SetRetention sets the retention of the metrics recorded from now on.
+func (storage *MemoryStorage) SetRetention(retention Retention) {
+	storage.recordsWM.mutex.Lock()
+	storage.recordsWM.retention = retention
+	storage.recordsWM.mutex.Unlock()
+}
+
+// trim returns records without the records beyond r, records are ordered by end time.
+func (r Retention) trim(records []metrichelper.Metrics) []metrichelper.Metrics {
+	if r.Count > 0 && len(records) > r.Count {
+		records = records[len(records)-r.Count:]
+	}
+	if r.Age > 0 && len(records) > 0 {
+		oldest := records[len(records)-1].EndTime.Add(-r.Age)
+		records = records[sort.Search(len(records), func(i int) bool {
+			return !records[i].EndTime.Before(oldest)
+		}):]
+	}
+	return records
+}
+
+// FetchTargets returns the hosts having metrics, in the order they were first recorded.
+func (storage *MemoryStorage) FetchTargets() ([]string, error) {
+	storage.recordsWM.mutex.Lock()
+	hosts := storage.recordsWM.hosts
+	storage.recordsWM.mutex.Unlock()
+	if len(hosts) < 1 {
+		return []string{}, &DataNotFound{}
+	}
+	return hosts, nil
+}
+
+// FetchLastHostMetrics returns the last metrics of host.
+func (storage *MemoryStorage) FetchLastHostMetrics(host string) (metrichelper.Metrics, error) {
+	storage.recordsWM.mutex.Lock()
+	records := storage.recordsWM.records
+	storage.recordsWM.mutex.Unlock()
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Host == host {
+			return records[i], nil
+		}
+	}
+	return metrichelper.Metrics{}, &DataNotFound{}
+}
+
+// FetchLastFewHostMetricsSlice returns the last count metrics of host, oldest first.
+func (storage *MemoryStorage) FetchLastFewHostMetricsSlice(host string, count int) (metrichelper.MetricsSlice, error) {
+	storage.recordsWM.mutex.Lock()
+	records := storage.recordsWM.records
+	storage.recordsWM.mutex.Unlock()
+	ms := metrichelper.MetricsSlice{}
+	for i := len(records) - 1; i >= 0 && len(ms) < count; i-- {
+		if records[i].Host == host {
+			ms = append(ms, records[i])
+		}
+	}
+	if len(ms) < 1 {
+		return ms, &DataNotFound{}
+	}
+	for i, j := 0, len(ms)-1; i < j; i, j = i+1, j-1 {
+		ms[i], ms[j] = ms[j], ms[i]
+	}
+	return ms, nil
+}
+
+// FetchMetricsSlice returns the metrics of host ending between from and to included.
+func (storage *MemoryStorage) FetchMetricsSlice(host string, from time.Time, to time.Time) (metrichelper.MetricsSlice, error) {
+	storage.recordsWM.mutex.Lock()
+	records := storage.recordsWM.records
+	storage.recordsWM.mutex.Unlock()
+	ms := metrichelper.MetricsSlice{}
+	start := sort.Search(len(records), func(i int) bool {
+		return !records[i].EndTime.Before(from)
+	})
+	for _, metrics := range records[start:] {
+		if metrics.EndTime.After(to) {
+			break
+		}
+		if metrics.Host == host {
+			ms = append(ms, metrics)
+		}
+	}
+	if len(ms) < 1 {
+		return ms, &DataNotFound{}
+	}
+	return ms, nil
+}
+
+// FetchMetricsRollup returns the metrics of host ending between from and to included,
+// averaged over the steps starting at from. The raw metrics are returned when step is zero.
+func (storage *MemoryStorage) FetchMetricsRollup(
+	host string,
+	from time.Time,
+	to time.Time,
+	step time.Duration,
+) (metrichelper.MetricsSlice, error) {
+	ms, err := storage.FetchMetricsSlice(host, from, to)
+	if err != nil {
+		return ms, err
+	}
+	return ms.Rollup(from, step), nil
+}
+
+func (storage *MemoryStorage) FetchLastOplogMetrics(host string) (metrichelper.OplogMetrics, error) {
+	storage.oplogRecordsWM.mutex.Lock()
+	records := storage.oplogRecordsWM.records[host]
+	storage.oplogRecordsWM.mutex.Unlock()
+	if len(records) < 1 {
+		return metrichelper.OplogMetrics{}, &DataNotFound{}
+	}
+	return records[len(records)-1], nil
+}
+
+func (storage *MemoryStorage) RecordOplogMetrics(metrics metrichelper.OplogMetrics) error {
+	storage.oplogRecordsWM.mutex.Lock()
+	storage.oplogRecordsWM.records[metrics.Host] = append(storage.oplogRecordsWM.records[metrics.Host], metrics)
+	storage.oplogRecordsWM.mutex.Unlock()
+	return nil
+}
+
+func (storage *MemoryStorage) FetchLastSizeMetrics(namespace string) (metrichelper.SizeMetrics, error) {
+	storage.sizeRecordsWM.mutex.Lock()
+	records := storage.sizeRecordsWM.records[namespace]
+	storage.sizeRecordsWM.mutex.Unlock()
+	if len(records) < 1 {
+		return metrichelper.SizeMetrics{}, &DataNotFound{}
+	}
+	return records[len(records)-1], nil
+}
+
+func (storage *MemoryStorage) FetchLastSizeMetricsSlice() (metrichelper.SizeMetricsSlice, error) {
+	storage.sizeRecordsWM.mutex.Lock()
+	defer storage.sizeRecordsWM.mutex.Unlock()
+	if len(storage.sizeRecordsWM.namespaces) < 1 {
+		return metrichelper.SizeMetricsSlice{}, &DataNotFound{}
+	}
+	ms := make(metrichelper.SizeMetricsSlice, 0, len(storage.sizeRecordsWM.namespaces))
+	for _, namespace := range storage.sizeRecordsWM.namespaces {
+		records := storage.sizeRecordsWM.records[namespace]
+		ms = append(ms, records[len(records)-1])
+	}
+	return ms, nil
+}
+
+func (storage *MemoryStorage) RecordSizeMetrics(metrics metrichelper.SizeMetrics) error {
+	namespace := metrics.Namespace()
+	storage.sizeRecordsWM.mutex.Lock()
+	if _, ok := storage.sizeRecordsWM.records[namespace]; !ok {
+		storage.sizeRecordsWM.namespaces = append(storage.sizeRecordsWM.namespaces, namespace)
+	}
+	records := append(storage.sizeRecordsWM.records[namespace], metrics)
+	if over := len(records) - maxSizeRecords; over > 0 {
+		records = append(records[:0:0], records[over:]...)
+	}
+	storage.sizeRecordsWM.records[namespace] = records
+	storage.sizeRecordsWM.mutex.Unlock()
+	return nil
+}
+
+func (storage *MemoryStorage) FetchLastIndexMetrics(id string) (metrichelper.IndexMetrics, error) {
+	storage.indexRecordsWM.mutex.Lock()
+	records := storage.indexRecordsWM.records[id]
+	storage.indexRecordsWM.mutex.Unlock()
+	if len(records) < 1 {
+		return metrichelper.IndexMetrics{}, &DataNotFound{}
+	}
+	return records[len(records)-1], nil
+}
+
+func (storage *MemoryStorage) FetchLastIndexMetricsSlice() (metrichelper.IndexMetricsSlice, error) {
+	storage.indexRecordsWM.mutex.Lock()
+	defer storage.indexRecordsWM.mutex.Unlock()
+	if len(storage.indexRecordsWM.ids) < 1 {
+		return metrichelper.IndexMetricsSlice{}, &DataNotFound{}
+	}
+	ms := make(metrichelper.IndexMetricsSlice, 0, len(storage.indexRecordsWM.ids))
+	for _, id := range storage.indexRecordsWM.ids {
+		records := storage.indexRecordsWM.records[id]
+		ms = append(ms, records[len(records)-1])
+	}
+	return ms, nil
+}
+
+func (storage *MemoryStorage) RecordIndexMetrics(metrics metrichelper.IndexMetrics) error {
+	id := metrics.ID()
+	storage.indexRecordsWM.mutex.Lock()
+	if _, ok := storage.indexRecordsWM.records[id]; !ok {
+		storage.indexRecordsWM.ids = append(storage.indexRecordsWM.ids, id)
+	}
+	storage.indexRecordsWM.records[id] = append(storage.indexRecordsWM.records[id], metrics)
+	storage.indexRecordsWM.mutex.Unlock()
+	return nil
+}
+
+func (storage *MemoryStorage) FetchLastTopMetrics() (metrichelper.NamespaceMetricsSlice, error) {
+	storage.topRecordsWM.mutex.Lock()
+	last := storage.topRecordsWM.last
+	storage.topRecordsWM.mutex.Unlock()
+	if last == nil {
+		return metrichelper.NamespaceMetricsSlice{}, &DataNotFound{}
+	}
+	return last, nil
+}
+
+// RecordTopMetrics records the last top sample in place of the previous one.
+func (storage *MemoryStorage) RecordTopMetrics(ms metrichelper.NamespaceMetricsSlice) error {
+	storage.topRecordsWM.mutex.Lock()
+	storage.topRecordsWM.last = ms
+	storage.topRecordsWM.mutex.Unlock()
+	return nil
+}
+
+func (storage *MemoryStorage) FetchLastTopology(host string) (metrichelper.Topology, error) {
+	storage.topologyRecordsWM.mutex.Lock()
+	topology, ok := storage.topologyRecordsWM.records[host]
+	storage.topologyRecordsWM.mutex.Unlock()
+	if !ok {
+		return metrichelper.Topology{}, &DataNotFound{}
+	}
+	return topology, nil
+}
+
+func (storage *MemoryStorage) RecordTopology(topology metrichelper.Topology) error {
+	storage.topologyRecordsWM.mutex.Lock()
+	storage.topologyRecordsWM.records[topology.Host] = topology
+	storage.topologyRecordsWM.mutex.Unlock()
 	return nil
 }
 
+// FetchEvents returns the events of host between from and to included, of every
+// target when host is empty, oldest first.
+func (storage *MemoryStorage) FetchEvents(host string, from time.Time, to time.Time) (metrichelper.EventsSlice, error) {
+	storage.eventRecordsWM.mutex.Lock()
+	records := storage.eventRecordsWM.records
+	storage.eventRecordsWM.mutex.Unlock()
+	events := metrichelper.EventsSlice{}
+	first := sort.Search(len(records), func(i int) bool {
+		return !records[i].Time.Before(from)
+	})
+	for _, event := range records[first:] {
+		if event.Time.After(to) {
+			break
+		}
+		if host == "" || event.Target == host {
+			events = append(events, event)
+		}
+	}
+	if len(events) < 1 {
+		return events, &DataNotFound{}
+	}
+	return events, nil
+}
+
+// RecordEvent records an event, the events are kept ordered by time as the alerts
+// are recorded when they fire, after the samples of their time.
+func (storage *MemoryStorage) RecordEvent(event metrichelper.Event) error {
+	storage.eventRecordsWM.mutex.Lock()
+	defer storage.eventRecordsWM.mutex.Unlock()
+	records := storage.eventRecordsWM.records
+	if n := len(records); n == 0 || !records[n-1].Time.After(event.Time) {
+		storage.eventRecordsWM.records = append(records, event)
+		return nil
+	}
+	// The fetched slices share the records, so the late events are inserted in a copy.
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].Time.After(event.Time)
+	})
+	sorted := make(metrichelper.EventsSlice, 0, len(records)+1)
+	sorted = append(sorted, records[:i]...)
+	sorted = append(sorted, event)
+	storage.eventRecordsWM.records = append(sorted, records[i:]...)
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func createMemoryStorage() Storage {
-	return &MemoryStorage{}
+	return &MemoryStorage{
+		recordsWM: recordsWithMutex{
+			records: []metrichelper.Metrics{},
+			hosts:   []string{},
+		},
+		oplogRecordsWM: oplogRecordsWithMutex{
+			records: map[string][]metrichelper.OplogMetrics{},
+		},
+		sizeRecordsWM: sizeRecordsWithMutex{
+			records:    map[string][]metrichelper.SizeMetrics{},
+			namespaces: []string{},
+		},
+		indexRecordsWM: indexRecordsWithMutex{
+			records: map[string][]metrichelper.IndexMetrics{},
+			ids:     []string{},
+		},
+		topologyRecordsWM: topologyRecordsWithMutex{
+			records: map[string]metrichelper.Topology{},
+		},
+		eventRecordsWM: eventRecordsWithMutex{
+			records: metrichelper.EventsSlice{},
+		},
+	}
 }
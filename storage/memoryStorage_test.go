@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+var start = time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func metricsAt(host string, seconds int) metrichelper.Metrics {
+	return metrichelper.Metrics{
+		Host:                host,
+		QueryCountPerSecond: float64(seconds),
+		EndTime:             start.Add(time.Duration(seconds) * time.Second),
+	}
+}
+
+// seconds returns the end times of ms in seconds after start.
+func seconds(ms metrichelper.MetricsSlice) []int {
+	s := []int{}
+	for _, m := range ms {
+		s = append(s, int(m.EndTime.Sub(start)/time.Second))
+	}
+	return s
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFetchMetricsSlice(t *testing.T) {
+	s := CreateStorage(Memory)
+	// The samples of b end a bit before the ones of a recorded before them.
+	for i := 0; i < 10; i++ {
+		s.RecordMetrics(metricsAt("a", 2*i+1))
+		s.RecordMetrics(metricsAt("b", 2*i))
+	}
+
+	tests := []struct {
+		host     string
+		from, to int
+		want     []int
+	}{
+		{"a", 0, 100, []int{1, 3, 5, 7, 9, 11, 13, 15, 17, 19}},
+		{"a", 5, 9, []int{5, 7, 9}},
+		{"b", 5, 9, []int{6, 8}},
+		{"b", 18, 18, []int{18}},
+		{"a", 20, 30, []int{}},
+		{"c", 0, 100, []int{}},
+	}
+	for _, test := range tests {
+		ms, err := s.FetchMetricsSlice(test.host, start.Add(time.Duration(test.from)*time.Second), start.Add(time.Duration(test.to)*time.Second))
+		if got := seconds(ms); !equalInts(got, test.want) {
+			t.Errorf("FetchMetricsSlice(%s, %d, %d) = %v, want %v", test.host, test.from, test.to, got, test.want)
+		}
+		if _, notFound := err.(*DataNotFound); notFound != (len(test.want) == 0) {
+			t.Errorf("FetchMetricsSlice(%s, %d, %d) error = %v", test.host, test.from, test.to, err)
+		}
+	}
+
+	last, _ := s.FetchLastMetrics()
+	if last.Host != "a" || last.QueryCountPerSecond != 19 {
+		t.Errorf("FetchLastMetrics() = %+v, want the metrics ending last", last)
+	}
+}
+
+func TestRecordMetricsRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention Retention
+		want      []int
+	}{
+		{"none", Retention{}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"count", Retention{Count: 3}, []int{7, 8, 9}},
+		{"age", Retention{Age: 4 * time.Second}, []int{5, 6, 7, 8, 9}},
+		{"age and count", Retention{Age: 4 * time.Second, Count: 2}, []int{8, 9}},
+	}
+	for _, test := range tests {
+		s := CreateStorage(Memory)
+		s.SetRetention(test.retention)
+		for i := 0; i < 10; i++ {
+			s.RecordMetrics(metricsAt("a", i))
+		}
+		ms, _ := s.FetchMetricsSlice("a", start, start.Add(time.Hour))
+		if got := seconds(ms); !equalInts(got, test.want) {
+			t.Errorf("%s: kept %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRecordMetricsKeepsFetchedSlices(t *testing.T) {
+	s := CreateStorage(Memory)
+	s.SetRetention(Retention{Count: 3})
+	for i := 0; i < 3; i++ {
+		s.RecordMetrics(metricsAt("a", 2*i))
+	}
+	fetched, _ := s.FetchMetricsSlice("a", start, start.Add(time.Hour))
+	all, _ := s.FetchLastFewMetricsSlice(10)
+	s.RecordMetrics(metricsAt("a", 3))
+	s.RecordMetrics(metricsAt("a", 10))
+
+	if got := seconds(fetched); !equalInts(got, []int{0, 2, 4}) {
+		t.Errorf("fetched slice changed to %v", got)
+	}
+	if got := seconds(all); !equalInts(got, []int{0, 2, 4}) {
+		t.Errorf("last few slice changed to %v", got)
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	s := CreateStorage(Memory)
+	event := func(host string, seconds int) metrichelper.Event {
+		return metrichelper.Event{Target: host, Time: start.Add(time.Duration(seconds) * time.Second)}
+	}
+	s.RecordEvent(event("a", 1))
+	s.RecordEvent(event("b", 5))
+	fetched, _ := s.FetchEvents("", start, start.Add(time.Hour))
+	// The alert of a fires after the event of b.
+	s.RecordEvent(event("a", 3))
+	s.RecordEvent(event("a", 7))
+
+	all, _ := s.FetchEvents("", start, start.Add(time.Hour))
+	want := []int{1, 3, 5, 7}
+	if len(all) != len(want) {
+		t.Fatalf("FetchEvents() = %v, want events at %v", all, want)
+	}
+	for i := range want {
+		if got := int(all[i].Time.Sub(start) / time.Second); got != want[i] {
+			t.Errorf("FetchEvents() = %v, want events at %v", all, want)
+		}
+	}
+	if len(fetched) != 2 || fetched[1].Target != "b" {
+		t.Errorf("fetched events changed to %v", fetched)
+	}
+	if a, _ := s.FetchEvents("a", start.Add(2*time.Second), start.Add(7*time.Second)); len(a) != 2 {
+		t.Errorf("FetchEvents(a, 2, 7) = %v, want the events at 3 and 7", a)
+	}
+}
+
+func TestRecordTopMetricsKeepsTheLast(t *testing.T) {
+	s := CreateStorage(Memory)
+	if _, err := s.FetchLastTopMetrics(); err == nil {
+		t.Error("FetchLastTopMetrics() found metrics before any was recorded")
+	}
+	s.RecordTopMetrics(metrichelper.NamespaceMetricsSlice{{Ns: "test.a"}})
+	s.RecordTopMetrics(metrichelper.NamespaceMetricsSlice{{Ns: "test.b"}, {Ns: "test.c"}})
+
+	if last, err := s.FetchLastTopMetrics(); err != nil || len(last) != 2 || last[0].Ns != "test.b" {
+		t.Errorf("FetchLastTopMetrics() = %v, %v, want the last sample", last, err)
+	}
+}
@@ -0,0 +1,125 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mongo-monitor/alert"
+	"mongo-monitor/anomaly"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+var alertsFunc func() []alert.Alert
+var alertsMutex sync.Mutex
+
+// SetAlertsFunc sets the function returning the alerts displayed on the alerts panel.
+func SetAlertsFunc(fn func() []alert.Alert) {
+	alertsMutex.Lock()
+	alertsFunc = fn
+	alertsMutex.Unlock()
+}
+
+// newAlertsText returns a text block that displays the pending, firing and resolved alerts.
+func newAlertsText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("No alert rule, see --config\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(245)))); err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		alertsMutex.Lock()
+		fn := alertsFunc
+		alertsMutex.Unlock()
+		anomalies := recentAnomalies()
+		if fn == nil && anomalies == nil {
+			return nil
+		}
+		as := []alert.Alert{}
+		if fn != nil {
+			as = fn()
+		}
+
+		t.Reset()
+		if len(as) == 0 && len(anomalies) == 0 {
+			return t.Write("All clear\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(107))))
+		}
+		for _, a := range as {
+			color := cell.ColorNumber(222)
+			switch a.State {
+			case alert.StateFiring:
+				color = cell.ColorNumber(161)
+			case alert.StateResolved:
+				color = cell.ColorNumber(107)
+			}
+			if err := t.Write(
+				fmt.Sprintf(
+					"%-8s %-8s %s %s since %s  %s\n",
+					strings.ToUpper(string(a.State)),
+					a.Severity,
+					a.Target,
+					a.Rule,
+					a.Since.Format("15:04:05"),
+					formatAlertValues(a.Values),
+				),
+				text.WriteCellOpts(cell.FgColor(color)),
+			); err != nil {
+				return err
+			}
+		}
+		for _, a := range anomalies {
+			if err := t.Write(
+				fmt.Sprintf("%-8s %s at %s\n", "ANOMALY", a, a.Time.Format("15:04:05")),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(196))),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
+
+// recentAnomalies returns the anomalies found during the last anomaliesShown, nil
+// when the anomalies are not detected.
+func recentAnomalies() []anomaly.Anomaly {
+	anomalies := getAnomalies()
+	if anomalies == nil {
+		return nil
+	}
+	// The time of the last sample rather than the clock, so replays list their anomalies.
+	now := time.Now()
+	if ms := history.currentSamples(); len(ms) > 0 {
+		now = ms[len(ms)-1].EndTime
+	}
+	recent := []anomaly.Anomaly{}
+	for _, a := range anomalies {
+		if a.Time.Before(now.Add(-anomaliesShown)) {
+			break
+		}
+		recent = append(recent, a)
+	}
+	return recent
+}
+
+func formatAlertValues(values map[string]float64) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%.4g", name, values[name])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,57 @@
+package termui
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"mongo-monitor/anomaly"
+)
+
+// anomaliesShown is how long an anomaly stays listed on the alerts panel.
+const anomaliesShown = 5 * time.Minute
+
+var anomaliesFunc func() []anomaly.Anomaly
+var anomaliesMutex sync.Mutex
+
+// SetAnomaliesFunc sets the function returning the anomalies highlighted on the
+// charts and listed on the alerts panel, the most recent first.
+func SetAnomaliesFunc(fn func() []anomaly.Anomaly) {
+	anomaliesMutex.Lock()
+	anomaliesFunc = fn
+	anomaliesMutex.Unlock()
+}
+
+func getAnomalies() []anomaly.Anomaly {
+	anomaliesMutex.Lock()
+	fn := anomaliesFunc
+	anomaliesMutex.Unlock()
+	if fn == nil {
+		return nil
+	}
+	return fn()
+}
+
+// anomalyHighlight returns the series drawn over values to highlight the samples
+// of metric that are anomalous, values are the samples taken at times. The segment
+// leading to an anomalous sample is kept, the other values are NaN so they are
+// not drawn.
+func anomalyHighlight(anomalies []anomaly.Anomaly, metric string, values []float64, times []time.Time) []float64 {
+	anomalous := map[time.Time]bool{}
+	for _, a := range anomalies {
+		if a.Metric == metric {
+			anomalous[a.Time] = true
+		}
+	}
+	highlight := make([]float64, len(values))
+	for i := range highlight {
+		highlight[i] = math.NaN()
+	}
+	for i := 1; i < len(values); i++ {
+		if !times[i].IsZero() && anomalous[times[i]] {
+			highlight[i-1] = values[i-1]
+			highlight[i] = values[i]
+		}
+	}
+	return highlight
+}
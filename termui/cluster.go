@@ -0,0 +1,349 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"mongo-monitor/alert"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/sparkline"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// clusterSlots is the number of nodes on a page of the cluster page, the worst first.
+const clusterSlots = 10
+
+type clusterState struct {
+	// page is the page of clusterSlots nodes shown.
+	page  int
+	mutex sync.Mutex
+}
+
+var cluster = clusterState{}
+
+// handleKey shows the previous or the next nodes on PgUp and PgDn. It reports whether
+// the key was consumed.
+func (s *clusterState) handleKey(k *terminalapi.Keyboard) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch k.Key {
+	case keyboard.KeyPgUp:
+		if s.page > 0 {
+			s.page--
+		}
+	case keyboard.KeyPgDn:
+		s.page++
+	default:
+		return false
+	}
+	return true
+}
+
+// shownPage returns the page of the nodes shown, the last one when there are fewer
+// pages of count nodes than the page selected.
+func (s *clusterState) shownPage(count int) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	last := (count - 1) / clusterSlots
+	if s.page > last {
+		s.page = last
+	}
+	return s.page
+}
+
+const (
+	healthOK = iota
+	healthWarning
+	healthCritical
+)
+
+// healthColors are the colors of the nodes by health.
+var healthColors = []cell.Color{
+	cell.ColorNumber(107),
+	cell.ColorNumber(172),
+	cell.ColorNumber(161),
+}
+
+// connectionsWarning and connectionsCritical are the percentages of the connections
+// used from which a node is unhealthy.
+const (
+	connectionsWarning  = 80
+	connectionsCritical = 90
+)
+
+// clusterMetric is a sparkline of the rows of the cluster page.
+type clusterMetric struct {
+	label  string
+	value  func(values map[string]float64) (float64, bool)
+	format func(float64) string
+	// warning and critical are the values from which the node is unhealthy.
+	warning  float64
+	critical float64
+}
+
+// clusterMetrics are the sparklines of a node, the lag comes from the replica set
+// status rather than from the values of the node.
+var clusterMetrics = []clusterMetric{
+	{"ops/s", operationsPerSecond, shortRate, 0, 0},
+	{"conns", valueOf("connections_current"), formatCount, 0, 0},
+	{"lag s", nil, formatCount, 10, 60},
+	{"dirty", valueOf("wt_cache_dirty_pct"), formatPercent, 5, 20},
+}
+
+// operationsPerSecond sums the opcounters, they are missing for the first sample.
+func operationsPerSecond(values map[string]float64) (float64, bool) {
+	sum := 0.0
+	for _, metric := range []string{
+		"insert_per_second",
+		"query_per_second",
+		"update_per_second",
+		"delete_per_second",
+		"getmore_per_second",
+		"command_per_second",
+	} {
+		v, ok := values[metric]
+		if !ok {
+			return 0, false
+		}
+		sum += v
+	}
+	return sum, true
+}
+
+func valueOf(metric string) func(values map[string]float64) (float64, bool) {
+	return func(values map[string]float64) (float64, bool) {
+		v, ok := values[metric]
+		return v, ok
+	}
+}
+
+// clusterNode is the row of a node on the cluster page.
+type clusterNode struct {
+	host    string
+	health  int
+	reasons []string
+	series  [][]float64
+}
+
+// clusterNodes returns the rows of the nodes, the worst first: the critical ones,
+// then the ones with a warning, the ones with the most reasons first.
+func clusterNodes() []clusterNode {
+	valuesMutex.Lock()
+	histories := make(map[string][]valuesSample, len(valuesHistory))
+	for target, samples := range valuesHistory {
+		histories[target] = samples
+	}
+	valuesMutex.Unlock()
+
+	alertsMutex.Lock()
+	fn := alertsFunc
+	alertsMutex.Unlock()
+	alerts := map[string][]alert.Alert{}
+	if fn != nil {
+		for _, a := range fn() {
+			alerts[a.Target] = append(alerts[a.Target], a)
+		}
+	}
+	anomalies := map[string]int{}
+	for _, a := range recentAnomalies() {
+		anomalies[a.Target]++
+	}
+
+	nodes := make([]clusterNode, 0, len(histories))
+	for host, samples := range histories {
+		node := clusterNode{host: host, series: make([][]float64, len(clusterMetrics))}
+		raise := func(health int, reason string) {
+			if health > node.health {
+				node.health = health
+			}
+			node.reasons = append(node.reasons, reason)
+		}
+
+		for i, m := range clusterMetrics {
+			if m.value == nil {
+				node.series[i] = getLagHistory(memberName(host))
+			} else {
+				for _, sample := range samples {
+					if v, ok := m.value(sample.values); ok {
+						node.series[i] = append(node.series[i], v)
+					}
+				}
+			}
+			if len(node.series[i]) == 0 || m.warning == 0 {
+				continue
+			}
+			last := node.series[i][len(node.series[i])-1]
+			if last >= m.critical {
+				raise(healthCritical, fmt.Sprintf("%s %s", m.label, m.format(last)))
+			} else if last >= m.warning {
+				raise(healthWarning, fmt.Sprintf("%s %s", m.label, m.format(last)))
+			}
+		}
+		// The connections are charted by count but are only a concern near the limit.
+		if len(samples) > 0 {
+			if used, ok := samples[len(samples)-1].values["connections_used_pct"]; ok {
+				if used >= connectionsCritical {
+					raise(healthCritical, "conns "+formatPercent(used))
+				} else if used >= connectionsWarning {
+					raise(healthWarning, "conns "+formatPercent(used))
+				}
+			}
+		}
+		for _, a := range alerts[host] {
+			switch {
+			case a.State == alert.StateFiring && a.Severity == "critical":
+				raise(healthCritical, a.Rule)
+			case a.State == alert.StateFiring || a.State == alert.StatePending:
+				raise(healthWarning, a.Rule)
+			}
+		}
+		if n := anomalies[host]; n > 0 {
+			raise(healthWarning, fmt.Sprintf("%d anomalies", n))
+		}
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].health != nodes[j].health {
+			return nodes[i].health > nodes[j].health
+		}
+		if len(nodes[i].reasons) != len(nodes[j].reasons) {
+			return len(nodes[i].reasons) > len(nodes[j].reasons)
+		}
+		return nodes[i].host < nodes[j].host
+	})
+	return nodes
+}
+
+// clusterRow are the widgets of a row of the cluster page.
+type clusterRow struct {
+	text   *text.Text
+	sparks []*sparkline.SparkLine
+}
+
+// newClusterWidgets returns a text block that sums up the health of the nodes, and the
+// rows of the cluster page showing the worst nodes.
+func newClusterWidgets(ctx context.Context) (*text.Text, []clusterRow, error) {
+	summary, err := text.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := summary.Write("Waiting for the first sample...\n"); err != nil {
+		return nil, nil, err
+	}
+	rows := make([]clusterRow, clusterSlots)
+	for i := range rows {
+		if rows[i].text, err = text.New(); err != nil {
+			return nil, nil, err
+		}
+		for range clusterMetrics {
+			spark, err := sparkline.New()
+			if err != nil {
+				return nil, nil, err
+			}
+			rows[i].sparks = append(rows[i].sparks, spark)
+		}
+	}
+
+	go redraw(ctx, summary, func() error {
+		nodes := clusterNodes()
+		if len(nodes) == 0 {
+			return nil
+		}
+
+		counts := make([]int, len(healthColors))
+		for _, node := range nodes {
+			counts[node.health]++
+		}
+		summary.Reset()
+		if err := summary.Write(
+			fmt.Sprintf("%d nodes  ", len(nodes)),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+		); err != nil {
+			return err
+		}
+		for health, label := range []string{"ok", "warning", "critical"} {
+			if err := summary.Write(
+				fmt.Sprintf("%d %s  ", counts[health], label),
+				text.WriteCellOpts(cell.FgColor(healthColors[health])),
+			); err != nil {
+				return err
+			}
+		}
+		page := cluster.shownPage(len(nodes))
+		if len(nodes) > clusterSlots {
+			if err := summary.Write(
+				fmt.Sprintf("page %d/%d, PgUp/PgDn for the others", page+1, (len(nodes)-1)/clusterSlots+1),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(245))),
+			); err != nil {
+				return err
+			}
+		}
+		nodes = nodes[page*clusterSlots:]
+
+		for i, row := range rows {
+			row.text.Reset()
+			if i >= len(nodes) {
+				for _, spark := range row.sparks {
+					spark.Clear()
+					if err := spark.Add(nil, sparkline.Label("")); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := writeClusterNode(row.text, nodes[i]); err != nil {
+				return err
+			}
+			for j, spark := range row.sparks {
+				if err := drawClusterSpark(spark, clusterMetrics[j], nodes[i].series[j], healthColors[nodes[i].health]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	return summary, rows, nil
+}
+
+// writeClusterNode writes the host and the health of node on t.
+func writeClusterNode(t *text.Text, node clusterNode) error {
+	color := healthColors[node.health]
+	if err := t.Write(node.host+"\n", text.WriteCellOpts(cell.FgColor(color))); err != nil {
+		return err
+	}
+	status := "ok"
+	if len(node.reasons) > 0 {
+		status = strings.Join(node.reasons, ", ")
+	}
+	return t.Write(status+"\n", text.WriteCellOpts(cell.FgColor(color)))
+}
+
+// drawClusterSpark draws the values of a metric of a node on spark, labelled with the
+// last value.
+func drawClusterSpark(spark *sparkline.SparkLine, m clusterMetric, values []float64, color cell.Color) error {
+	label := m.label + " -"
+	if len(values) > 0 {
+		label = m.label + " " + m.format(values[len(values)-1])
+	}
+	// The sparklines are scaled on their maximum, the values are only multiplied to
+	// keep the fractions of the small ones.
+	data := make([]int, len(values))
+	for i, v := range values {
+		if v > 0 {
+			data[i] = int(v * 100)
+		}
+	}
+	spark.Clear()
+	return spark.Add(data,
+		sparkline.Label(label, cell.FgColor(cell.ColorNumber(222))),
+		sparkline.Color(color),
+	)
+}
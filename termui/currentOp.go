@@ -0,0 +1,289 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+type currentOpState struct {
+	operations metricHelper.Operations
+	sortKey    metricHelper.OperationSortKey
+	filter     string
+	filtering  bool
+	selected   int
+	// killing is the operation waiting for the kill confirmation.
+	killing *metricHelper.Operation
+	message string
+	mutex   sync.Mutex
+}
+
+var currentOps = currentOpState{}
+var killOp func(opID interface{}) error
+
+// UpdateOperations sets the operations displayed on the current operations table.
+func UpdateOperations(ops metricHelper.Operations) {
+	currentOps.mutex.Lock()
+	currentOps.operations = ops
+	currentOps.mutex.Unlock()
+	changed()
+}
+
+// SetOperationsFilter sets the keyword filtering the current operations table.
+func SetOperationsFilter(filter string) {
+	currentOps.mutex.Lock()
+	currentOps.filter = filter
+	currentOps.mutex.Unlock()
+}
+
+// SetKillOpFunc sets the function killing an operation once the user confirms.
+func SetKillOpFunc(fn func(opID interface{}) error) {
+	currentOps.mutex.Lock()
+	killOp = fn
+	currentOps.mutex.Unlock()
+}
+
+// visibleOperations returns the filtered and sorted operations, the caller must hold the mutex.
+func (s *currentOpState) visibleOperations() metricHelper.Operations {
+	ops := make(metricHelper.Operations, len(s.operations))
+	copy(ops, s.operations)
+	ops = ops.Filter(s.filter)
+	ops.Sort(s.sortKey)
+	if s.selected >= len(ops) {
+		s.selected = len(ops) - 1
+	}
+	if s.selected < 0 {
+		s.selected = 0
+	}
+	return ops
+}
+
+// handleKey handles the keys of the current operations view and reports whether the key was consumed.
+func (s *currentOpState) handleKey(k *terminalapi.Keyboard) bool {
+	s.mutex.Lock()
+	consumed, kill := s.handleLockedKey(k)
+	fn := killOp
+	s.mutex.Unlock()
+	if kill != nil {
+		s.kill(fn, *kill)
+	}
+	return consumed
+}
+
+// kill kills op with fn, without holding the mutex as killOp is a round trip to the
+// server the redraws would wait for.
+func (s *currentOpState) kill(fn func(opID interface{}) error, op metricHelper.Operation) {
+	message := fmt.Sprintf("Killed op %s", op.OpIDString())
+	if fn == nil {
+		message = "killOp is not available"
+	} else if err := fn(op.OpID); err != nil {
+		message = fmt.Sprintf("Failed to kill op %s: %s", op.OpIDString(), err)
+	}
+	s.mutex.Lock()
+	s.message = message
+	s.mutex.Unlock()
+	changed()
+}
+
+// handleLockedKey handles a key with the mutex held, it returns whether the key was
+// consumed and the operation to kill once the mutex is released.
+func (s *currentOpState) handleLockedKey(k *terminalapi.Keyboard) (bool, *metricHelper.Operation) {
+	if s.filtering {
+		switch k.Key {
+		case keyboard.KeyEnter:
+			s.filtering = false
+		case keyboard.KeyEsc:
+			s.filtering = false
+			s.filter = ""
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+			if len(s.filter) > 0 {
+				s.filter = s.filter[:len(s.filter)-1]
+			}
+		default:
+			if k.Key >= 0x20 && k.Key < 0x7f {
+				s.filter += string(rune(k.Key))
+			}
+		}
+		return true, nil
+	}
+
+	if s.killing != nil {
+		op := *s.killing
+		s.killing = nil
+		if k.Key.String() == "y" {
+			s.message = fmt.Sprintf("Killing op %s...", op.OpIDString())
+			return true, &op
+		}
+		s.message = fmt.Sprintf("Kept op %s", op.OpIDString())
+		return true, nil
+	}
+
+	switch k.Key {
+	case keyboard.KeyArrowUp:
+		s.selected--
+		return true, nil
+	case keyboard.KeyArrowDown:
+		s.selected++
+		return true, nil
+	}
+	switch k.Key.String() {
+	case "/":
+		s.filtering = true
+		s.message = ""
+		return true, nil
+	case "s":
+		s.sortKey = s.sortKey.Next()
+		return true, nil
+	case "k":
+		ops := s.visibleOperations()
+		if len(ops) > 0 {
+			op := ops[s.selected]
+			s.killing = &op
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// newCurrentOpStatusText returns a text block that displays the key bindings, the filter and the kill prompt.
+func newCurrentOpStatusText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		currentOps.mutex.Lock()
+		filter := currentOps.filter
+		filtering := currentOps.filtering
+		sortKey := currentOps.sortKey
+		killing := currentOps.killing
+		message := currentOps.message
+		currentOps.mutex.Unlock()
+
+		t.Reset()
+		if err := t.Write(
+			"Up/Down select, s sort, / filter, k kill, Esc/Q/Ctrl-C quit\n",
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+		); err != nil {
+			return err
+		}
+		cursor := ""
+		if filtering {
+			cursor = "_"
+		}
+		if err := t.Write(
+			fmt.Sprintf("sort: %s  filter: %s%s\n", sortKey, filter, cursor),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		if killing != nil {
+			return t.Write(
+				fmt.Sprintf("Kill op %s on %s (%s)? y/n\n", killing.OpIDString(), killing.Ns, killing.Op),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(161))),
+			)
+		}
+		return t.Write(message+"\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(107))))
+	})
+
+	return t, nil
+}
+
+// newCurrentOpText returns a text block that displays the current operations table.
+func newCurrentOpText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		currentOps.mutex.Lock()
+		ops := currentOps.visibleOperations()
+		selected := currentOps.selected
+		currentOps.mutex.Unlock()
+
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf(
+				"%-16s %-40s %-10s %10s %12s %-5s %-24s %s\n",
+				"OPID", "NS", "OP", "SECS", "LOCK WAIT", "WAIT", "CLIENT", "PLAN",
+			),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for i, op := range ops {
+			opts := []cell.Option{cell.FgColor(cell.ColorNumber(111))}
+			if op.WaitingForLock {
+				opts = []cell.Option{cell.FgColor(cell.ColorNumber(161))}
+			}
+			if i == selected {
+				opts = append(opts, cell.BgColor(cell.ColorNumber(238)))
+			}
+			waiting := ""
+			if op.WaitingForLock {
+				waiting = "yes"
+			}
+			if err := t.Write(
+				fmt.Sprintf(
+					"%-16s %-40s %-10s %10.1f %10.0fms %-5s %-24s %s\n",
+					op.OpIDString(),
+					op.Ns,
+					op.Op,
+					op.SecsRunning,
+					op.LockWaitMicros/1000,
+					waiting,
+					op.Client,
+					op.PlanSummary,
+				),
+				text.WriteCellOpts(opts...),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
+
+// RenderCurrentOp is starting the current operations UI on terminal
+func RenderCurrentOp(parentCtx context.Context) {
+	run(parentCtx, func(ctx context.Context, c *container.Container) ([]container.Option, error) {
+		statusText, err := newCurrentOpStatusText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		currentOpText, err := newCurrentOpText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.PlaceWidget(statusText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Current Operations"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.Bottom(
+					container.PlaceWidget(currentOpText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Operations"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.SplitPercent(15),
+			),
+		}, nil
+	}, currentOps.handleKey)
+}
@@ -0,0 +1,25 @@
+package termui
+
+import (
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+func TestCurrentOpFilterKeys(t *testing.T) {
+	s := &currentOpState{}
+	keys := []keyboard.Key{'/'}
+	for _, c := range "find users" {
+		keys = append(keys, keyboard.Key(c))
+	}
+	keys = append(keys, keyboard.KeyBackspace2, keyboard.KeyEnter)
+	for _, k := range keys {
+		if !s.handleKey(&terminalapi.Keyboard{Key: k}) {
+			t.Errorf("key %v was not consumed", k)
+		}
+	}
+	if s.filter != "find user" || s.filtering {
+		t.Errorf("filter = %q, filtering %v, want \"find user\" once Enter is pressed", s.filter, s.filtering)
+	}
+}
@@ -0,0 +1,204 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// eventsShown is the number of events listed on the events panel, the oldest are
+// dropped.
+const eventsShown = 200
+
+// eventsLateness is how late an event may be recorded after its time.
+const eventsLateness = time.Minute
+
+// eventMarkColor is the color of the marks of the events on the line charts.
+var eventMarkColor = cell.ColorNumber(226)
+
+// eventColors are the colors of the events by kind on the events panel.
+var eventColors = map[metricHelper.EventKind]cell.Color{
+	metricHelper.EventRestart:  cell.ColorNumber(161),
+	metricHelper.EventElection: cell.ColorNumber(172),
+	metricHelper.EventState:    cell.ColorNumber(111),
+	metricHelper.EventConfig:   cell.ColorNumber(135),
+	metricHelper.EventFCV:      cell.ColorNumber(107),
+	metricHelper.EventAlert:    cell.ColorNumber(196),
+}
+
+// endOfTime is after every event, the events are fetched up to it.
+var endOfTime = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var eventsFunc func(host string, from time.Time, to time.Time) (metricHelper.EventsSlice, error)
+var eventsMutex sync.Mutex
+
+// SetEventsFunc sets the function returning the events of a host between two times,
+// of every host when the host is empty. The events are listed on the events panel
+// and marked on the line charts.
+func SetEventsFunc(fn func(host string, from time.Time, to time.Time) (metricHelper.EventsSlice, error)) {
+	eventsMutex.Lock()
+	eventsFunc = fn
+	eventsMutex.Unlock()
+}
+
+// getEvents returns the events of host between from and to, nil when there is none.
+func getEvents(host string, from time.Time, to time.Time) metricHelper.EventsSlice {
+	eventsMutex.Lock()
+	fn := eventsFunc
+	eventsMutex.Unlock()
+	if fn == nil {
+		return nil
+	}
+	events, err := fn(host, from, to)
+	if err != nil {
+		return nil
+	}
+	return events
+}
+
+// newEventsText returns a text block that displays the last events of every node,
+// the most recent at the bottom.
+func newEventsText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New(text.RollContent())
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("No event yet\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(245)))); err != nil {
+		return nil, err
+	}
+
+	// The events following the last one shown are written below the others, so the
+	// text keeps its scrolling. The alerts are recorded a bit after their time, the
+	// events of the last eventsLateness are fetched again to catch them.
+	var shown metricHelper.EventsSlice
+	written := 0
+	go redraw(ctx, t, func() error {
+		from := time.Time{}
+		if len(shown) > 0 {
+			from = shown[len(shown)-1].Time.Add(-eventsLateness)
+		}
+		fresh := newEvents(shown, getEvents("", from, endOfTime))
+		if len(fresh) == 0 {
+			return nil
+		}
+		shown = append(shown, fresh...)
+		if len(shown) > eventsShown {
+			shown = append(metricHelper.EventsSlice{}, shown[len(shown)-eventsShown:]...)
+		}
+		// The text only ever grows, it is written again once it holds twice the events shown.
+		if written == 0 || written+len(fresh) > 2*eventsShown {
+			t.Reset()
+			written = 0
+			fresh = shown
+		}
+		written += len(fresh)
+		for _, e := range fresh {
+			if err := writeEvent(t, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
+
+// writeEvent writes e on a line of t, in the color of its kind.
+func writeEvent(t *text.Text, e metricHelper.Event) error {
+	color, ok := eventColors[e.Kind]
+	if !ok {
+		color = cell.ColorNumber(250)
+	}
+	return t.Write(
+		fmt.Sprintf(
+			"%s %-8s %s %s\n",
+			e.Time.Format("15:04:05"),
+			strings.ToUpper(string(e.Kind)),
+			e.Target,
+			e.Message,
+		),
+		text.WriteCellOpts(cell.FgColor(color)),
+	)
+}
+
+// newEvents returns the events of fetched that are not in shown, fetched holding the
+// events from a bit before the last one shown.
+func newEvents(shown, fetched metricHelper.EventsSlice) metricHelper.EventsSlice {
+	if len(shown) == 0 {
+		return fetched
+	}
+	seen := map[metricHelper.Event]bool{}
+	for i := len(shown) - 1; i >= 0 && len(fetched) > 0 && !shown[i].Time.Before(fetched[0].Time); i-- {
+		seen[shown[i]] = true
+	}
+	var fresh metricHelper.EventsSlice
+	for _, e := range fetched {
+		if !seen[e] {
+			fresh = append(fresh, e)
+		}
+	}
+	return fresh
+}
+
+// eventMarks returns the series marking the events of the selected node on a line
+// chart of the samples taken at times, the zero times having no sample. An event is
+// marked by a spike up to max at the first sample taken after it, the other values
+// are NaN so they are not drawn.
+func eventMarks(times []time.Time, max float64) []float64 {
+	marks := make([]float64, len(times))
+	for i := range marks {
+		marks[i] = math.NaN()
+	}
+	first, last := -1, -1
+	for i, t := range times {
+		if t.IsZero() {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	if first < 0 {
+		return marks
+	}
+	if max <= 0 {
+		max = 1
+	}
+
+	for _, e := range getEvents(selectedNode(), times[first], times[last]) {
+		i := first
+		for i < last && times[i].Before(e.Time) {
+			i++
+		}
+		marks[i] = max
+		if i > 0 && math.IsNaN(marks[i-1]) {
+			marks[i-1] = 0
+		}
+		if i+1 < len(marks) && math.IsNaN(marks[i+1]) {
+			marks[i+1] = 0
+		}
+	}
+	return marks
+}
+
+// maxValue returns the largest of values, NaN values aside.
+func maxValue(values ...[]float64) float64 {
+	max := 0.0
+	for _, vs := range values {
+		for _, v := range vs {
+			if !math.IsNaN(v) && v > max {
+				max = v
+			}
+		}
+	}
+	return max
+}
@@ -0,0 +1,32 @@
+package termui
+
+import (
+	"testing"
+	"time"
+
+	metricHelper "mongo-monitor/metric_helper"
+)
+
+func TestNewEvents(t *testing.T) {
+	at := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	event := func(seconds int, message string) metricHelper.Event {
+		return metricHelper.Event{Time: at.Add(time.Duration(seconds) * time.Second), Message: message}
+	}
+	shown := metricHelper.EventsSlice{event(0, "restart"), event(10, "election"), event(20, "state")}
+
+	// The alert recorded late and the events at the time of the last one shown are new.
+	fetched := metricHelper.EventsSlice{event(10, "election"), event(15, "alert"), event(20, "state"), event(20, "config"), event(30, "fcv")}
+	fresh := newEvents(shown, fetched)
+	want := []string{"alert", "config", "fcv"}
+	if len(fresh) != len(want) {
+		t.Fatalf("newEvents() = %v, want %v", fresh, want)
+	}
+	for i := range want {
+		if fresh[i].Message != want[i] {
+			t.Errorf("newEvents() = %v, want %v", fresh, want)
+		}
+	}
+	if fresh := newEvents(nil, fetched); len(fresh) != len(fetched) {
+		t.Errorf("newEvents() without events shown = %v, want every event", fresh)
+	}
+}
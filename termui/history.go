@@ -0,0 +1,256 @@
+package termui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+// zooms are the time windows of the charts, the first one shows the last samples.
+var zooms = []time.Duration{0, time.Minute, 5 * time.Minute, time.Hour, 24 * time.Hour}
+
+type historyState struct {
+	// samples are the last samples of the selected node, see UpdateMetricsSlice.
+	samples metricHelper.MetricsSlice
+	fetch   func(host string, from time.Time, to time.Time, step time.Duration) (metricHelper.MetricsSlice, error)
+	// visible are the metrics drawn on the charts, the last samples or the window
+	// fetched with fetch.
+	visible metricHelper.MetricsSlice
+	// last is the end time of the last samples visible was refreshed with.
+	last   time.Time
+	zoom   int
+	paused bool
+	// end is the end of the window while paused, the last sample otherwise.
+	end    time.Time
+	cursor time.Time
+	// frozenValues are the values of the charts of the pages by node while paused.
+	frozenValues map[string][]valuesSample
+	mutex        sync.Mutex
+}
+
+var history = historyState{}
+
+// SetHistoryFunc sets the function fetching the metrics of host between from and to,
+// averaged over step. The charts only show the last samples when it is nil.
+func SetHistoryFunc(fn func(host string, from time.Time, to time.Time, step time.Duration) (metricHelper.MetricsSlice, error)) {
+	history.mutex.Lock()
+	history.fetch = fn
+	history.mutex.Unlock()
+}
+
+// UpdateMetricsSlice sets the last samples of the node they come from, see
+// UpdateNodeMetricsSlice.
+func UpdateMetricsSlice(ms metricHelper.MetricsSlice) {
+	host := ""
+	if len(ms) > 0 {
+		host = ms[len(ms)-1].Host
+	}
+	UpdateNodeMetricsSlice(host, ms)
+}
+
+// update sets the last samples of the selected node, the charts follow them unless paused.
+func (s *historyState) update(ms metricHelper.MetricsSlice) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.samples = ms
+	if len(ms) == 0 || ms[len(ms)-1].EndTime.Equal(s.last) {
+		return
+	}
+	s.last = ms[len(ms)-1].EndTime
+	if s.paused {
+		return
+	}
+	s.end = s.last
+	s.refresh()
+}
+
+// setNode switches the charts to the last samples ms of another node, showing the
+// same window as the previous node.
+func (s *historyState) setNode(ms metricHelper.MetricsSlice) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.samples = ms
+	s.last = time.Time{}
+	if len(ms) > 0 {
+		s.last = ms[len(ms)-1].EndTime
+	}
+	if !s.paused {
+		s.end = s.last
+	}
+	if zooms[s.zoom] == 0 {
+		s.visible = ms
+		return
+	}
+	s.refresh()
+}
+
+// refresh fetches the visible metrics of the window ending at end.
+func (s *historyState) refresh() {
+	window := zooms[s.zoom]
+	if window == 0 {
+		if !s.paused {
+			s.visible = s.samples
+		}
+		return
+	}
+	ms := s.samples
+	if s.fetch == nil || len(ms) == 0 {
+		return
+	}
+	visible, err := s.fetch(ms[len(ms)-1].Host, s.end.Add(-window), s.end, window/ChartLength)
+	if err != nil {
+		visible = metricHelper.MetricsSlice{}
+	}
+	s.visible = visible
+}
+
+// currentSamples returns the last samples of the selected node.
+func (s *historyState) currentSamples() metricHelper.MetricsSlice {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.samples
+}
+
+// cursorIndex returns the index of the last visible metrics ending at the cursor, -1
+// when there is no cursor.
+func (s *historyState) cursorIndex() int {
+	if s.cursor.IsZero() {
+		return -1
+	}
+	index := 0
+	for i, m := range s.visible {
+		if m.EndTime.After(s.cursor) {
+			break
+		}
+		index = i
+	}
+	return index
+}
+
+// visibleMetrics returns the metrics drawn on the charts and the index of the cursor,
+// -1 when there is no cursor.
+func visibleMetrics() (metricHelper.MetricsSlice, int) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	if len(history.visible) == 0 {
+		return history.visible, -1
+	}
+	return history.visible, history.cursorIndex()
+}
+
+// historyStatus describes the window the charts show.
+func historyStatus() string {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	status := "LIVE"
+	if history.paused {
+		status = "PAUSED"
+	}
+	if window := zooms[history.zoom]; window == 0 {
+		status += fmt.Sprintf(" last %d samples", ChartLength)
+	} else {
+		status += fmt.Sprintf(" %s to %s", formatWindow(window), history.end.Format("15:04:05"))
+	}
+	if i := history.cursorIndex(); i >= 0 && i < len(history.visible) {
+		status += " AT " + history.visible[i].EndTime.Format("15:04:05")
+	}
+	return status
+}
+
+// formatWindow formats a zoom window as 1m, 5m, 1h or 1d.
+func formatWindow(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// setPaused freezes the charts on the current window, or makes them follow the last
+// samples again.
+func (s *historyState) setPaused(paused bool) {
+	if paused == s.paused {
+		return
+	}
+	s.paused = paused
+	nodes.freeze(paused)
+	if paused {
+		valuesMutex.Lock()
+		s.frozenValues = make(map[string][]valuesSample, len(valuesHistory))
+		for target, samples := range valuesHistory {
+			s.frozenValues[target] = samples
+		}
+		valuesMutex.Unlock()
+		return
+	}
+	s.frozenValues = nil
+	s.cursor = time.Time{}
+	s.end = s.last
+}
+
+// handleKey pauses the charts on p, zooms on +/-, scrolls on the arrows and moves the
+// cursor on [ and ]. It reports whether the key was consumed.
+func (s *historyState) handleKey(k *terminalapi.Keyboard) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch k.Key.String() {
+	case "p":
+		s.setPaused(!s.paused)
+	case "+", "=":
+		// The last samples are not kept while paused.
+		if s.zoom > 1 || (s.zoom == 1 && !s.paused) {
+			s.zoom--
+		}
+	case "-":
+		if s.fetch != nil && s.zoom < len(zooms)-1 {
+			s.zoom++
+		}
+	case "[", "]":
+		if len(s.visible) == 0 {
+			return true
+		}
+		i := s.cursorIndex()
+		switch {
+		case i < 0:
+			i = len(s.visible) - 1
+		case k.Key.String() == "[" && i > 0:
+			i--
+		case k.Key.String() == "]" && i < len(s.visible)-1:
+			i++
+		}
+		s.cursor = s.visible[i].EndTime
+		return true
+	default:
+		switch k.Key {
+		case keyboard.KeyArrowLeft:
+			if s.fetch == nil {
+				return true
+			}
+			if s.zoom == 0 {
+				s.zoom = 1
+			}
+			s.setPaused(true)
+			s.end = s.end.Add(-zooms[s.zoom] / 2)
+		case keyboard.KeyArrowRight:
+			if !s.paused {
+				return true
+			}
+			if s.end = s.end.Add(zooms[s.zoom] / 2); s.end.After(s.last) {
+				s.end = s.last
+			}
+		default:
+			return false
+		}
+	}
+	s.refresh()
+	return true
+}
@@ -0,0 +1,80 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+var topMetricsSlice metricHelper.NamespaceMetricsSlice
+var topMutex sync.Mutex
+
+// UpdateTopMetrics sets the namespace metrics displayed on the hottest collections panel.
+func UpdateTopMetrics(ms metricHelper.NamespaceMetricsSlice) {
+	topMutex.Lock()
+	topMetricsSlice = ms
+	topMutex.Unlock()
+	changed()
+}
+
+// newHotCollectionsText returns a text block that ranks the namespaces by the time spent in them.
+func newHotCollectionsText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("Waiting for top stats...\n"); err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		topMutex.Lock()
+		ms := topMetricsSlice
+		topMutex.Unlock()
+		if ms == nil {
+			return nil
+		}
+
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf(
+				"%-4s %-48s %10s %10s %10s %10s %10s\n",
+				"#", "NAMESPACE", "TOTAL ms/s", "READ ms/s", "WRITE ms/s", "READ/s", "WRITE/s",
+			),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for i, m := range ms {
+			color := cell.ColorNumber(111)
+			if m.TotalMillisPerSecond >= 500 {
+				color = cell.ColorNumber(161)
+			} else if m.TotalMillisPerSecond >= 100 {
+				color = cell.ColorNumber(172)
+			}
+			if err := t.Write(
+				fmt.Sprintf(
+					"%-4d %-48s %10.1f %10.1f %10.1f %10.1f %10.1f\n",
+					i+1,
+					m.Ns,
+					m.TotalMillisPerSecond,
+					m.ReadLockMillisPerSecond,
+					m.WriteLockMillisPerSecond,
+					m.ReadOpsPerSecond,
+					m.WriteOpsPerSecond,
+				),
+				text.WriteCellOpts(cell.FgColor(color)),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
@@ -3,6 +3,7 @@ package termui
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	metricHelper "mongo-monitor/metric_helper"
@@ -11,24 +12,39 @@ import (
 	"github.com/mum4k/termdash/cell"
 	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/keyboard"
-	"github.com/mum4k/termdash/linestyle"
 	"github.com/mum4k/termdash/terminal/termbox"
 	"github.com/mum4k/termdash/terminal/terminalapi"
-	"github.com/mum4k/termdash/widgets/button"
 	"github.com/mum4k/termdash/widgets/linechart"
 	"github.com/mum4k/termdash/widgets/text"
 )
 
-// layoutButtons are buttons that change the layout.
-type layoutButtons struct {
-	lcB *button.Button
-}
-
 // widgets holds the widgets used by this demo.
 type widgets struct {
 	mongostatUIText *text.Text
 	opcountersLC    *linechart.LineChart
 	opcountersText  *text.Text
+	oplogText       *text.Text
+	hotText         *text.Text
+	alertsText      *text.Text
+	eventsText      *text.Text
+	networkLC       *linechart.LineChart
+	topologyText    *text.Text
+	cacheText       *text.Text
+	cacheLC         *linechart.LineChart
+	checkpointsLC   *linechart.LineChart
+	connectionsText *text.Text
+	connectionsLC   *linechart.LineChart
+	helpText        *text.Text
+	nodesText       *text.Text
+	compareText     *text.Text
+	compareLC       *linechart.LineChart
+	clusterText     *text.Text
+	clusterRows     []clusterRow
+	latencyText     *text.Text
+	latencyHeatmap  *text.Text
+
+	currentOpStatusText *text.Text
+	currentOpText       *text.Text
 }
 
 // periodic executes the provided closure periodically every interval.
@@ -48,12 +64,6 @@ func periodic(ctx context.Context, interval time.Duration, fn func() error) {
 	}
 }
 
-var metricsSlice metricHelper.MetricsSlice
-
-func UpdateMetricsSlice(ms metricHelper.MetricsSlice) {
-	metricsSlice = ms
-}
-
 func extractOpcounters() (
 	[]float64,
 	[]float64,
@@ -62,27 +72,30 @@ func extractOpcounters() (
 	[]float64,
 	[]float64,
 	map[int]string,
+	[]time.Time,
 ) {
-	var sortedMS metricHelper.MetricsSlice
-	if metricsSlice == nil {
-		return []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, map[int]string{}
-	} else {
-		sortedMS = make(metricHelper.MetricsSlice, len(metricsSlice))
-		copy(sortedMS, metricsSlice)
-	}
-
-	insertCountSlice := make([]float64, 50)
-	queryCountSlice := make([]float64, 50)
-	updateCountSlice := make([]float64, 50)
-	deleteCountSlice := make([]float64, 50)
-	getmoreCountSlice := make([]float64, 50)
-	commandCountSlice := make([]float64, 50)
+	sortedMS, cursor := visibleMetrics()
+	if sortedMS == nil {
+		return []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, map[int]string{}, []time.Time{}
+	}
+	if len(sortedMS) > ChartLength {
+		cursor -= len(sortedMS) - ChartLength
+		sortedMS = sortedMS[len(sortedMS)-ChartLength:]
+	}
+
+	insertCountSlice := make([]float64, ChartLength)
+	queryCountSlice := make([]float64, ChartLength)
+	updateCountSlice := make([]float64, ChartLength)
+	deleteCountSlice := make([]float64, ChartLength)
+	getmoreCountSlice := make([]float64, ChartLength)
+	commandCountSlice := make([]float64, ChartLength)
 	XLabelMap := map[int]string{}
+	times := make([]time.Time, ChartLength)
 	index := 0
-	for i := 0; i < 50; i++ {
+	for i := 0; i < ChartLength; i++ {
 		XLabelMap[i] = "-"
 	}
-	for i := 50 - len(sortedMS); i < 50; i++ {
+	for i := ChartLength - len(sortedMS); i < ChartLength; i++ {
 		insertCountSlice[i] = sortedMS[index].InsertCountPerSecond
 		queryCountSlice[i] = sortedMS[index].QueryCountPerSecond
 		updateCountSlice[i] = sortedMS[index].UpdateCountPerSecond
@@ -90,9 +103,13 @@ func extractOpcounters() (
 		getmoreCountSlice[i] = sortedMS[index].GetmoreCountPerSecond
 		commandCountSlice[i] = sortedMS[index].CommandCountPerSecond
 		XLabelMap[i] = sortedMS[index].EndTime.Format("15:04:05")
+		if index == cursor {
+			XLabelMap[i] = "[" + XLabelMap[i] + "]"
+		}
+		times[i] = sortedMS[index].EndTime
 		index++
 	}
-	return insertCountSlice, queryCountSlice, updateCountSlice, deleteCountSlice, getmoreCountSlice, commandCountSlice, XLabelMap
+	return insertCountSlice, queryCountSlice, updateCountSlice, deleteCountSlice, getmoreCountSlice, commandCountSlice, XLabelMap, times
 }
 
 // newOpcountersLc returns a line chart that displays opcounters line chart.
@@ -106,8 +123,8 @@ func newOpcountersLc(ctx context.Context) (*linechart.LineChart, error) {
 	if err != nil {
 		return nil, err
 	}
-	go periodic(ctx, redrawInterval/3, func() error {
-		i, q, u, d, g, c, XLabelMap := extractOpcounters()
+	go redraw(ctx, lc, func() error {
+		i, q, u, d, g, c, XLabelMap, times := extractOpcounters()
 		err := lc.Series("insert", i,
 			linechart.SeriesCellOpts(cell.FgColor(cell.ColorNumber(111))),
 			linechart.SeriesXLabels(XLabelMap),
@@ -150,7 +167,36 @@ func newOpcountersLc(ctx context.Context) (*linechart.LineChart, error) {
 		if err != nil {
 			return err
 		}
-		return nil
+
+		// The series are drawn in the order of their names, "~" sorts the
+		// highlights after the opcounters so they are drawn over them.
+		anomalies := getAnomalies()
+		highlights := []struct {
+			name   string
+			metric string
+			values []float64
+		}{
+			{"~insert", "insert_per_second", i},
+			{"~query", "query_per_second", q},
+			{"~update", "update_per_second", u},
+			{"~delete", "delete_per_second", d},
+			{"~getmore", "getmore_per_second", g},
+			{"~command", "command_per_second", c},
+		}
+		for _, h := range highlights {
+			err = lc.Series(h.name, anomalyHighlight(anomalies, h.metric, h.values, times),
+				linechart.SeriesCellOpts(cell.FgColor(cell.ColorNumber(196))),
+				linechart.SeriesXLabels(XLabelMap),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		// "~~" sorts the events after the highlights.
+		return lc.Series("~~events", eventMarks(times, maxValue(i, q, u, d, g, c)),
+			linechart.SeriesCellOpts(cell.FgColor(eventMarkColor)),
+			linechart.SeriesXLabels(XLabelMap),
+		)
 	})
 	return lc, nil
 }
@@ -162,7 +208,7 @@ func newMongostatUIText(ctx context.Context) (*text.Text, error) {
 		return nil, err
 	}
 	if err := t.Write(
-		fmt.Sprintf("Press Esc/Q/Ctrl-C to quit\n"),
+		fmt.Sprintf("1-%d/Tab: pages  ?: help\nEsc/Q/Ctrl-C: quit\n", len(pages)),
 		text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
 	); err != nil {
 		return nil, err
@@ -171,48 +217,247 @@ func newMongostatUIText(ctx context.Context) (*text.Text, error) {
 	return t, nil
 }
 
-// newOpcountersText returns a text block that displays the infomation of opcounters line chart.
+// legendLine is a line of the legend, the statistics of a metric over the visible window.
+type legendLine struct {
+	unit   string
+	label  string
+	value  func(m metricHelper.Metrics) float64
+	format func(float64) string
+	color  cell.Color
+}
+
+// legendLines are the lines of the legend, the opcounters in the colors of their
+// series then the rates that are not charted.
+var legendLines = []legendLine{
+	{"ops/s", "INSERT", func(m metricHelper.Metrics) float64 { return m.InsertCountPerSecond }, shortRate, cell.ColorNumber(111)},
+	{"ops/s", "QUERY", func(m metricHelper.Metrics) float64 { return m.QueryCountPerSecond }, shortRate, cell.ColorNumber(172)},
+	{"ops/s", "UPDATE", func(m metricHelper.Metrics) float64 { return m.UpdateCountPerSecond }, shortRate, cell.ColorNumber(107)},
+	{"ops/s", "DELETE", func(m metricHelper.Metrics) float64 { return m.DeleteCountPerSecond }, shortRate, cell.ColorNumber(161)},
+	{"ops/s", "GETMORE", func(m metricHelper.Metrics) float64 { return m.GetmoreCountPerSecond }, shortRate, cell.ColorNumber(245)},
+	{"ops/s", "COMMAND", func(m metricHelper.Metrics) float64 { return m.CommandCountPerSecond }, shortRate, cell.ColorNumber(135)},
+	{"bytes/s", "IN", func(m metricHelper.Metrics) float64 { return m.NetworkInBytesPerSecond }, shortBytes, cell.ColorNumber(250)},
+	{"bytes/s", "OUT", func(m metricHelper.Metrics) float64 { return m.NetworkOutBytesPerSecond }, shortBytes, cell.ColorNumber(250)},
+	{"ckpt/s", "CHECKPT", func(m metricHelper.Metrics) float64 { return m.CheckpointCountPerSecond }, shortRate, cell.ColorNumber(250)},
+}
+
+// shortRate formats a rate in at most 6 characters with a decimal unit.
+func shortRate(v float64) string {
+	return shortUnit(v, 1000, []string{"", "k", "M", "G"})
+}
+
+// shortBytes formats a number of bytes in at most 6 characters with a binary unit.
+func shortBytes(v float64) string {
+	return shortUnit(v, 1024, []string{"B", "KB", "MB", "GB"})
+}
+
+// shortUnit divides v by base until it is below base, and formats it with its unit.
+func shortUnit(v float64, base float64, units []string) string {
+	i := 0
+	for v >= base && i < len(units)-1 {
+		v /= base
+		i++
+	}
+	if v >= 100 {
+		return fmt.Sprintf("%.0f%s", v, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", v, units[i])
+}
+
+// newOpcountersText returns a text block that displays the current, or the one at the
+// cursor, min, max and average per second of the opcounters and the other rates over
+// the visible window.
 func newOpcountersText(ctx context.Context) (*text.Text, error) {
 	t, err := text.New()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := t.Write(fmt.Sprintf("INSERT  .......\n"), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111)))); err != nil {
+	go redraw(ctx, t, func() error {
+		ms, cursor := visibleMetrics()
+		now := "NOW"
+		if cursor >= 0 {
+			now = "AT"
+		} else {
+			cursor = len(ms) - 1
+		}
+
+		t.Reset()
+		if err := t.Write(historyStatus()+"\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(107)))); err != nil {
+			return err
+		}
+		unit := ""
+		for _, line := range legendLines {
+			if line.unit != unit {
+				unit = line.unit
+				if err := t.Write(
+					fmt.Sprintf("%-8s%7s%7s%7s%7s\n", unit, now, "MIN", "MAX", "AVG"),
+					text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+				); err != nil {
+					return err
+				}
+			}
+			row := fmt.Sprintf("%-8s%7s%7s%7s%7s\n", line.label, "-", "-", "-", "-")
+			if len(ms) > 0 {
+				min, max, sum := math.Inf(1), math.Inf(-1), 0.0
+				for _, m := range ms {
+					v := line.value(m)
+					min = math.Min(min, v)
+					max = math.Max(max, v)
+					sum += v
+				}
+				row = fmt.Sprintf(
+					"%-8s%7s%7s%7s%7s\n",
+					line.label,
+					line.format(line.value(ms[cursor])),
+					line.format(min),
+					line.format(max),
+					line.format(sum/float64(len(ms))),
+				)
+			}
+			if err := t.Write(row, text.WriteCellOpts(cell.FgColor(line.color))); err != nil {
+				return err
+			}
+		}
+		return t.Write("ANOMALY\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(196))))
+	})
+
+	return t, nil
+}
+
+// newWidgets creates all widgets used by this demo.
+func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
+	mongostatUIText, err := newMongostatUIText(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opcountersLC, err := newOpcountersLc(ctx)
+	if err != nil {
 		return nil, err
 	}
-	if err := t.Write(fmt.Sprintf("QUERY   .......\n"), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)))); err != nil {
+
+	opcountersText, err := newOpcountersText(ctx)
+	if err != nil {
 		return nil, err
 	}
-	if err := t.Write(fmt.Sprintf("UPDATE  .......\n"), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(107)))); err != nil {
+
+	oplogText, err := newOplogText(ctx)
+	if err != nil {
 		return nil, err
 	}
-	if err := t.Write(fmt.Sprintf("DELETE  .......\n"), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(161)))); err != nil {
+
+	hotText, err := newHotCollectionsText(ctx)
+	if err != nil {
 		return nil, err
 	}
-	if err := t.Write(fmt.Sprintf("GETMORE .......\n"), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(245)))); err != nil {
+
+	alertsText, err := newAlertsText(ctx)
+	if err != nil {
 		return nil, err
 	}
-	if err := t.Write(fmt.Sprintf("COMMAND .......\n"), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(135)))); err != nil {
+
+	eventsText, err := newEventsText(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	return t, nil
-}
+	networkLC, err := newValuesLc(ctx, []valuesSeries{
+		{"in", "network_in_bytes_per_second", cell.ColorNumber(111)},
+		{"out", "network_out_bytes_per_second", cell.ColorNumber(172)},
+	})
+	if err != nil {
+		return nil, err
+	}
 
-// newWidgets creates all widgets used by this demo.
-func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
-	mongostatUIText, err := newMongostatUIText(ctx)
+	topologyText, err := newTopologyText(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	opcountersLC, err := newOpcountersLc(ctx)
+	cacheText, err := newValuesText(ctx, []valuesLine{
+		{"USED", "wt_cache_bytes", shortBytes, cell.ColorNumber(111)},
+		{"USED %", "wt_cache_used_pct", formatPercent, cell.ColorNumber(111)},
+		{"DIRTY", "wt_cache_dirty_bytes", shortBytes, cell.ColorNumber(172)},
+		{"DIRTY %", "wt_cache_dirty_pct", formatPercent, cell.ColorNumber(172)},
+		{"MAX", "wt_cache_max_bytes", shortBytes, cell.ColorNumber(245)},
+		{"CHECKPOINT/s", "checkpoint_per_second", formatCount, cell.ColorNumber(107)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	opcountersText, err := newOpcountersText(ctx)
+	cacheLC, err := newValuesLc(ctx, []valuesSeries{
+		{"used", "wt_cache_used_pct", cell.ColorNumber(111)},
+		{"dirty", "wt_cache_dirty_pct", cell.ColorNumber(172)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkpointsLC, err := newValuesLc(ctx, []valuesSeries{
+		{"checkpoint", "checkpoint_per_second", cell.ColorNumber(107)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	connectionsText, err := newValuesText(ctx, []valuesLine{
+		{"CURRENT", "connections_current", formatCount, cell.ColorNumber(111)},
+		{"AVAILABLE", "connections_available", formatCount, cell.ColorNumber(107)},
+		{"USED %", "connections_used_pct", formatPercent, cell.ColorNumber(172)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	connectionsLC, err := newValuesLc(ctx, []valuesSeries{
+		{"current", "connections_current", cell.ColorNumber(111)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	helpText, err := newHelpText(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodesText, err := newNodesText(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	compareText, err := newCompareText(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	compareLC, err := newCompareLc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterText, clusterRows, err := newClusterWidgets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latencyText, err := newLatencyText(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latencyHeatmap, err := newLatencyHeatmap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currentOpStatusText, err := newCurrentOpStatusText(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currentOpText, err := newCurrentOpText(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -221,47 +466,88 @@ func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
 		mongostatUIText: mongostatUIText,
 		opcountersLC:    opcountersLC,
 		opcountersText:  opcountersText,
-	}, nil
-}
+		oplogText:       oplogText,
+		hotText:         hotText,
+		alertsText:      alertsText,
+		eventsText:      eventsText,
+		networkLC:       networkLC,
+		topologyText:    topologyText,
+		cacheText:       cacheText,
+		cacheLC:         cacheLC,
+		checkpointsLC:   checkpointsLC,
+		connectionsText: connectionsText,
+		connectionsLC:   connectionsLC,
+		helpText:        helpText,
+		nodesText:       nodesText,
+		compareText:     compareText,
+		compareLC:       compareLC,
+		clusterText:     clusterText,
+		clusterRows:     clusterRows,
+		latencyText:     latencyText,
+		latencyHeatmap:  latencyHeatmap,
 
-func getLayoutOpts(w *widgets) ([]container.Option, error) {
-	return []container.Option{
-		container.SplitHorizontal(
-			container.Top(
-				container.PlaceWidget(w.mongostatUIText),
-				container.Border(linestyle.Light),
-				container.BorderTitle("Welcome to Mongostat UI"),
-				container.BorderTitleAlignCenter(),
-			),
-			container.Bottom(
-				container.SplitVertical(
-					container.Left(
-						container.PlaceWidget(w.opcountersText),
-						container.Border(linestyle.Light),
-						container.BorderTitle("Lines"),
-						container.BorderTitleAlignCenter(),
-					),
-					container.Right(
-						container.PlaceWidget(w.opcountersLC),
-						container.Border(linestyle.Light),
-						container.BorderTitle("Opcounters Line Chart"),
-						container.BorderTitleAlignCenter(),
-					),
-					container.SplitPercent(15),
-				),
-			),
-			container.SplitPercent(12),
-		),
+		currentOpStatusText: currentOpStatusText,
+		currentOpText:       currentOpText,
 	}, nil
 }
 
 const rootID = "root"
 
-// redrawInterval is how often termdash redraws the screen.
-var redrawInterval = 500 * time.Microsecond
+// redrawInterval is how often termdash redraws the screen. The keys redraw it at
+// once, the periodic redraw only has to follow the samples.
+var redrawInterval = 250 * time.Millisecond
 
 // Render is starting the mongostat UI on terminal
 func Render(parentCtx context.Context) {
+	renderPages(parentCtx, nil, nil)
+}
+
+// renderPages draws the pages on terminal, starting with the first one. customize may
+// replace some of the widgets, and onKey gets the keys before the page navigation.
+func renderPages(
+	parentCtx context.Context,
+	customize func(ctx context.Context, w *widgets) error,
+	onKey func(k *terminalapi.Keyboard) bool,
+) {
+	n := &navigator{}
+	run(parentCtx, func(ctx context.Context, c *container.Container) ([]container.Option, error) {
+		w, err := newWidgets(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if customize != nil {
+			if err := customize(ctx, w); err != nil {
+				return nil, err
+			}
+		}
+		n.c, n.w = c, w
+		// The layout depends on the nodes, the navigator draws it.
+		go periodic(ctx, redrawInterval/3, n.refresh)
+		return nil, nil
+	}, func(k *terminalapi.Keyboard) bool {
+		if onKey != nil && onKey(k) {
+			return true
+		}
+		// The keys of the operations, like the filter being typed, and the paging of the
+		// nodes only apply to their page.
+		if n.showsPage("Operations") && currentOps.handleKey(k) {
+			return true
+		}
+		if n.showsPage("Cluster") && cluster.handleKey(k) {
+			return true
+		}
+		return history.handleKey(k) || nodes.handleKey(k) || latency.handleKey(k) || n.handleKey(k)
+	})
+}
+
+// run draws the layout built by newLayout on terminal until the context expires or
+// the user quits. Keys are passed to onKey first when it is not nil, the quit keys
+// are ignored when onKey reports the key as handled.
+func run(
+	parentCtx context.Context,
+	newLayout func(ctx context.Context, c *container.Container) ([]container.Option, error),
+	onKey func(k *terminalapi.Keyboard) bool,
+) {
 	t, err := termbox.New(termbox.ColorMode(terminalapi.ColorMode256))
 	if err != nil {
 		panic(err)
@@ -276,12 +562,7 @@ func Render(parentCtx context.Context) {
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 
-	w, err := newWidgets(ctx, c)
-	if err != nil {
-		panic(err)
-	}
-
-	layoutOpts, err := getLayoutOpts(w)
+	layoutOpts, err := newLayout(ctx, c)
 	if err != nil {
 		panic(err)
 	}
@@ -291,6 +572,11 @@ func Render(parentCtx context.Context) {
 	}
 
 	quitter := func(k *terminalapi.Keyboard) {
+		// The keys change what is drawn, once they are handled.
+		defer changed()
+		if onKey != nil && onKey(k) {
+			return
+		}
 		if k.Key == keyboard.KeyEsc || k.Key == keyboard.KeyCtrlC || k.Key.String() == "q" {
 			cancel()
 		}
@@ -0,0 +1,170 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+type indexSortMode int
+
+const (
+	sortIndexesBySize indexSortMode = iota
+	sortIndexesByOps
+	sortIndexesByUnused
+)
+
+var indexMetricsSlice metricHelper.IndexMetricsSlice
+var indexMutex sync.Mutex
+var indexSort = sortIndexesByUnused
+var indexUnusedSince = 7 * 24 * time.Hour
+
+// UpdateIndexMetricsSlice sets the index metrics displayed on the indexes table.
+func UpdateIndexMetricsSlice(ms metricHelper.IndexMetricsSlice) {
+	indexMutex.Lock()
+	indexMetricsSlice = ms
+	indexMutex.Unlock()
+	changed()
+}
+
+// SetIndexUnusedSince sets the period without accesses after which an index is displayed as unused.
+func SetIndexUnusedSince(d time.Duration) {
+	indexUnusedSince = d
+}
+
+func sortedIndexMetrics(since time.Time) metricHelper.IndexMetricsSlice {
+	indexMutex.Lock()
+	ms := make(metricHelper.IndexMetricsSlice, len(indexMetricsSlice))
+	copy(ms, indexMetricsSlice)
+	mode := indexSort
+	indexMutex.Unlock()
+
+	switch mode {
+	case sortIndexesByOps:
+		ms.SortByOps()
+	case sortIndexesByUnused:
+		ms.SortBySize()
+		sort.SliceStable(ms, func(i, j int) bool {
+			return ms[i].Unused(since) && !ms[j].Unused(since)
+		})
+	default:
+		ms.SortBySize()
+	}
+	return ms
+}
+
+// newIndexesText returns a text block that displays the indexes table.
+func newIndexesText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		since := time.Now().Add(-indexUnusedSince)
+		ms := sortedIndexMetrics(since)
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf(
+				"%-56s %12s %14s %12s %-20s\n",
+				"INDEX", "SIZE", "OPS", "OPS/H", "IDLE SINCE",
+			),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for _, m := range ms {
+			color := cell.ColorNumber(111)
+			if m.Unused(since) {
+				color = cell.ColorNumber(161)
+			}
+			if err := t.Write(
+				fmt.Sprintf(
+					"%-56s %12s %14.0f %12.2f %-20s\n",
+					m.ID(),
+					metricHelper.FormatBytes(m.SizeBytes),
+					m.Ops,
+					m.OpsPerHour,
+					m.IdleSince.Format("2006-01-02 15:04:05"),
+				),
+				text.WriteCellOpts(cell.FgColor(color)),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
+
+// newIndexesHelpText returns a text block that displays the key bindings of the indexes table.
+func newIndexesHelpText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write(
+		fmt.Sprintf(
+			"Unused (red) = no access for %s. Press u to sort by unused, s by size, o by ops, Esc/Q/Ctrl-C to quit\n",
+			indexUnusedSince,
+		),
+		text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// RenderIndexes is starting the index usage UI on terminal
+func RenderIndexes(parentCtx context.Context) {
+	run(parentCtx, func(ctx context.Context, c *container.Container) ([]container.Option, error) {
+		helpText, err := newIndexesHelpText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		indexesText, err := newIndexesText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.PlaceWidget(helpText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Index Usage"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.Bottom(
+					container.PlaceWidget(indexesText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Indexes"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.SplitPercent(10),
+			),
+		}, nil
+	}, func(k *terminalapi.Keyboard) bool {
+		indexMutex.Lock()
+		defer indexMutex.Unlock()
+		switch k.Key.String() {
+		case "u":
+			indexSort = sortIndexesByUnused
+		case "s":
+			indexSort = sortIndexesBySize
+		case "o":
+			indexSort = sortIndexesByOps
+		}
+		return false
+	})
+}
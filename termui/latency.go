@@ -0,0 +1,277 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// latencyColors are the colors of the cells of the heatmap, from the fewest
+// operations to the most.
+var latencyColors = []cell.Color{
+	cell.ColorNumber(24),
+	cell.ColorNumber(31),
+	cell.ColorNumber(38),
+	cell.ColorNumber(44),
+	cell.ColorNumber(78),
+	cell.ColorNumber(154),
+	cell.ColorNumber(220),
+	cell.ColorNumber(208),
+	cell.ColorNumber(202),
+	cell.ColorNumber(196),
+}
+
+// latencyOpColors are the colors of the kinds of operations, in the order of
+// metricHelper.LatencyOps.
+var latencyOpColors = []cell.Color{
+	cell.ColorNumber(111),
+	cell.ColorNumber(172),
+	cell.ColorNumber(135),
+}
+
+type latencyState struct {
+	// op is the index in metricHelper.LatencyOps of the operations on the heatmap.
+	op    int
+	mutex sync.Mutex
+}
+
+var latency = latencyState{}
+
+// handleKey shows the next kind of operations on the heatmap on l. It reports whether
+// the key was consumed.
+func (s *latencyState) handleKey(k *terminalapi.Keyboard) bool {
+	if k.Key.String() != "l" {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.op = (s.op + 1) % len(metricHelper.LatencyOps)
+	return true
+}
+
+// selectedOp returns the index of the operations on the heatmap.
+func (s *latencyState) selectedOp() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.op
+}
+
+// visibleLatencies returns the last ChartLength visible metrics and the index of the
+// cursor among them, -1 when there is no cursor.
+func visibleLatencies() (metricHelper.MetricsSlice, int) {
+	ms, cursor := visibleMetrics()
+	last := lastSamples(ms)
+	if cursor >= 0 {
+		cursor -= len(ms) - len(last)
+	}
+	return last, cursor
+}
+
+// newLatencyText returns a text block that displays the operations per second and
+// the percentiles of every kind of operations, over the visible window or at the cursor.
+func newLatencyText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		ms, cursor := visibleLatencies()
+		selected := latency.selectedOp()
+
+		t.Reset()
+		if err := t.Write(historyStatus()+"\n\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(245)))); err != nil {
+			return err
+		}
+		if cursor >= 0 && cursor < len(ms) {
+			ms = ms[cursor : cursor+1]
+		}
+		if err := t.Write(
+			fmt.Sprintf("%-10s %6s %6s %6s %6s\n", "", "OPS/S", "P50", "P95", "P99"),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for i, op := range metricHelper.LatencyOps {
+			h := sumLatencies(ms, op)
+			marker := "  "
+			if i == selected {
+				marker = "> "
+			}
+			line := fmt.Sprintf("%s%-8s %6s %6s %6s %6s\n", marker, op, "-", "-", "-", "-")
+			if total := h.Total(); total > 0 {
+				n := float64(len(ms))
+				line = fmt.Sprintf(
+					"%s%-8s %6s %6s %6s %6s\n",
+					marker, op, shortRate(total/n),
+					metricHelper.FormatLatency(h.Percentile(50)),
+					metricHelper.FormatLatency(h.Percentile(95)),
+					metricHelper.FormatLatency(h.Percentile(99)),
+				)
+			}
+			if err := t.Write(line, text.WriteCellOpts(cell.FgColor(latencyOpColors[i]))); err != nil {
+				return err
+			}
+		}
+
+		if err := t.Write("\nfew ", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222)))); err != nil {
+			return err
+		}
+		for _, color := range latencyColors {
+			if err := t.Write("█", text.WriteCellOpts(cell.FgColor(color))); err != nil {
+				return err
+			}
+		}
+		return t.Write(
+			" many ops\n\nThe percentiles are the upper\nbounds of their buckets.\n\nl next operations\n",
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		)
+	})
+
+	return t, nil
+}
+
+// sumLatencies returns the sum of the histograms of op in ms.
+func sumLatencies(ms metricHelper.MetricsSlice, op string) metricHelper.LatencyHistogram {
+	var sum metricHelper.LatencyHistogram
+	for _, m := range ms {
+		sum = sum.Add(m.Latencies[op])
+	}
+	return sum
+}
+
+// newLatencyHeatmap returns a text block that draws the latencies of the selected
+// operations as a heatmap: a column per sample, a line per latency bucket, the
+// slowest on top, colored by the number of operations.
+func newLatencyHeatmap(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("Waiting for the first sample...\n"); err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		ms, cursor := visibleLatencies()
+		if len(ms) == 0 {
+			return nil
+		}
+		i := latency.selectedOp()
+		op := metricHelper.LatencyOps[i]
+
+		// The buckets between the fastest and the slowest operations are drawn.
+		low, high, max := -1, -1, 0.0
+		for _, m := range ms {
+			for b, ops := range m.Latencies[op] {
+				if ops <= 0 {
+					continue
+				}
+				if low < 0 || b < low {
+					low = b
+				}
+				if b > high {
+					high = b
+				}
+				max = math.Max(max, ops)
+			}
+		}
+
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf("%s latency by sample\n", op),
+			text.WriteCellOpts(cell.FgColor(latencyOpColors[i])),
+		); err != nil {
+			return err
+		}
+		if low < 0 {
+			return t.Write(
+				"No latency histogram in the samples, mongod reports them from 3.2 on.\n",
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(245))),
+			)
+		}
+		offset := ChartLength - len(ms)
+		for b := high; b >= low; b-- {
+			if err := t.Write(
+				fmt.Sprintf("%8s ", metricHelper.FormatLatency(metricHelper.LatencyBucketMicros(b))),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+			); err != nil {
+				return err
+			}
+			// The cells are written by runs of the same color.
+			run, runColor := strings.Repeat(" ", offset), -1
+			for _, m := range ms {
+				h := m.Latencies[op]
+				color := -1
+				if b < len(h) && h[b] > 0 {
+					color = latencyLevel(h[b], max)
+				}
+				if color != runColor {
+					if err := writeLatencyRun(t, run, runColor); err != nil {
+						return err
+					}
+					run, runColor = "", color
+				}
+				if color < 0 {
+					run += " "
+				} else {
+					run += "█"
+				}
+			}
+			if err := writeLatencyRun(t, run+"\n", runColor); err != nil {
+				return err
+			}
+		}
+
+		// The times of the first and the last samples, and the cursor below its sample.
+		axis := []rune(strings.Repeat(" ", ChartLength))
+		first, last := ms[0].EndTime.Format("15:04:05"), ms[len(ms)-1].EndTime.Format("15:04:05")
+		if len(ms) > 2*len(last) {
+			copy(axis[offset:], []rune(first))
+		}
+		copy(axis[ChartLength-len(last):], []rune(last))
+		line := fmt.Sprintf("%8s %s\n", "", string(axis))
+		if cursor >= 0 && cursor < len(ms) {
+			marker := []rune(strings.Repeat(" ", ChartLength))
+			marker[offset+cursor] = '^'
+			line += fmt.Sprintf("%8s %s\n", "", string(marker))
+		}
+		return t.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))))
+	})
+
+	return t, nil
+}
+
+// latencyLevel returns the index in latencyColors of a cell of ops operations, max
+// being the most operations of a cell. The levels are logarithmic, so the few slow
+// operations stand out beside the many fast ones.
+func latencyLevel(ops float64, max float64) int {
+	level := int(math.Ceil(math.Log1p(ops)/math.Log1p(max)*float64(len(latencyColors)))) - 1
+	if level < 0 {
+		return 0
+	}
+	if level >= len(latencyColors) {
+		return len(latencyColors) - 1
+	}
+	return level
+}
+
+// writeLatencyRun writes cells of the heatmap in the color of level, in the default
+// color when level is negative.
+func writeLatencyRun(t *text.Text, run string, level int) error {
+	if run == "" {
+		return nil
+	}
+	if level < 0 {
+		return t.Write(run)
+	}
+	return t.Write(run, text.WriteCellOpts(cell.FgColor(latencyColors[level])))
+}
@@ -0,0 +1,369 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// layout is either a panel showing a widget, or a split of the space between two layouts.
+type layout struct {
+	title  string
+	widget func(w *widgets) widgetapi.Widget
+	// bare panels have no border, for the small widgets packed on a line.
+	bare bool
+
+	vertical bool
+	percent  int
+	first    *layout
+	second   *layout
+}
+
+// panel returns a layout that shows the widget picked from the widgets in a titled border.
+func panel(title string, widget func(w *widgets) widgetapi.Widget) *layout {
+	return &layout{title: title, widget: widget}
+}
+
+// bare returns a layout that shows the widget picked from the widgets without a border.
+func bare(widget func(w *widgets) widgetapi.Widget) *layout {
+	return &layout{widget: widget, bare: true}
+}
+
+// rows returns a layout that gives percent of the height to top and the rest to bottom.
+func rows(percent int, top *layout, bottom *layout) *layout {
+	return &layout{percent: percent, first: top, second: bottom}
+}
+
+// columns returns a layout that gives percent of the width to left and the rest to right.
+func columns(percent int, left *layout, right *layout) *layout {
+	return &layout{vertical: true, percent: percent, first: left, second: right}
+}
+
+// shown returns the widgets of w the layout shows.
+func (l *layout) shown(w *widgets) []widgetapi.Widget {
+	if l.widget != nil {
+		return []widgetapi.Widget{l.widget(w)}
+	}
+	return append(l.first.shown(w), l.second.shown(w)...)
+}
+
+// options returns the container options drawing the layout with the widgets.
+func (l *layout) options(w *widgets) []container.Option {
+	if l.widget != nil && l.bare {
+		return []container.Option{container.PlaceWidget(l.widget(w))}
+	}
+	if l.widget != nil {
+		return []container.Option{
+			container.PlaceWidget(l.widget(w)),
+			container.Border(linestyle.Light),
+			container.BorderTitle(l.title),
+			container.BorderTitleAlignCenter(),
+		}
+	}
+	if l.vertical {
+		return []container.Option{
+			container.SplitVertical(
+				container.Left(l.first.options(w)...),
+				container.Right(l.second.options(w)...),
+				container.SplitPercent(l.percent),
+			),
+		}
+	}
+	return []container.Option{
+		container.SplitHorizontal(
+			container.Top(l.first.options(w)...),
+			container.Bottom(l.second.options(w)...),
+			container.SplitPercent(l.percent),
+		),
+	}
+}
+
+// page is a layout of the UI, the pages are switched with the number keys and Tab.
+type page struct {
+	name        string
+	description string
+	layout      *layout
+}
+
+// withHeader returns a layout with the key bindings, the alerts and the events above body.
+func withHeader(name string, body *layout) *layout {
+	return rows(15,
+		columns(25,
+			panel(name, func(w *widgets) widgetapi.Widget { return w.mongostatUIText }),
+			columns(50,
+				panel("Alerts", func(w *widgets) widgetapi.Widget { return w.alertsText }),
+				panel("Events", func(w *widgets) widgetapi.Widget { return w.eventsText }),
+			),
+		),
+		body,
+	)
+}
+
+// pages are the pages of the UI, in the order of their keys. A page is added by
+// appending it here, the widgets it shows are created by newWidgets.
+var pages = []page{
+	{
+		name:        "Overview",
+		description: "the opcounters, the alerts, the events, the oplog and the hottest collections",
+		layout: rows(15,
+			columns(25,
+				panel("Overview", func(w *widgets) widgetapi.Widget { return w.mongostatUIText }),
+				columns(40,
+					panel("Alerts", func(w *widgets) widgetapi.Widget { return w.alertsText }),
+					columns(55,
+						panel("Events", func(w *widgets) widgetapi.Widget { return w.eventsText }),
+						panel("Oplog", func(w *widgets) widgetapi.Widget { return w.oplogText }),
+					),
+				),
+			),
+			columns(25,
+				panel("Lines", func(w *widgets) widgetapi.Widget { return w.opcountersText }),
+				rows(65,
+					panel("Opcounters Line Chart", func(w *widgets) widgetapi.Widget { return w.opcountersLC }),
+					panel("Hottest Collections", func(w *widgets) widgetapi.Widget { return w.hotText }),
+				),
+			),
+		),
+	},
+	{
+		name:        "Opcounters",
+		description: "the operations and the network traffic per second",
+		layout: withHeader("Opcounters", rows(60,
+			columns(25,
+				panel("Lines", func(w *widgets) widgetapi.Widget { return w.opcountersText }),
+				panel("Opcounters Line Chart", func(w *widgets) widgetapi.Widget { return w.opcountersLC }),
+			),
+			panel("Network (bytes/s)", func(w *widgets) widgetapi.Widget { return w.networkLC }),
+		)),
+	},
+	{
+		name:        "Replication",
+		description: "the oplog window and the members of the replica set",
+		layout: withHeader("Replication", rows(30,
+			panel("Oplog", func(w *widgets) widgetapi.Widget { return w.oplogText }),
+			panel("Replica Set", func(w *widgets) widgetapi.Widget { return w.topologyText }),
+		)),
+	},
+	{
+		name:        "WiredTiger",
+		description: "the cache usage and the checkpoints",
+		layout: withHeader("WiredTiger", rows(55,
+			columns(25,
+				panel("Cache", func(w *widgets) widgetapi.Widget { return w.cacheText }),
+				panel("Cache Used/Dirty (%)", func(w *widgets) widgetapi.Widget { return w.cacheLC }),
+			),
+			panel("Checkpoints/s", func(w *widgets) widgetapi.Widget { return w.checkpointsLC }),
+		)),
+	},
+	{
+		name:        "Connections",
+		description: "the current and the available connections",
+		layout: withHeader("Connections", columns(25,
+			panel("Connections", func(w *widgets) widgetapi.Widget { return w.connectionsText }),
+			panel("Current Connections", func(w *widgets) widgetapi.Widget { return w.connectionsLC }),
+		)),
+	},
+	{
+		name:        "Operations",
+		description: "the operations running right now, to sort, filter and kill",
+		layout: withHeader("Operations", rows(15,
+			panel("Current Operations", func(w *widgets) widgetapi.Widget { return w.currentOpStatusText }),
+			panel("Operations", func(w *widgets) widgetapi.Widget { return w.currentOpText }),
+		)),
+	},
+	{
+		name:        "Compare",
+		description: "a metric of several nodes on the same chart",
+		layout: withHeader("Compare", columns(20,
+			panel("Compared Metric", func(w *widgets) widgetapi.Widget { return w.compareText }),
+			panel("Compare Line Chart", func(w *widgets) widgetapi.Widget { return w.compareLC }),
+		)),
+	},
+	{
+		name:        "Cluster",
+		description: "the nodes, the worst first, with their ops/s, connections, lag and dirty cache",
+		layout: withHeader("Cluster", rows(10,
+			panel("Cluster", func(w *widgets) widgetapi.Widget { return w.clusterText }),
+			clusterRowsLayout(0),
+		)),
+	},
+	{
+		name:        "Latency",
+		description: "a heatmap of the latencies of the reads, the writes or the commands",
+		layout: withHeader("Latency", columns(30,
+			panel("Latency", func(w *widgets) widgetapi.Widget { return w.latencyText }),
+			panel("Latency Heatmap", func(w *widgets) widgetapi.Widget { return w.latencyHeatmap }),
+		)),
+	},
+}
+
+// clusterRowsLayout returns the rows of the cluster page from the row i, they share
+// the height equally.
+func clusterRowsLayout(i int) *layout {
+	if i == clusterSlots-1 {
+		return clusterRowLayout(i)
+	}
+	return rows(100/(clusterSlots-i), clusterRowLayout(i), clusterRowsLayout(i+1))
+}
+
+// clusterRowLayout returns the row i of the cluster page, the node and its sparklines.
+func clusterRowLayout(i int) *layout {
+	spark := func(j int) *layout {
+		return bare(func(w *widgets) widgetapi.Widget { return w.clusterRows[i].sparks[j] })
+	}
+	return columns(25,
+		bare(func(w *widgets) widgetapi.Widget { return w.clusterRows[i].text }),
+		columns(25, spark(0), columns(33, spark(1), columns(50, spark(2), spark(3)))),
+	)
+}
+
+// nodesLayout is the sidebar listing the nodes, shown beside the pages when there are
+// several nodes.
+var nodesLayout = panel("Nodes", func(w *widgets) widgetapi.Widget { return w.nodesText })
+
+// helpLayout is the layout of the help overlay.
+var helpLayout = panel("Help", func(w *widgets) widgetapi.Widget { return w.helpText })
+
+// navigator switches the root container between the pages and the help overlay.
+type navigator struct {
+	c       *container.Container
+	w       *widgets
+	current int
+	help    bool
+	sidebar bool
+	shown   bool
+	mutex   sync.Mutex
+}
+
+// layout returns the layout of the current page, or of the help overlay when it is open.
+func (n *navigator) layout() *layout {
+	if n.help {
+		return helpLayout
+	}
+	if n.sidebar {
+		return columns(15, nodesLayout, pages[n.current].layout)
+	}
+	return pages[n.current].layout
+}
+
+// refresh draws the layout the first time, then again when the sidebar listing the
+// nodes appears because there are several of them.
+func (n *navigator) refresh() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if sidebar := len(nodeHosts()) > 1; sidebar != n.sidebar || !n.shown {
+		n.sidebar = sidebar
+		n.shown = true
+		return n.show()
+	}
+	return nil
+}
+
+// showsPage reports whether the page named name is shown, the help overlay hides it.
+func (n *navigator) showsPage(name string) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return !n.help && pages[n.current].name == name
+}
+
+// show draws the layout of the navigator.
+func (n *navigator) show() error {
+	l := n.layout()
+	// The border of the help overlay stays on the root container when a page
+	// replaces it, the pages are splits that are drawn without one.
+	opts := append([]container.Option{container.Border(linestyle.None)}, l.options(n.w)...)
+	if err := n.c.Update(rootID, opts...); err != nil {
+		return err
+	}
+	redraws.show(l.shown(n.w))
+	return nil
+}
+
+// handleKey switches the page on the number keys and Tab, and opens or closes the
+// help on ?. It reports whether the key was consumed.
+func (n *navigator) handleKey(k *terminalapi.Keyboard) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	current, help := n.current, n.help
+	switch {
+	case k.Key == keyboard.KeyTab:
+		n.current = (n.current + 1) % len(pages)
+		n.help = false
+	case k.Key.String() == "?":
+		n.help = !n.help
+	case k.Key == keyboard.KeyEsc && n.help:
+		n.help = false
+	case k.Key >= '1' && k.Key <= '9' && int(k.Key-'1') < len(pages):
+		n.current = int(k.Key - '1')
+		n.help = false
+	default:
+		return false
+	}
+	if err := n.show(); err != nil {
+		// The page that failed to draw is not shown, the previous one stays.
+		n.current, n.help = current, help
+		n.w.mongostatUIText.Write(
+			fmt.Sprintf("cannot draw the page: %s\n", err),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(161))),
+		)
+	}
+	return true
+}
+
+// newHelpText returns a text block that lists the pages and the key bindings.
+func newHelpText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("Pages\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222)))); err != nil {
+		return nil, err
+	}
+	for i, p := range pages {
+		if err := t.Write(
+			fmt.Sprintf("  %d  %-12s %s\n", i+1, p.name, p.description),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+		); err != nil {
+			return nil, err
+		}
+	}
+	keys := []string{
+		"  Tab          next page",
+		"  ?            open or close this help",
+		"  Esc          close this help",
+		"  p            pause or resume the charts",
+		"  +/-          zoom the charts in or out: last samples, 1m, 5m, 1h, 1d",
+		"  Left/Right   scroll the charts back or forward by half their window",
+		"  [/]          move the cursor showing the values at a time on the Lines panel",
+		"  Up/Down      select the node the charts show",
+		"  c            add the selected node to the compare chart or remove it",
+		"  v            change the metric of the compare chart",
+		"  l            change the operations of the latency heatmap",
+		"  Up/Down/s/k  select, sort or kill the operations, on the Operations page",
+		"  /            filter the operations, on the Operations page",
+		"  PgUp/PgDn    show the previous or the next nodes, on the Cluster page",
+		"  n/Space      next sample, when replaying step by step",
+		"  Esc/Q/Ctrl-C quit",
+	}
+	if err := t.Write("\nKeys\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222)))); err != nil {
+		return nil, err
+	}
+	if err := t.Write(
+		strings.Join(keys, "\n")+"\n",
+		text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+	); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
@@ -0,0 +1,214 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"mongo-monitor/loganalyzer"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/linechart"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+var logReport *loganalyzer.Report
+var logReportMutex sync.Mutex
+
+// UpdateLogReport sets the log analysis displayed on the log analysis UI.
+func UpdateLogReport(report *loganalyzer.Report) {
+	logReportMutex.Lock()
+	logReport = report
+	logReportMutex.Unlock()
+	UpdateMetricsSlice(report.MetricsSlice())
+	UpdateQueryShapes(report.Shapes)
+}
+
+// newLogSummaryText returns a text block that displays the totals of the log analysis.
+func newLogSummaryText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	logReportMutex.Lock()
+	r := logReport
+	logReportMutex.Unlock()
+	if r == nil {
+		return t, nil
+	}
+
+	if err := t.Write(
+		fmt.Sprintf(
+			"%s - %s  (%d lines, buckets of %s)  Press s to change the sort of the query shapes, Esc/Q/Ctrl-C to quit\n",
+			r.FirstTime.Format("2006-01-02 15:04:05"),
+			r.LastTime.Format("2006-01-02 15:04:05"),
+			r.Lines,
+			r.BucketSize,
+		),
+		text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+	); err != nil {
+		return nil, err
+	}
+	if err := t.Write(
+		fmt.Sprintf(
+			"slow operations %d  connections opened %d closed %d peak %.0f  errors %d\n",
+			r.SlowOperations,
+			r.ConnectionsOpened,
+			r.ConnectionsClosed,
+			r.PeakConnections,
+			r.ErrorCount,
+		),
+		text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// newLogEventsText returns a text block that lists the restarts, elections, state changes and errors.
+func newLogEventsText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	logReportMutex.Lock()
+	r := logReport
+	logReportMutex.Unlock()
+	if r == nil {
+		return t, nil
+	}
+
+	for _, event := range r.Events {
+		color := cell.ColorNumber(111)
+		switch event.Kind {
+		case loganalyzer.EventError:
+			color = cell.ColorNumber(161)
+		case loganalyzer.EventElection, loganalyzer.EventRestart:
+			color = cell.ColorNumber(172)
+		}
+		if err := t.Write(
+			fmt.Sprintf("%s %-12s %s\n", event.Time.Format("01-02 15:04:05"), event.Kind, event.Message),
+			text.WriteCellOpts(cell.FgColor(color)),
+		); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// newConnectionsChurnLc returns a line chart that displays the connections opened and closed per bucket.
+func newConnectionsChurnLc(ctx context.Context) (*linechart.LineChart, error) {
+	lc, err := linechart.New(
+		linechart.AxesCellOpts(cell.FgColor(cell.ColorNumber(161))),
+		linechart.YLabelCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		linechart.XLabelCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		linechart.XAxisUnscaled(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	logReportMutex.Lock()
+	r := logReport
+	logReportMutex.Unlock()
+	if r == nil {
+		return lc, nil
+	}
+
+	opened := make([]float64, len(r.Buckets))
+	closed := make([]float64, len(r.Buckets))
+	XLabelMap := map[int]string{}
+	for i, bucket := range r.Buckets {
+		opened[i] = bucket.ConnectionsOpened
+		closed[i] = bucket.ConnectionsClosed
+		XLabelMap[i] = bucket.Start.Format("15:04")
+	}
+	if err := lc.Series("opened", opened,
+		linechart.SeriesCellOpts(cell.FgColor(cell.ColorNumber(107))),
+		linechart.SeriesXLabels(XLabelMap),
+	); err != nil {
+		return nil, err
+	}
+	if err := lc.Series("closed", closed,
+		linechart.SeriesCellOpts(cell.FgColor(cell.ColorNumber(161))),
+		linechart.SeriesXLabels(XLabelMap),
+	); err != nil {
+		return nil, err
+	}
+	return lc, nil
+}
+
+// RenderLogAnalysis is starting the log analysis UI on terminal
+func RenderLogAnalysis(parentCtx context.Context) {
+	run(parentCtx, func(ctx context.Context, c *container.Container) ([]container.Option, error) {
+		summaryText, err := newLogSummaryText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		slowOpsLC, err := newOpcountersLc(ctx)
+		if err != nil {
+			return nil, err
+		}
+		churnLC, err := newConnectionsChurnLc(ctx)
+		if err != nil {
+			return nil, err
+		}
+		shapesText, err := newQueryShapesText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		eventsText, err := newLogEventsText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.PlaceWidget(summaryText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Log Analysis"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.Bottom(
+					container.SplitHorizontal(
+						container.Top(
+							container.SplitVertical(
+								container.Left(
+									container.PlaceWidget(slowOpsLC),
+									container.Border(linestyle.Light),
+									container.BorderTitle("Slow Operations per Second"),
+									container.BorderTitleAlignCenter(),
+								),
+								container.Right(
+									container.PlaceWidget(churnLC),
+									container.Border(linestyle.Light),
+									container.BorderTitle("Connections Opened/Closed"),
+									container.BorderTitleAlignCenter(),
+								),
+							),
+						),
+						container.Bottom(
+							container.SplitVertical(
+								container.Left(
+									container.PlaceWidget(shapesText),
+									container.Border(linestyle.Light),
+									container.BorderTitle("Query Shapes"),
+									container.BorderTitleAlignCenter(),
+								),
+								container.Right(
+									container.PlaceWidget(eventsText),
+									container.Border(linestyle.Light),
+									container.BorderTitle("Events"),
+									container.BorderTitleAlignCenter(),
+								),
+								container.SplitPercent(70),
+							),
+						),
+					),
+				),
+				container.SplitPercent(12),
+			),
+		}, nil
+	}, queryShapes.handleKey)
+}
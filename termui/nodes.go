@@ -0,0 +1,334 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/linechart"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// nodeColors are the colors of the nodes on the compare chart.
+var nodeColors = []cell.Color{
+	cell.ColorNumber(111),
+	cell.ColorNumber(172),
+	cell.ColorNumber(107),
+	cell.ColorNumber(161),
+	cell.ColorNumber(245),
+	cell.ColorNumber(135),
+	cell.ColorNumber(222),
+}
+
+type nodesState struct {
+	hosts   []string
+	metrics map[string]metricHelper.MetricsSlice
+	// selected is the node the charts show, the first node by default.
+	selected string
+	// compared are the nodes drawn on the compare chart, every node when empty.
+	compared map[string]bool
+	// compareLine is the index in legendLines of the metric compared.
+	compareLine int
+	// frozen are the metrics of the nodes while paused.
+	frozen map[string]metricHelper.MetricsSlice
+	mutex  sync.Mutex
+}
+
+var nodes = nodesState{
+	metrics:  map[string]metricHelper.MetricsSlice{},
+	compared: map[string]bool{},
+}
+
+// UpdateNodeMetricsSlice sets the last samples of host, the charts follow them when
+// host is the selected node.
+func UpdateNodeMetricsSlice(host string, ms metricHelper.MetricsSlice) {
+	nodes.mutex.Lock()
+	if !containsString(nodes.hosts, host) {
+		nodes.hosts = append(nodes.hosts, host)
+	}
+	nodes.metrics[host] = ms
+	if nodes.selected == "" {
+		nodes.selected = host
+	}
+	selected := nodes.selected == host
+	nodes.mutex.Unlock()
+
+	if selected {
+		history.update(ms)
+	}
+	changed()
+}
+
+// selectedNode returns the node the charts show.
+func selectedNode() string {
+	nodes.mutex.Lock()
+	defer nodes.mutex.Unlock()
+	return nodes.selected
+}
+
+// nodeHosts returns the nodes in the order their first samples came.
+func nodeHosts() []string {
+	nodes.mutex.Lock()
+	defer nodes.mutex.Unlock()
+	return nodes.hosts
+}
+
+// freeze keeps the current metrics of the nodes for the compare chart, or drops them
+// when frozen is false.
+func (s *nodesState) freeze(frozen bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.frozen = nil
+	if frozen {
+		s.frozen = make(map[string]metricHelper.MetricsSlice, len(s.metrics))
+		for host, ms := range s.metrics {
+			s.frozen[host] = ms
+		}
+	}
+}
+
+// comparedNodes returns the nodes drawn on the compare chart with their colors and
+// last samples, and the legend line of the metric compared.
+func (s *nodesState) comparedNodes() ([]string, []cell.Color, []metricHelper.MetricsSlice, legendLine) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	metrics := s.metrics
+	if s.frozen != nil {
+		metrics = s.frozen
+	}
+	var hosts []string
+	var colors []cell.Color
+	var slices []metricHelper.MetricsSlice
+	for i, host := range s.hosts {
+		if len(s.compared) > 0 && !s.compared[host] {
+			continue
+		}
+		hosts = append(hosts, host)
+		colors = append(colors, nodeColors[i%len(nodeColors)])
+		slices = append(slices, metrics[host])
+	}
+	return hosts, colors, slices, legendLines[s.compareLine]
+}
+
+// handleKey selects the previous or the next node on the up and down arrows, adds the
+// selected node to the compare chart or removes it on c, and changes the metric
+// compared on v. It reports whether the key was consumed.
+func (s *nodesState) handleKey(k *terminalapi.Keyboard) bool {
+	s.mutex.Lock()
+	if len(s.hosts) == 0 {
+		s.mutex.Unlock()
+		return false
+	}
+
+	i := 0
+	for j, host := range s.hosts {
+		if host == s.selected {
+			i = j
+		}
+	}
+	switch {
+	case k.Key == keyboard.KeyArrowUp:
+		i = (i + len(s.hosts) - 1) % len(s.hosts)
+	case k.Key == keyboard.KeyArrowDown:
+		i = (i + 1) % len(s.hosts)
+	case k.Key.String() == "c":
+		if s.compared[s.selected] {
+			delete(s.compared, s.selected)
+		} else {
+			s.compared[s.selected] = true
+		}
+		s.mutex.Unlock()
+		return true
+	case k.Key.String() == "v":
+		s.compareLine = (s.compareLine + 1) % len(legendLines)
+		s.mutex.Unlock()
+		return true
+	default:
+		s.mutex.Unlock()
+		return false
+	}
+	changed := s.hosts[i] != s.selected
+	s.selected = s.hosts[i]
+	ms := s.metrics[s.selected]
+	s.mutex.Unlock()
+
+	if changed {
+		history.setNode(ms)
+	}
+	return true
+}
+
+// newNodesText returns a text block that lists the nodes, marking the selected one
+// and the ones on the compare chart.
+func newNodesText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		nodes.mutex.Lock()
+		hosts := nodes.hosts
+		selected := nodes.selected
+		compared := make(map[string]bool, len(nodes.compared))
+		for host := range nodes.compared {
+			compared[host] = true
+		}
+		nodes.mutex.Unlock()
+
+		t.Reset()
+		for i, host := range hosts {
+			marker := "  "
+			if host == selected {
+				marker = "> "
+			}
+			color := cell.ColorNumber(245)
+			if compared[host] || len(compared) == 0 {
+				color = nodeColors[i%len(nodeColors)]
+			}
+			if compared[host] {
+				marker += "+"
+			} else {
+				marker += " "
+			}
+			if err := t.Write(fmt.Sprintf("%s%s\n", marker, host), text.WriteCellOpts(cell.FgColor(color))); err != nil {
+				return err
+			}
+		}
+		return t.Write(
+			"\nUp/Down select\nc compare\n",
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		)
+	})
+
+	return t, nil
+}
+
+// newCompareLc returns a line chart that overlays the metric compared of the compared nodes.
+func newCompareLc(ctx context.Context) (*linechart.LineChart, error) {
+	lc, err := linechart.New(
+		linechart.AxesCellOpts(cell.FgColor(cell.ColorNumber(161))),
+		linechart.YLabelCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		linechart.XLabelCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		linechart.XAxisUnscaled(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	drawn := map[string]bool{}
+	go redraw(ctx, lc, func() error {
+		hosts, colors, slices, line := nodes.comparedNodes()
+
+		// The nodes are sampled at the same interval, their last samples are aligned
+		// on the right and the times of the longest are shown.
+		XLabelMap := map[int]string{}
+		for i := 0; i < ChartLength; i++ {
+			XLabelMap[i] = "-"
+		}
+		longest := metricHelper.MetricsSlice{}
+		for _, ms := range slices {
+			if len(ms) > len(longest) {
+				longest = ms
+			}
+		}
+		for i, m := range lastSamples(longest) {
+			XLabelMap[ChartLength-len(lastSamples(longest))+i] = m.EndTime.Format("15:04:05")
+		}
+
+		series := map[string]bool{}
+		for i, host := range hosts {
+			ms := lastSamples(slices[i])
+			values := make([]float64, ChartLength)
+			for j, m := range ms {
+				values[ChartLength-len(ms)+j] = line.value(m)
+			}
+			if err := lc.Series(host, values,
+				linechart.SeriesCellOpts(cell.FgColor(colors[i])),
+				linechart.SeriesXLabels(XLabelMap),
+			); err != nil {
+				return err
+			}
+			series[host] = true
+		}
+		// A series cannot be removed, the series of a node removed from the
+		// comparison are not a number and are not drawn.
+		for host := range drawn {
+			if !series[host] {
+				values := make([]float64, ChartLength)
+				for i := range values {
+					values[i] = math.NaN()
+				}
+				if err := lc.Series(host, values); err != nil {
+					return err
+				}
+			}
+		}
+		drawn = series
+		return nil
+	})
+	return lc, nil
+}
+
+// newCompareText returns a text block that displays the metric compared and its
+// current value on the compared nodes.
+func newCompareText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		hosts, colors, slices, line := nodes.comparedNodes()
+
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf("%s %s\n\n", line.label, line.unit),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for i, host := range hosts {
+			now := "-"
+			if ms := slices[i]; len(ms) > 0 {
+				now = line.format(line.value(ms[len(ms)-1]))
+			}
+			if err := t.Write(
+				fmt.Sprintf("%-7s %s\n", now, host),
+				text.WriteCellOpts(cell.FgColor(colors[i])),
+			); err != nil {
+				return err
+			}
+		}
+		return t.Write(
+			"\nv next metric\n",
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		)
+	})
+
+	return t, nil
+}
+
+// lastSamples returns the last ChartLength metrics of ms.
+func lastSamples(ms metricHelper.MetricsSlice) metricHelper.MetricsSlice {
+	if len(ms) > ChartLength {
+		return ms[len(ms)-ChartLength:]
+	}
+	return ms
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
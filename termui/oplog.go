@@ -0,0 +1,82 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+var oplogMetrics *metricHelper.OplogMetrics
+var oplogMutex sync.Mutex
+var oplogAlertHours float64
+
+// UpdateOplogMetrics sets the oplog metrics displayed on the oplog panel.
+func UpdateOplogMetrics(m metricHelper.OplogMetrics) {
+	oplogMutex.Lock()
+	oplogMetrics = &m
+	oplogMutex.Unlock()
+	changed()
+}
+
+// SetOplogAlertHours sets the number of hours under which the oplog panel turns red.
+func SetOplogAlertHours(hours float64) {
+	oplogAlertHours = hours
+}
+
+// newOplogText returns a text block that displays the oplog window of the monitored node.
+func newOplogText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("Waiting for oplog stats...\n"); err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		oplogMutex.Lock()
+		m := oplogMetrics
+		oplogMutex.Unlock()
+		if m == nil {
+			return nil
+		}
+
+		color := cell.ColorNumber(107)
+		if oplogAlertHours > 0 && m.HoursUntilFallOff < oplogAlertHours {
+			color = cell.ColorNumber(161)
+		}
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf(
+				"%s  window %.2fh  used %.2f/%.2fGB  churn %.3fGB/h\n",
+				m.Host,
+				m.WindowHours,
+				m.UsedGB(),
+				m.MaxGB(),
+				m.GBPerHour,
+			),
+			text.WriteCellOpts(cell.FgColor(color)),
+		); err != nil {
+			return err
+		}
+		if m.LaggingMember == "" {
+			return nil
+		}
+		return t.Write(
+			fmt.Sprintf(
+				"lagging %s  lag %.0fs  falls off in %.2fh\n",
+				m.LaggingMember,
+				m.MaxLagSeconds,
+				m.HoursUntilFallOff,
+			),
+			text.WriteCellOpts(cell.FgColor(color)),
+		)
+	})
+
+	return t, nil
+}
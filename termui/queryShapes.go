@@ -0,0 +1,161 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+type queryShapeState struct {
+	shapes  metricHelper.QueryShapeStatsSlice
+	sortKey metricHelper.QueryShapeSortKey
+	status  string
+	mutex   sync.Mutex
+}
+
+var queryShapes = queryShapeState{}
+
+// UpdateQueryShapes sets the query shapes displayed on the query shapes table.
+func UpdateQueryShapes(ss metricHelper.QueryShapeStatsSlice) {
+	queryShapes.mutex.Lock()
+	queryShapes.shapes = ss
+	queryShapes.mutex.Unlock()
+	changed()
+}
+
+// SetQueryShapesStatus sets the status line displayed above the query shapes table.
+func SetQueryShapesStatus(status string) {
+	queryShapes.mutex.Lock()
+	queryShapes.status = status
+	queryShapes.mutex.Unlock()
+	changed()
+}
+
+// handleKey changes the sort of the query shapes table on s and reports whether the key was consumed.
+func (s *queryShapeState) handleKey(k *terminalapi.Keyboard) bool {
+	if k.Key.String() != "s" {
+		return false
+	}
+	s.mutex.Lock()
+	s.sortKey = s.sortKey.Next()
+	s.mutex.Unlock()
+	return true
+}
+
+// newQueryShapesStatusText returns a text block that displays the key bindings and the status.
+func newQueryShapesStatusText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		queryShapes.mutex.Lock()
+		sortKey := queryShapes.sortKey
+		status := queryShapes.status
+		queryShapes.mutex.Unlock()
+
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf("Press s to change the sort (%s), Esc/Q/Ctrl-C to quit\n", sortKey),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+		); err != nil {
+			return err
+		}
+		return t.Write(status+"\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(107))))
+	})
+
+	return t, nil
+}
+
+// newQueryShapesText returns a text block that displays the query shapes table.
+func newQueryShapesText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		queryShapes.mutex.Lock()
+		ss := make(metricHelper.QueryShapeStatsSlice, len(queryShapes.shapes))
+		copy(ss, queryShapes.shapes)
+		sortKey := queryShapes.sortKey
+		queryShapes.mutex.Unlock()
+		ss.Sort(sortKey)
+
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf(
+				"%-32s %8s %10s %9s %9s %10s %-16s %s\n",
+				"NS", "COUNT", "TOTAL ms", "AVG ms", "MAX ms", "EXAM/RET", "PLAN", "SHAPE",
+			),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for _, s := range ss {
+			color := cell.ColorNumber(111)
+			if s.ExaminedPerReturned() >= 100 {
+				color = cell.ColorNumber(161)
+			}
+			if err := t.Write(
+				fmt.Sprintf(
+					"%-32s %8.0f %10.0f %9.1f %9.0f %10.1f %-16s %s\n",
+					s.Ns,
+					s.Count,
+					s.TotalMillis,
+					s.AvgMillis,
+					s.MaxMillis,
+					s.ExaminedPerReturned(),
+					s.PlanSummary,
+					s.Shape,
+				),
+				text.WriteCellOpts(cell.FgColor(color)),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
+
+// RenderQueryShapes is starting the query shapes UI on terminal
+func RenderQueryShapes(parentCtx context.Context, title string) {
+	run(parentCtx, func(ctx context.Context, c *container.Container) ([]container.Option, error) {
+		statusText, err := newQueryShapesStatusText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		shapesText, err := newQueryShapesText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.PlaceWidget(statusText),
+					container.Border(linestyle.Light),
+					container.BorderTitle(title),
+					container.BorderTitleAlignCenter(),
+				),
+				container.Bottom(
+					container.PlaceWidget(shapesText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Query Shapes"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.SplitPercent(12),
+			),
+		}, nil
+	}, queryShapes.handleKey)
+}
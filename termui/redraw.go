@@ -0,0 +1,65 @@
+package termui
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// refreshInterval is how often the widgets shown are drawn when their data does not
+// change, for the ones following the time or pulling their data like the alerts.
+var refreshInterval = time.Second
+
+// redrawState tracks what the widgets have to draw again: the data they draw changes
+// with the generation, and only the widgets of the page shown are drawn.
+type redrawState struct {
+	generation uint64
+	// visible are the widgets shown, every widget is shown when it is nil.
+	visible map[widgetapi.Widget]bool
+	mutex   sync.Mutex
+}
+
+var redraws = redrawState{}
+
+// changed tells the widgets shown that their data changed, like when a sample came
+// or a key was pressed.
+func changed() {
+	redraws.mutex.Lock()
+	redraws.generation++
+	redraws.mutex.Unlock()
+}
+
+// show sets the widgets shown and has them drawn at once.
+func (s *redrawState) show(ws []widgetapi.Widget) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.visible = make(map[widgetapi.Widget]bool, len(ws))
+	for _, w := range ws {
+		s.visible[w] = true
+	}
+	s.generation++
+}
+
+// state returns the generation of the data and whether w is shown.
+func (s *redrawState) state(w widgetapi.Widget) (uint64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.generation, s.visible == nil || s.visible[w]
+}
+
+// redraw executes fn drawing w while w is shown, when the data changed since fn last
+// ran and every refreshInterval. Exits when the context expires.
+func redraw(ctx context.Context, w widgetapi.Widget, fn func() error) {
+	drawn := uint64(0)
+	last := time.Time{}
+	periodic(ctx, redrawInterval/3, func() error {
+		generation, visible := redraws.state(w)
+		if !visible || (generation == drawn && time.Since(last) < refreshInterval) {
+			return nil
+		}
+		drawn, last = generation, time.Now()
+		return fn()
+	})
+}
@@ -0,0 +1,77 @@
+package termui
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// countDraws runs redraw on w for d, calling during in the meantime, and returns the
+// number of draws.
+func countDraws(w widgetapi.Widget, d time.Duration, during func()) int64 {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var draws int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		redraw(ctx, w, func() error {
+			atomic.AddInt64(&draws, 1)
+			return nil
+		})
+	}()
+	if during != nil {
+		during()
+	}
+	time.Sleep(d)
+	cancel()
+	<-done
+	return atomic.LoadInt64(&draws)
+}
+
+func TestRedraw(t *testing.T) {
+	defer func(redrawPeriod, refreshPeriod time.Duration) {
+		redrawInterval, refreshInterval = redrawPeriod, refreshPeriod
+		redraws.mutex.Lock()
+		redraws.visible = nil
+		redraws.mutex.Unlock()
+	}(redrawInterval, refreshInterval)
+	redrawInterval, refreshInterval = 30*time.Millisecond, time.Hour
+
+	shown, err := text.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hidden, err := text.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	redraws.show([]widgetapi.Widget{shown})
+
+	// Idle, the widget shown is drawn once and the hidden one never.
+	if draws := countDraws(shown, 300*time.Millisecond, nil); draws != 1 {
+		t.Errorf("idle widget shown drawn %d times, want once", draws)
+	}
+	if draws := countDraws(hidden, 300*time.Millisecond, changed); draws != 0 {
+		t.Errorf("hidden widget drawn %d times, want none", draws)
+	}
+
+	// A change is drawn once, however many came in between.
+	if draws := countDraws(shown, 300*time.Millisecond, func() {
+		time.Sleep(100 * time.Millisecond)
+		changed()
+		changed()
+	}); draws != 2 {
+		t.Errorf("widget shown drawn %d times for a change, want the first draw and the change", draws)
+	}
+
+	// Without changes the widget shown is drawn every refreshInterval.
+	refreshInterval = 100 * time.Millisecond
+	if draws := countDraws(shown, 550*time.Millisecond, nil); draws < 3 || draws > 7 {
+		t.Errorf("idle widget shown drawn %d times in 550ms, want about every 100ms", draws)
+	}
+}
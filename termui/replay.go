@@ -0,0 +1,87 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+type replayState struct {
+	status string
+	step   func()
+	mutex  sync.Mutex
+}
+
+var replay = replayState{}
+
+// SetReplayStatus sets the status line displayed while replaying.
+func SetReplayStatus(status string) {
+	replay.mutex.Lock()
+	replay.status = status
+	replay.mutex.Unlock()
+	changed()
+}
+
+// SetReplayStepFunc sets the function advancing the replay by one sample, the replay
+// is not step by step when it is nil.
+func SetReplayStepFunc(fn func()) {
+	replay.mutex.Lock()
+	replay.step = fn
+	replay.mutex.Unlock()
+}
+
+// handleKey advances the replay on n or space and reports whether the key was consumed.
+func (s *replayState) handleKey(k *terminalapi.Keyboard) bool {
+	if k.Key != keyboard.KeySpace && k.Key.String() != "n" {
+		return false
+	}
+	s.mutex.Lock()
+	step := s.step
+	s.mutex.Unlock()
+	if step == nil {
+		return false
+	}
+	step()
+	return true
+}
+
+// newReplayText returns a text block that displays the key bindings and the replay status.
+func newReplayText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		replay.mutex.Lock()
+		status := replay.status
+		stepping := replay.step != nil
+		replay.mutex.Unlock()
+
+		t.Reset()
+		help := fmt.Sprintf("1-%d/Tab: pages  ?: help\nEsc/Q/Ctrl-C: quit\n", len(pages))
+		if stepping {
+			help = fmt.Sprintf("1-%d/Tab: pages  ?: help\nn/Space: next sample  Esc/Q/Ctrl-C: quit\n", len(pages))
+		}
+		if err := t.Write(help, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111)))); err != nil {
+			return err
+		}
+		return t.Write(fmt.Sprintf("%s\n", status), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(107))))
+	})
+
+	return t, nil
+}
+
+// RenderReplay is starting the mongostat UI on terminal for a replayed session
+func RenderReplay(parentCtx context.Context) {
+	renderPages(parentCtx, func(ctx context.Context, w *widgets) error {
+		var err error
+		w.mongostatUIText, err = newReplayText(ctx)
+		return err
+	}, replay.handleKey)
+}
@@ -0,0 +1,178 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+type sizeSortMode int
+
+const (
+	sortBySize sizeSortMode = iota
+	sortByGrowth
+)
+
+var sizeMetricsSlice metricHelper.SizeMetricsSlice
+var sizeMutex sync.Mutex
+var sizeSort = sortBySize
+
+// sizesStatus is the last error fetching the sizes, shown below the key bindings.
+var sizesStatus string
+
+// UpdateSizeMetricsSlice sets the database and collection sizes displayed on the sizes table.
+func UpdateSizeMetricsSlice(ms metricHelper.SizeMetricsSlice) {
+	sizeMutex.Lock()
+	sizeMetricsSlice = ms
+	sizeMutex.Unlock()
+	changed()
+}
+
+func sortedSizeMetrics() metricHelper.SizeMetricsSlice {
+	sizeMutex.Lock()
+	ms := make(metricHelper.SizeMetricsSlice, len(sizeMetricsSlice))
+	copy(ms, sizeMetricsSlice)
+	mode := sizeSort
+	sizeMutex.Unlock()
+
+	if mode == sortByGrowth {
+		ms.SortByGrowth()
+	} else {
+		ms.SortBySize()
+	}
+	return ms
+}
+
+// newSizesText returns a text block that displays the sizes table.
+func newSizesText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		ms := sortedSizeMetrics()
+		t.Reset()
+		if err := t.Write(
+			fmt.Sprintf(
+				"%-48s %12s %12s %12s %14s %14s\n",
+				"NAMESPACE", "DATA", "STORAGE", "INDEXES", "DOCUMENTS", "GROWTH/H",
+			),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for _, m := range ms {
+			color := cell.ColorNumber(111)
+			if m.Collection != "" {
+				color = cell.ColorNumber(245)
+			}
+			if m.DataGrowthPerHour < 0 {
+				color = cell.ColorNumber(107)
+			}
+			if err := t.Write(
+				fmt.Sprintf(
+					"%-48s %12s %12s %12s %14.0f %14s\n",
+					m.Namespace(),
+					metricHelper.FormatBytes(m.DataSize),
+					metricHelper.FormatBytes(m.StorageSize),
+					metricHelper.FormatBytes(m.IndexSize),
+					m.Count,
+					metricHelper.FormatBytes(m.DataGrowthPerHour),
+				),
+				text.WriteCellOpts(cell.FgColor(color)),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
+
+// SetSizesStatus sets the status line displayed below the key bindings of the sizes
+// table, the errors are shown there rather than logged over the UI.
+func SetSizesStatus(status string) {
+	sizeMutex.Lock()
+	sizesStatus = status
+	sizeMutex.Unlock()
+	changed()
+}
+
+// newSizesHelpText returns a text block that displays the key bindings of the sizes
+// table and the status line.
+func newSizesHelpText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		sizeMutex.Lock()
+		status := sizesStatus
+		sizeMutex.Unlock()
+		t.Reset()
+		if err := t.Write(
+			"Press s to sort by size, g to sort by growth, Esc/Q/Ctrl-C to quit\n",
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+		); err != nil {
+			return err
+		}
+		if status == "" {
+			return nil
+		}
+		return t.Write(status+"\n", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(161))))
+	})
+
+	return t, nil
+}
+
+// RenderSizes is starting the database and collection sizes UI on terminal
+func RenderSizes(parentCtx context.Context) {
+	run(parentCtx, func(ctx context.Context, c *container.Container) ([]container.Option, error) {
+		helpText, err := newSizesHelpText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sizesText, err := newSizesText(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.PlaceWidget(helpText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Database and Collection Sizes"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.Bottom(
+					container.PlaceWidget(sizesText),
+					container.Border(linestyle.Light),
+					container.BorderTitle("Sizes"),
+					container.BorderTitleAlignCenter(),
+				),
+				container.SplitPercent(10),
+			),
+		}, nil
+	}, func(k *terminalapi.Keyboard) bool {
+		sizeMutex.Lock()
+		defer sizeMutex.Unlock()
+		switch k.Key.String() {
+		case "s":
+			sizeSort = sortBySize
+		case "g":
+			sizeSort = sortByGrowth
+		}
+		return false
+	})
+}
@@ -0,0 +1,145 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	metricHelper "mongo-monitor/metric_helper"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+var topology *metricHelper.Topology
+
+// lagHistory are the last lags of the secondaries by member name.
+var lagHistory = map[string][]float64{}
+var topologyMutex sync.Mutex
+
+// UpdateTopology sets the replica set displayed on the replication page.
+func UpdateTopology(t metricHelper.Topology) {
+	defer changed()
+	topologyMutex.Lock()
+	defer topologyMutex.Unlock()
+	topology = &t
+	for _, m := range t.Members {
+		if m.State != "SECONDARY" {
+			continue
+		}
+		lags := append(lagHistory[m.Name], m.LagSeconds)
+		if len(lags) > ChartLength {
+			lags = lags[len(lags)-ChartLength:]
+		}
+		lagHistory[m.Name] = lags
+	}
+}
+
+// getLagHistory returns the last lags of the member name, nil when it has not been a secondary.
+func getLagHistory(name string) []float64 {
+	topologyMutex.Lock()
+	defer topologyMutex.Unlock()
+	return lagHistory[name]
+}
+
+// memberName returns the name of the replica set member host reports itself as, the
+// names come from the replica set config and may differ from the host of serverStatus.
+// It is host itself when no member matches.
+func memberName(host string) string {
+	topologyMutex.Lock()
+	tp := topology
+	topologyMutex.Unlock()
+	if tp == nil {
+		return host
+	}
+	for _, m := range tp.Members {
+		if m.Name == host {
+			return host
+		}
+	}
+	for _, m := range tp.Members {
+		if m.Self && tp.Host == host {
+			return m.Name
+		}
+	}
+	// A node may be configured by its fully qualified name and report its short one,
+	// or the other way around.
+	for _, m := range tp.Members {
+		if shortHost(m.Name) == shortHost(host) {
+			return m.Name
+		}
+	}
+	return host
+}
+
+// shortHost returns host:port without the domain of host.
+func shortHost(hostPort string) string {
+	host, port := hostPort, ""
+	if i := strings.LastIndex(hostPort, ":"); i >= 0 {
+		host, port = hostPort[:i], hostPort[i:]
+	}
+	if i := strings.Index(host, "."); i >= 0 {
+		host = host[:i]
+	}
+	return host + port
+}
+
+// newTopologyText returns a text block that lists the members of the replica set and their lag.
+func newTopologyText(ctx context.Context) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("Waiting for the replica set status...\n"); err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		topologyMutex.Lock()
+		tp := topology
+		topologyMutex.Unlock()
+		if tp == nil {
+			return nil
+		}
+
+		t.Reset()
+		if tp.SetName != "" {
+			if err := t.Write(
+				fmt.Sprintf("replica set %s  primary %s\n", tp.SetName, tp.Primary),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(111))),
+			); err != nil {
+				return err
+			}
+		}
+		if err := t.Write(
+			fmt.Sprintf("%-2s %-40s %-12s %10s\n", "", "MEMBER", "STATE", "LAG"),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		); err != nil {
+			return err
+		}
+		for _, m := range tp.Members {
+			self := ""
+			if m.Self {
+				self = "*"
+			}
+			color := cell.ColorNumber(107)
+			switch m.State {
+			case "PRIMARY", "STANDALONE":
+				color = cell.ColorNumber(111)
+			case "SECONDARY", "ARBITER":
+			default:
+				color = cell.ColorNumber(161)
+			}
+			if err := t.Write(
+				fmt.Sprintf("%-2s %-40s %-12s %9.0fs\n", self, m.Name, m.State, m.LagSeconds),
+				text.WriteCellOpts(cell.FgColor(color)),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
@@ -0,0 +1,39 @@
+package termui
+
+import (
+	"testing"
+
+	metricHelper "mongo-monitor/metric_helper"
+)
+
+func TestMemberName(t *testing.T) {
+	UpdateTopology(metricHelper.Topology{
+		Host: "localhost:27017",
+		Members: []metricHelper.TopologyMember{
+			{Name: "db1.example.com:27017", State: "PRIMARY", Self: true},
+			{Name: "db2.example.com:27017", State: "SECONDARY", LagSeconds: 3},
+			{Name: "db3:27018", State: "SECONDARY"},
+		},
+	})
+	defer func() {
+		topologyMutex.Lock()
+		topology, lagHistory = nil, map[string][]float64{}
+		topologyMutex.Unlock()
+	}()
+
+	tests := []struct{ host, want string }{
+		{"db2.example.com:27017", "db2.example.com:27017"},
+		{"localhost:27017", "db1.example.com:27017"},
+		{"db2:27017", "db2.example.com:27017"},
+		{"db3.example.com:27018", "db3:27018"},
+		{"db3:27017", "db3:27017"},
+	}
+	for _, test := range tests {
+		if got := memberName(test.host); got != test.want {
+			t.Errorf("memberName(%s) = %s, want %s", test.host, got, test.want)
+		}
+	}
+	if lags := getLagHistory(memberName("db2:27017")); len(lags) != 1 || lags[0] != 3 {
+		t.Errorf("lag of db2:27017 = %v, want the lag of its member", lags)
+	}
+}
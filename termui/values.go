@@ -0,0 +1,173 @@
+package termui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/linechart"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// ChartLength is the number of samples, or of steps of the zoomed windows, the line charts show.
+const ChartLength = 50
+
+// valuesSample are the values of a sample by metric name, see metric_helper.MetricValues.
+type valuesSample struct {
+	time   time.Time
+	values map[string]float64
+}
+
+// valuesHistory are the values of the last samples by target.
+var valuesHistory = map[string][]valuesSample{}
+var valuesMutex sync.Mutex
+
+// UpdateValues adds the values of a sample of target to the charts of the pages.
+func UpdateValues(target string, t time.Time, values map[string]float64) {
+	defer changed()
+	valuesMutex.Lock()
+	defer valuesMutex.Unlock()
+	samples := append(valuesHistory[target], valuesSample{time: t, values: values})
+	if len(samples) > ChartLength {
+		samples = samples[len(samples)-ChartLength:]
+	}
+	valuesHistory[target] = samples
+}
+
+// getValuesHistory returns the values of the last samples of the selected node, oldest
+// first, or the values at the time the charts were paused.
+func getValuesHistory() []valuesSample {
+	target := selectedNode()
+	history.mutex.Lock()
+	frozen := history.frozenValues
+	history.mutex.Unlock()
+	if frozen != nil {
+		return frozen[target]
+	}
+
+	valuesMutex.Lock()
+	defer valuesMutex.Unlock()
+	return valuesHistory[target]
+}
+
+// valuesSeries is a metric drawn on a values line chart.
+type valuesSeries struct {
+	name   string
+	metric string
+	color  cell.Color
+}
+
+// newValuesLc returns a line chart that displays the last values of the metrics of series.
+func newValuesLc(ctx context.Context, series []valuesSeries) (*linechart.LineChart, error) {
+	lc, err := linechart.New(
+		linechart.AxesCellOpts(cell.FgColor(cell.ColorNumber(161))),
+		linechart.YLabelCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		linechart.XLabelCellOpts(cell.FgColor(cell.ColorNumber(222))),
+		linechart.XAxisUnscaled(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	go redraw(ctx, lc, func() error {
+		history := getValuesHistory()
+		XLabelMap := map[int]string{}
+		for i := 0; i < ChartLength; i++ {
+			XLabelMap[i] = "-"
+		}
+		offset := ChartLength - len(history)
+		times := make([]time.Time, ChartLength)
+		for i, sample := range history {
+			XLabelMap[offset+i] = sample.time.Format("15:04:05")
+			times[offset+i] = sample.time
+		}
+		max := 0.0
+		for _, s := range series {
+			values := make([]float64, ChartLength)
+			for i, sample := range history {
+				values[offset+i] = sample.values[s.metric]
+			}
+			max = math.Max(max, maxValue(values))
+			if err := lc.Series(s.name, values,
+				linechart.SeriesCellOpts(cell.FgColor(s.color)),
+				linechart.SeriesXLabels(XLabelMap),
+			); err != nil {
+				return err
+			}
+		}
+		// "~~" sorts the events after the metrics.
+		return lc.Series("~~events", eventMarks(times, max),
+			linechart.SeriesCellOpts(cell.FgColor(eventMarkColor)),
+			linechart.SeriesXLabels(XLabelMap),
+		)
+	})
+	return lc, nil
+}
+
+// valuesLine is a line of a values text block, it is skipped when its metric is
+// missing from the last sample.
+type valuesLine struct {
+	label  string
+	metric string
+	format func(float64) string
+	color  cell.Color
+}
+
+// newValuesText returns a text block that displays the last values of the metrics of lines.
+func newValuesText(ctx context.Context, lines []valuesLine) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write("Waiting for the first sample...\n"); err != nil {
+		return nil, err
+	}
+
+	go redraw(ctx, t, func() error {
+		history := getValuesHistory()
+		if len(history) == 0 {
+			return nil
+		}
+		last := history[len(history)-1].values
+
+		t.Reset()
+		for _, line := range lines {
+			v, ok := last[line.metric]
+			if !ok {
+				continue
+			}
+			if err := t.Write(
+				fmt.Sprintf("%-12s %s\n", line.label, line.format(v)),
+				text.WriteCellOpts(cell.FgColor(line.color)),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return t, nil
+}
+
+// formatBytes formats a number of bytes with a binary unit.
+func formatBytes(v float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f%s", v, units[i])
+}
+
+// formatPercent formats a percentage.
+func formatPercent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+// formatCount formats a count or a rate.
+func formatCount(v float64) string {
+	return fmt.Sprintf("%.0f", v)
+}
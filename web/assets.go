@@ -0,0 +1,612 @@
+package web
+
+// The assets are compiled into the binary, so the dashboard is served without
+// any file next to it.
+
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>mongo-monitor</title>
+<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+<header>
+  <h1>mongo-monitor</h1>
+  <label>Target <select id="target"></select></label>
+  <label>Range
+    <select id="range">
+      <option value="5m" selected>Last 5 minutes (live)</option>
+      <option value="15m">Last 15 minutes (live)</option>
+      <option value="1h">Last hour (live)</option>
+      <option value="6h">Last 6 hours (live)</option>
+      <option value="24h">Last 24 hours (live)</option>
+      <option value="custom">Custom</option>
+    </select>
+  </label>
+  <span id="custom" hidden>
+    <input id="from" type="datetime-local" step="1"> to <input id="to" type="datetime-local" step="1">
+    <button id="apply">Apply</button>
+  </span>
+  <span id="status"></span>
+</header>
+<main>
+  <section>
+    <h2>Opcounters</h2>
+    <canvas id="opcounters"></canvas>
+    <div class="legend" id="opcounters-legend"></div>
+  </section>
+  <section>
+    <h2>Network</h2>
+    <canvas id="network"></canvas>
+    <div class="legend" id="network-legend"></div>
+  </section>
+  <section>
+    <h2>Checkpoints</h2>
+    <canvas id="checkpoints"></canvas>
+    <div class="legend" id="checkpoints-legend"></div>
+  </section>
+  <section>
+    <h2>Latency
+      <select id="latency-op">
+        <option value="reads" selected>reads</option>
+        <option value="writes">writes</option>
+        <option value="commands">commands</option>
+      </select>
+    </h2>
+    <canvas id="latency"></canvas>
+    <div class="legend" id="latency-legend"></div>
+  </section>
+  <section>
+    <h2>Events</h2>
+    <div id="events"></div>
+  </section>
+</main>
+<script src="/static/app.js"></script>
+</body>
+</html>
+`
+
+const styleCSS = `body {
+  margin: 0;
+  background: #1c1c1c;
+  color: #d0d0d0;
+  font: 14px monospace;
+}
+header {
+  display: flex;
+  flex-wrap: wrap;
+  align-items: center;
+  gap: 16px;
+  padding: 8px 16px;
+  border-bottom: 1px solid #444;
+}
+h1 {
+  margin: 0;
+  font-size: 18px;
+  color: #87afff;
+}
+h2 {
+  margin: 0 0 4px;
+  font-size: 14px;
+  color: #ffd787;
+}
+select, input, button {
+  background: #262626;
+  color: #d0d0d0;
+  border: 1px solid #555;
+  font: inherit;
+}
+#status {
+  margin-left: auto;
+  color: #8a8a8a;
+}
+main {
+  padding: 8px 16px;
+}
+section {
+  margin-bottom: 16px;
+}
+canvas {
+  width: 100%;
+  height: 220px;
+  border: 1px solid #444;
+}
+.legend span {
+  margin-right: 16px;
+}
+#events {
+  max-height: 200px;
+  overflow-y: auto;
+  white-space: pre;
+}
+`
+
+const appJS = `(function () {
+  "use strict";
+
+  // The colors of the series are those of the terminal UI.
+  var charts = {
+    opcounters: [
+      {metric: "insert_per_second", label: "insert", color: "#87afff"},
+      {metric: "query_per_second", label: "query", color: "#d78700"},
+      {metric: "update_per_second", label: "update", color: "#87af5f"},
+      {metric: "delete_per_second", label: "delete", color: "#d7005f"},
+      {metric: "getmore_per_second", label: "getmore", color: "#8a8a8a"},
+      {metric: "command_per_second", label: "command", color: "#af5fff"}
+    ],
+    network: [
+      {metric: "network_in_bytes_per_second", label: "in B/s", color: "#87afff"},
+      {metric: "network_out_bytes_per_second", label: "out B/s", color: "#d78700"}
+    ],
+    checkpoints: [
+      {metric: "checkpoint_per_second", label: "checkpoint", color: "#87af5f"}
+    ]
+  };
+  // maxPoints is the number of points a chart is drawn with at most.
+  var maxPoints = 600;
+  // The colors of the heatmap cells, from the fewest operations to the most, are
+  // those of the terminal UI.
+  var latencyColors = [
+    "#005f87", "#0087af", "#00afd7", "#00d7d7", "#5fd787",
+    "#afff00", "#ffd700", "#ff8700", "#ff5f00", "#ff0000"
+  ];
+  // The colors of the events by kind are those of the terminal UI.
+  var eventColors = {
+    restart: "#d7005f", election: "#d78700", state: "#87afff",
+    config: "#af5fff", fcv: "#87af5f", alert: "#ff0000"
+  };
+
+  var state = {
+    target: "", range: "5m", live: true, from: null, to: null, points: {}, source: null,
+    latencies: [], latencyOp: "reads", events: []
+  };
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  function setStatus(text) {
+    $("status").textContent = text;
+  }
+
+  function getJSON(url) {
+    return fetch(url).then(function (response) {
+      return response.json().then(function (body) {
+        if (!response.ok) {
+          throw new Error(body.error || response.statusText);
+        }
+        return body;
+      });
+    });
+  }
+
+  function parseDuration(s) {
+    var units = {s: 1000, m: 60000, h: 3600000};
+    return parseFloat(s) * units[s.charAt(s.length - 1)];
+  }
+
+  function formatValue(v) {
+    if (v >= 1e9) {
+      return (v / 1e9).toFixed(1) + "G";
+    }
+    if (v >= 1e6) {
+      return (v / 1e6).toFixed(1) + "M";
+    }
+    if (v >= 1e3) {
+      return (v / 1e3).toFixed(1) + "k";
+    }
+    return String(Math.round(v * 10) / 10);
+  }
+
+  function formatLatency(micros) {
+    if (micros >= 1e6) {
+      return Math.round(micros / 1e5) / 10 + "s";
+    }
+    if (micros >= 1e3) {
+      return Math.round(micros / 1e2) / 10 + "ms";
+    }
+    return micros + "\u00b5s";
+  }
+
+  function formatTime(t) {
+    return new Date(t).toTimeString().slice(0, 8);
+  }
+
+  function pad(n) {
+    return (n < 10 ? "0" : "") + n;
+  }
+
+  function toLocalInput(t) {
+    var d = new Date(t);
+    return d.getFullYear() + "-" + pad(d.getMonth() + 1) + "-" + pad(d.getDate()) +
+      "T" + pad(d.getHours()) + ":" + pad(d.getMinutes()) + ":" + pad(d.getSeconds());
+  }
+
+  function metricsParams() {
+    var params = [];
+    Object.keys(charts).forEach(function (name) {
+      charts[name].forEach(function (series) {
+        params.push("metric=" + series.metric);
+      });
+    });
+    return params.join("&");
+  }
+
+  function draw(name) {
+    var canvas = $(name);
+    var ratio = window.devicePixelRatio || 1;
+    var width = canvas.clientWidth;
+    var height = canvas.clientHeight;
+    canvas.width = width * ratio;
+    canvas.height = height * ratio;
+    var ctx = canvas.getContext("2d");
+    ctx.scale(ratio, ratio);
+    ctx.clearRect(0, 0, width, height);
+
+    var left = 60, bottom = 20, top = 8, right = 8;
+    var plotWidth = width - left - right;
+    var plotHeight = height - top - bottom;
+    var from = state.from, to = state.to;
+    var max = 0;
+    charts[name].forEach(function (series) {
+      (state.points[series.metric] || []).forEach(function (p) {
+        max = Math.max(max, p.value);
+      });
+    });
+    max = max > 0 ? max * 1.1 : 1;
+
+    ctx.strokeStyle = "#444";
+    ctx.fillStyle = "#ffd787";
+    ctx.font = "11px monospace";
+    ctx.beginPath();
+    for (var i = 0; i <= 4; i++) {
+      var y = top + plotHeight * i / 4;
+      ctx.moveTo(left, y);
+      ctx.lineTo(left + plotWidth, y);
+      ctx.fillText(formatValue(max * (4 - i) / 4), 4, y + 4);
+    }
+    ctx.stroke();
+    if (from === null || to <= from) {
+      return;
+    }
+    for (var j = 0; j <= 4; j++) {
+      var t = from + (to - from) * j / 4;
+      var x = left + plotWidth * j / 4;
+      ctx.fillText(formatTime(t), Math.min(x - 28, width - 60), height - 4);
+    }
+
+    var legend = [];
+    charts[name].forEach(function (series) {
+      var points = state.points[series.metric] || [];
+      ctx.strokeStyle = series.color;
+      ctx.beginPath();
+      points.forEach(function (p, k) {
+        var px = left + plotWidth * (p.time - from) / (to - from);
+        var py = top + plotHeight * (1 - p.value / max);
+        if (k === 0) {
+          ctx.moveTo(px, py);
+        } else {
+          ctx.lineTo(px, py);
+        }
+      });
+      ctx.stroke();
+      var last = points.length > 0 ? formatValue(points[points.length - 1].value) : "-";
+      legend.push('<span style="color:' + series.color + '">' + series.label + " " + last + "</span>");
+    });
+    $(name + "-legend").innerHTML = legend.join("");
+    drawEventMarks(ctx, left, top, plotWidth, plotHeight);
+  }
+
+  // drawEventMarks draws a dashed vertical line at the time of every event of the
+  // range on a chart.
+  function drawEventMarks(ctx, left, top, plotWidth, plotHeight) {
+    var from = state.from, to = state.to;
+    ctx.save();
+    ctx.setLineDash([4, 3]);
+    state.events.forEach(function (e) {
+      if (e.time < from || e.time > to) {
+        return;
+      }
+      var x = left + plotWidth * (e.time - from) / (to - from);
+      ctx.strokeStyle = eventColors[e.kind] || "#bcbcbc";
+      ctx.beginPath();
+      ctx.moveTo(x, top);
+      ctx.lineTo(x, top + plotHeight);
+      ctx.stroke();
+    });
+    ctx.restore();
+  }
+
+  // drawEvents lists the events of the range, the most recent first.
+  function drawEvents() {
+    var list = $("events");
+    list.innerHTML = "";
+    if (state.events.length === 0) {
+      list.textContent = "No event";
+      return;
+    }
+    state.events.slice().reverse().forEach(function (e) {
+      var line = document.createElement("div");
+      line.style.color = eventColors[e.kind] || "#bcbcbc";
+      line.textContent = formatTime(e.time) + " " + e.kind.toUpperCase() + " " + e.target + " " + e.message;
+      list.appendChild(line);
+    });
+  }
+
+  // percentile returns the upper bound of the bucket of histogram holding the p-th
+  // percentile of the operations.
+  function percentile(histogram, total, p) {
+    var seen = 0;
+    for (var i = 0; i < histogram.length; i++) {
+      seen += histogram[i];
+      if (seen >= total * p / 100) {
+        return Math.pow(2, i + 1);
+      }
+    }
+    return Math.pow(2, histogram.length);
+  }
+
+  // drawLatency draws the histograms of the selected operations as a heatmap: a
+  // column per point, a line per bucket, the slowest on top.
+  function drawLatency() {
+    var canvas = $("latency");
+    var ratio = window.devicePixelRatio || 1;
+    var width = canvas.clientWidth;
+    var height = canvas.clientHeight;
+    canvas.width = width * ratio;
+    canvas.height = height * ratio;
+    var ctx = canvas.getContext("2d");
+    ctx.scale(ratio, ratio);
+    ctx.clearRect(0, 0, width, height);
+
+    var left = 60, bottom = 20, top = 8, right = 8;
+    var plotWidth = width - left - right;
+    var plotHeight = height - top - bottom;
+    var from = state.from, to = state.to, op = state.latencyOp;
+    var points = state.latencies.filter(function (p) {
+      return p.latencies[op] && p.time >= from;
+    });
+
+    var low = -1, high = -1, max = 0, sum = [], total = 0;
+    points.forEach(function (p) {
+      p.latencies[op].forEach(function (ops, b) {
+        sum[b] = (sum[b] || 0) + ops;
+        total += ops;
+        if (ops <= 0) {
+          return;
+        }
+        low = low < 0 ? b : Math.min(low, b);
+        high = Math.max(high, b);
+        max = Math.max(max, ops);
+      });
+    });
+
+    ctx.fillStyle = "#ffd787";
+    ctx.font = "11px monospace";
+    if (from === null || to <= from || low < 0) {
+      ctx.fillText("no latency histogram in the samples", left, top + 12);
+      $("latency-legend").innerHTML = "";
+      return;
+    }
+    for (var j = 0; j <= 4; j++) {
+      var t = from + (to - from) * j / 4;
+      var x = left + plotWidth * j / 4;
+      ctx.fillText(formatTime(t), Math.min(x - 28, width - 60), height - 4);
+    }
+    var rowHeight = plotHeight / (high - low + 1);
+    for (var b = low; b <= high; b++) {
+      ctx.fillStyle = "#ffd787";
+      ctx.fillText(formatLatency(Math.pow(2, b)), 4, top + (high - b + 0.5) * rowHeight + 4);
+    }
+
+    // A point spans until the next one, the last one as long as the previous one.
+    points.forEach(function (p, k) {
+      var end = k + 1 < points.length ? points[k + 1].time :
+        p.time + (k > 0 ? p.time - points[k - 1].time : 1000);
+      var x0 = left + plotWidth * (p.time - from) / (to - from);
+      var x1 = left + plotWidth * (end - from) / (to - from);
+      p.latencies[op].forEach(function (ops, b) {
+        if (ops <= 0) {
+          return;
+        }
+        // The levels are logarithmic, so the few slow operations stand out.
+        var level = Math.ceil(Math.log(1 + ops) / Math.log(1 + max) * latencyColors.length) - 1;
+        ctx.fillStyle = latencyColors[Math.max(0, Math.min(latencyColors.length - 1, level))];
+        ctx.fillRect(x0, top + (high - b) * rowHeight, Math.max(1, x1 - x0), rowHeight);
+      });
+    });
+
+    var legend = [];
+    [50, 95, 99].forEach(function (p) {
+      legend.push("<span>p" + p + " \u2264" + formatLatency(percentile(sum, total, p)) + "</span>");
+    });
+    legend.push("<span>" + formatValue(total / points.length) + " ops/s</span>");
+    $("latency-legend").innerHTML = legend.join("");
+  }
+
+  function drawAll() {
+    Object.keys(charts).forEach(draw);
+    drawLatency();
+  }
+
+  function setEvents(events) {
+    state.events = events.map(function (e) {
+      return {time: Date.parse(e.time), target: e.target, kind: e.kind, message: e.message};
+    });
+    drawEvents();
+  }
+
+  function addEvent(e) {
+    state.events.push({time: Date.parse(e.time), target: e.target, kind: e.kind, message: e.message});
+    while (state.events.length > 0 && state.events[0].time < state.from) {
+      state.events.shift();
+    }
+    drawEvents();
+  }
+
+  function setLatencies(result) {
+    state.latencies = result.points.map(function (p) {
+      return {time: Date.parse(p.time), latencies: p.latencies};
+    });
+  }
+
+  function setPoints(result) {
+    state.points = {};
+    result.series.forEach(function (series) {
+      state.points[series.metric] = series.points.map(function (p) {
+        return {time: Date.parse(p.time), value: p.value};
+      });
+    });
+    state.from = Date.parse(result.from);
+    state.to = Date.parse(result.to);
+  }
+
+  function addSample(sample) {
+    var t = Date.parse(sample.time);
+    state.to = Math.max(state.to || t, t);
+    state.from = state.to - parseDuration(state.range);
+    Object.keys(sample.values).forEach(function (metric) {
+      var points = state.points[metric] || (state.points[metric] = []);
+      points.push({time: t, value: sample.values[metric]});
+      while (points.length > 0 && points[0].time < state.from) {
+        points.shift();
+      }
+      // The samples may come faster than they can be drawn.
+      while (points.length > maxPoints * 4) {
+        points.shift();
+      }
+    });
+    if (sample.latencies) {
+      state.latencies.push({time: t, latencies: sample.latencies});
+      while (state.latencies.length > 0 &&
+        (state.latencies[0].time < state.from || state.latencies.length > maxPoints * 4)) {
+        state.latencies.shift();
+      }
+    }
+  }
+
+  function stopStream() {
+    if (state.source) {
+      state.source.close();
+      state.source = null;
+    }
+  }
+
+  function startStream() {
+    stopStream();
+    var source = new EventSource("/events?target=" + encodeURIComponent(state.target));
+    source.addEventListener("sample", function (event) {
+      addSample(JSON.parse(event.data));
+      setStatus("live, last sample " + formatTime(state.to));
+    });
+    source.addEventListener("event", function (event) {
+      addEvent(JSON.parse(event.data));
+    });
+    source.onerror = function () {
+      setStatus("disconnected, retrying...");
+    };
+    state.source = source;
+  }
+
+  function step(rangeMillis) {
+    return Math.max(1, Math.ceil(rangeMillis / 1000 / maxPoints)) + "s";
+  }
+
+  function load() {
+    stopStream();
+    if (!state.target) {
+      drawAll();
+      return;
+    }
+    var params = "target=" + encodeURIComponent(state.target);
+    if (state.live) {
+      params += "&range=" + state.range + "&step=" + step(parseDuration(state.range));
+    } else {
+      var from = new Date($("from").value), to = new Date($("to").value);
+      if (isNaN(from) || isNaN(to)) {
+        setStatus("set the custom range");
+        return;
+      }
+      params += "&from=" + from.toISOString().replace(/\.\d+Z$/, "Z") +
+        "&to=" + to.toISOString().replace(/\.\d+Z$/, "Z") + "&step=" + step(to - from);
+    }
+    setStatus("loading...");
+    Promise.all([
+      getJSON("/api/v1/query?" + params + "&" + metricsParams()),
+      getJSON("/api/v1/latencies?" + params),
+      getJSON("/api/v1/events?" + params)
+    ]).then(function (results) {
+      setPoints(results[0]);
+      setLatencies(results[1]);
+      setEvents(results[2]);
+      drawAll();
+      if (state.live) {
+        startStream();
+        setStatus("live");
+      } else {
+        setStatus("history");
+      }
+    }).catch(function (err) {
+      setStatus(err.message);
+    });
+  }
+
+  function loadTargets() {
+    getJSON("/api/v1/targets").then(function (targets) {
+      var select = $("target");
+      select.innerHTML = "";
+      targets.forEach(function (target) {
+        var option = document.createElement("option");
+        option.value = option.textContent = target;
+        select.appendChild(option);
+      });
+      if (targets.length === 0) {
+        setStatus("waiting for the first sample...");
+        setTimeout(loadTargets, 2000);
+        return;
+      }
+      state.target = targets.indexOf(state.target) >= 0 ? state.target : targets[0];
+      select.value = state.target;
+      load();
+    }).catch(function (err) {
+      setStatus(err.message);
+    });
+  }
+
+  $("target").addEventListener("change", function (event) {
+    state.target = event.target.value;
+    load();
+  });
+  $("range").addEventListener("change", function (event) {
+    var custom = event.target.value === "custom";
+    $("custom").hidden = !custom;
+    state.live = !custom;
+    if (custom) {
+      if (state.to !== null) {
+        $("to").value = toLocalInput(state.to);
+        $("from").value = toLocalInput(state.to - parseDuration(state.range));
+      }
+      stopStream();
+      setStatus("set the custom range");
+      return;
+    }
+    state.range = event.target.value;
+    load();
+  });
+  $("apply").addEventListener("click", load);
+  $("latency-op").addEventListener("change", function (event) {
+    state.latencyOp = event.target.value;
+    drawLatency();
+  });
+  window.addEventListener("resize", drawAll);
+  setInterval(function () {
+    if (state.live) {
+      drawAll();
+    }
+  }, 1000);
+
+  loadTargets();
+})();
+`
@@ -0,0 +1,180 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"mongo-monitor/api"
+	metrichelper "mongo-monitor/metric_helper"
+)
+
+// heartbeatInterval is how often an idle event stream is written to, so proxies
+// keep it open.
+const heartbeatInterval = 15 * time.Second
+
+// subscriberBuffer is the number of messages a slow client may lag behind before
+// messages are dropped for it.
+const subscriberBuffer = 64
+
+// message is a Server-Sent Event, a sample or an event of a target.
+type message struct {
+	name   string
+	target string
+	data   interface{}
+}
+
+// Server serves the web dashboard and streams the samples and the events published
+// to it as Server-Sent Events:
+//
+//	GET /                     the dashboard
+//	GET /static/...           its scripts and styles
+//	GET /events?target=...    the samples and the events of target, every target
+//	                          without target
+//
+// The history comes from the JSON API, which is expected on /api/v1/.
+type Server struct {
+	mux         *http.ServeMux
+	subscribers map[chan message]struct{}
+	closed      bool
+	mutex       sync.Mutex
+}
+
+// NewServer returns a server without subscriber.
+func NewServer() *Server {
+	server := &Server{
+		mux:         http.NewServeMux(),
+		subscribers: map[chan message]struct{}{},
+	}
+	server.mux.HandleFunc("/", handleIndex)
+	server.mux.HandleFunc("/static/app.js", serveAsset("application/javascript", appJS))
+	server.mux.HandleFunc("/static/style.css", serveAsset("text/css", styleCSS))
+	server.mux.HandleFunc("/events", server.handleEvents)
+	return server
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+// handleIndex serves the dashboard on / only, the other paths are not found.
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	serveAsset("text/html; charset=utf-8", indexHTML)(w, r)
+}
+
+func serveAsset(contentType string, content string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "no-cache")
+		fmt.Fprint(w, content)
+	}
+}
+
+// Publish sends the metrics of a sample to the clients following its target.
+func (server *Server) Publish(metrics metrichelper.Metrics) {
+	sample := api.Sample{
+		Target:    metrics.Host,
+		Time:      metrics.EndTime,
+		Values:    metrics.Values(),
+		Latencies: metrics.Latencies,
+	}
+	server.publish(message{name: "sample", target: sample.Target, data: sample})
+}
+
+// PublishEvent sends an event to the clients following its target.
+func (server *Server) PublishEvent(event metrichelper.Event) {
+	server.publish(message{name: "event", target: event.Target, data: event})
+}
+
+func (server *Server) publish(m message) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	for subscriber := range server.subscribers {
+		select {
+		case subscriber <- m:
+		default:
+		}
+	}
+}
+
+// Close ends the event streams, so the HTTP server shuts down without waiting for them.
+func (server *Server) Close() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	for subscriber := range server.subscribers {
+		close(subscriber)
+		delete(server.subscribers, subscriber)
+	}
+	server.closed = true
+}
+
+func (server *Server) subscribe() (chan message, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.closed {
+		return nil, false
+	}
+	subscriber := make(chan message, subscriberBuffer)
+	server.subscribers[subscriber] = struct{}{}
+	return subscriber, true
+}
+
+func (server *Server) unsubscribe(subscriber chan message) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if _, ok := server.subscribers[subscriber]; ok {
+		close(subscriber)
+		delete(server.subscribers, subscriber)
+	}
+}
+
+func (server *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	subscriber, ok := server.subscribe()
+	if !ok {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer server.unsubscribe(subscriber)
+	target := r.URL.Query().Get("target")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case m, ok := <-subscriber:
+			if !ok {
+				return
+			}
+			if target != "" && m.target != target {
+				continue
+			}
+			data, err := json.Marshal(m.data)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.name, data)
+		}
+		flusher.Flush()
+	}
+}